@@ -2,4 +2,10 @@ module gh.tarampamp.am/video-dl-bot
 
 go 1.26
 
-require gopkg.in/telebot.v4 v4.0.0-beta.10
+require (
+	go.etcd.io/bbolt v1.5.0
+	gopkg.in/telebot.v4 v4.0.0-beta.10
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require golang.org/x/sys v0.45.0 // indirect
@@ -0,0 +1,158 @@
+// Package aeszip writes the password-protected zip archives, encrypted with the WinZip AES scheme (AE-2, AES-256).
+// Such archives are opened by 7-Zip, WinZip, WinRAR, bsdtar and most of the archive managers.
+package aeszip
+
+import (
+	"archive/zip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha1" //nolint:gosec // required by the specification (PBKDF2 and the authentication code)
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	methodAES     = 99     // compression method of the encrypted entries
+	extraAESID    = 0x9901 // ID of the AES extra field
+	flagEncrypted = 0x1    // general purpose flag: the entry is encrypted
+	flagUTF8      = 0x800  // general purpose flag: the name is UTF-8 encoded
+	zipVersion51  = 51     // minimal version to extract the AES-encrypted entries
+
+	keySize       = 32   // AES-256
+	saltSize      = 16   // salt size for AES-256
+	verifierSize  = 2    // size of the password verification value
+	authCodeSize  = 10   // size of the authentication code (truncated HMAC-SHA1)
+	kdfIterations = 1000 // PBKDF2 iterations, as the specification requires
+)
+
+// Overhead is the number of bytes, that the encryption adds to the file size (the salt, the password verification
+// value and the authentication code), not counting the zip headers.
+const Overhead = saltSize + verifierSize + authCodeSize
+
+// Encrypt writes the zip archive with a single file, read from r (exactly size bytes), encrypted with the password.
+// The file is stored as is, without compression (the videos don't compress anyway).
+func Encrypt(w io.Writer, r io.Reader, size int64, name, password string, modified time.Time) error {
+	if password == "" {
+		return errors.New("aeszip: empty password")
+	}
+
+	var salt = make([]byte, saltSize)
+
+	_, _ = rand.Read(salt) // never returns an error
+
+	keys, err := pbkdf2.Key(sha1.New, password, salt, kdfIterations, 2*keySize+verifierSize)
+	if err != nil {
+		return fmt.Errorf("aeszip: %w", err)
+	}
+
+	block, err := aes.NewCipher(keys[:keySize])
+	if err != nil {
+		return fmt.Errorf("aeszip: %w", err)
+	}
+
+	var (
+		zw         = zip.NewWriter(w)
+		date, tm   = msDosTime(modified)
+		extraField = make([]byte, 11) //nolint:mnd // 2x uint16 header + 7 bytes of data
+	)
+
+	binary.LittleEndian.PutUint16(extraField[0:], extraAESID)
+	binary.LittleEndian.PutUint16(extraField[2:], 7) //nolint:mnd // data size
+	binary.LittleEndian.PutUint16(extraField[4:], 2) //nolint:mnd // vendor version (AE-2: no CRC)
+	copy(extraField[6:], "AE")                       // vendor ID
+	extraField[8] = 3                                // AES strength (AES-256)
+	binary.LittleEndian.PutUint16(extraField[9:], zip.Store)
+
+	fw, err := zw.CreateRaw(&zip.FileHeader{
+		Name:               name,
+		CreatorVersion:     zipVersion51,
+		ReaderVersion:      zipVersion51,
+		Flags:              flagEncrypted | flagUTF8,
+		Method:             methodAES,
+		ModifiedTime:       tm,   //nolint:staticcheck // CreateRaw doesn't convert the Modified field
+		ModifiedDate:       date, //nolint:staticcheck // --//--
+		CompressedSize64:   uint64(size + Overhead),
+		UncompressedSize64: uint64(size),
+		Extra:              extraField,
+	})
+	if err != nil {
+		return fmt.Errorf("aeszip: %w", err)
+	}
+
+	if _, err = fw.Write(append(salt, keys[2*keySize:]...)); err != nil {
+		return fmt.Errorf("aeszip: %w", err)
+	}
+
+	var (
+		mac = hmac.New(sha1.New, keys[keySize:2*keySize])
+		enc = cipher.StreamWriter{S: &ctrLE{block: block}, W: io.MultiWriter(fw, mac)}
+	)
+
+	if _, err = io.CopyN(enc, r, size); err != nil {
+		return fmt.Errorf("aeszip: failed to encrypt the file: %w", err)
+	}
+
+	if _, err = fw.Write(mac.Sum(nil)[:authCodeSize]); err != nil {
+		return fmt.Errorf("aeszip: %w", err)
+	}
+
+	if err = zw.Close(); err != nil {
+		return fmt.Errorf("aeszip: %w", err)
+	}
+
+	return nil
+}
+
+// ctrLE is the AES-CTR mode with the little-endian counter, starting at 1, as the WinZip AES specification requires
+// (the standard cipher.NewCTR increments the counter as a big-endian number).
+type ctrLE struct {
+	block   cipher.Block
+	counter uint64
+	buf     []byte                   // the unused part of the key stream
+	stream  [64 * aes.BlockSize]byte // the key stream is generated in batches
+}
+
+// XORKeyStream implements the cipher.Stream interface.
+func (c *ctrLE) XORKeyStream(dst, src []byte) {
+	for len(src) > 0 {
+		if len(c.buf) == 0 {
+			c.refill()
+		}
+
+		var n = subtle.XORBytes(dst, src, c.buf)
+
+		c.buf, dst, src = c.buf[n:], dst[n:], src[n:]
+	}
+}
+
+// refill generates the next batch of the key stream.
+func (c *ctrLE) refill() {
+	for i := 0; i < len(c.stream); i += aes.BlockSize {
+		var blk = c.stream[i : i+aes.BlockSize]
+
+		c.counter++
+
+		clear(blk)
+		binary.LittleEndian.PutUint64(blk, c.counter)
+		c.block.Encrypt(blk, blk)
+	}
+
+	c.buf = c.stream[:]
+}
+
+// msDosTime converts the time to the MS-DOS date and time (the zero time is kept as is).
+func msDosTime(t time.Time) (date, tm uint16) {
+	if t.IsZero() {
+		return 0, 0
+	}
+
+	return uint16(t.Day() + int(t.Month())<<5 + (t.Year()-1980)<<9), //nolint:gosec,mnd
+		uint16(t.Second()/2 + t.Minute()<<5 + t.Hour()<<11) //nolint:gosec,mnd
+}
@@ -0,0 +1,159 @@
+package aeszip_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/pbkdf2"
+	"crypto/sha1" //nolint:gosec
+	"encoding/binary"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"gh.tarampamp.am/video-dl-bot/internal/aeszip"
+)
+
+func TestEncrypt(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveContent []byte
+		giveName    string
+	}{
+		"empty":         {giveContent: []byte{}, giveName: "empty.mp4"},
+		"short":         {giveContent: []byte("video"), giveName: "video.mp4"},
+		"several pages": {giveContent: bytes.Repeat([]byte("0123456789abcdef"), 10_000), giveName: "Видео.mp4"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				buf      bytes.Buffer
+				modified = time.Date(2026, 10, 18, 12, 34, 56, 0, time.UTC)
+			)
+
+			err := aeszip.Encrypt(&buf, bytes.NewReader(tc.giveContent), int64(len(tc.giveContent)), tc.giveName,
+				"passw0rd", modified,
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(zr.File) != 1 {
+				t.Fatalf("expected a single file, got %d", len(zr.File))
+			}
+
+			var f = zr.File[0]
+
+			if f.Name != tc.giveName || f.Method != 99 || f.Flags&0x1 == 0 || f.CRC32 != 0 {
+				t.Errorf("unexpected header: %+v", f.FileHeader)
+			}
+
+			if !f.Modified.Equal(modified) {
+				t.Errorf("unexpected modification time: %s", f.Modified)
+			}
+
+			if f.UncompressedSize64 != uint64(len(tc.giveContent)) ||
+				f.CompressedSize64 != uint64(len(tc.giveContent)+aeszip.Overhead) {
+				t.Errorf("unexpected sizes: %d, %d", f.UncompressedSize64, f.CompressedSize64)
+			}
+
+			if !bytes.Contains(f.Extra, []byte{0x01, 0x99, 7, 0, 2, 0, 'A', 'E', 3, 0, 0}) {
+				t.Errorf("the AES extra field is missing: %x", f.Extra)
+			}
+
+			raw, err := f.OpenRaw()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := io.ReadAll(raw)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err = decrypt(data, "wrong"); err == nil {
+				t.Error("the wrong password should not be accepted")
+			}
+
+			plain, err := decrypt(data, "passw0rd")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(plain, tc.giveContent) {
+				t.Error("the decrypted content differs")
+			}
+		})
+	}
+}
+
+func TestEncrypt_Errors(t *testing.T) {
+	t.Parallel()
+
+	if err := aeszip.Encrypt(io.Discard, strings.NewReader("video"), 5, "video.mp4", "", time.Now()); err == nil {
+		t.Error("expected an error for the empty password")
+	}
+
+	err := aeszip.Encrypt(io.Discard, strings.NewReader("video"), 10, "video.mp4", "passw0rd", time.Now())
+	if err == nil || !strings.Contains(err.Error(), "failed to encrypt") {
+		t.Errorf("expected an error for the truncated file, got %v", err)
+	}
+}
+
+// decrypt decrypts the WinZip AES (AES-256) entry data, written independently of the package implementation.
+func decrypt(data []byte, password string) ([]byte, error) {
+	const saltSize, keySize = 16, 32
+
+	var (
+		salt     = data[:saltSize]
+		verifier = data[saltSize : saltSize+2]
+		body     = data[saltSize+2 : len(data)-10]
+		authCode = data[len(data)-10:]
+	)
+
+	keys, err := pbkdf2.Key(sha1.New, password, salt, 1000, 2*keySize+2)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(keys[2*keySize:], verifier) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	var mac = hmac.New(sha1.New, keys[keySize:2*keySize])
+
+	mac.Write(body)
+
+	if !bytes.Equal(mac.Sum(nil)[:10], authCode) {
+		return nil, io.ErrUnexpectedEOF
+	}
+
+	block, err := aes.NewCipher(keys[:keySize])
+	if err != nil {
+		return nil, err
+	}
+
+	var plain = make([]byte, len(body))
+
+	for i := 0; i < len(body); i += aes.BlockSize {
+		var counter, stream [aes.BlockSize]byte
+
+		binary.LittleEndian.PutUint64(counter[:], uint64(i/aes.BlockSize+1))
+		block.Encrypt(stream[:], counter[:])
+
+		for j := i; j < min(i+aes.BlockSize, len(body)); j++ {
+			plain[j] = body[j] ^ stream[j-i]
+		}
+	}
+
+	return plain, nil
+}
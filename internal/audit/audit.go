@@ -0,0 +1,268 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultQueueSize is the default number of the events waiting to be written to the sink.
+const DefaultQueueSize = 1024
+
+// ErrQueueFull is returned by Logger.Log when the event is dropped because the sink can't keep up.
+var ErrQueueFull = errors.New("audit queue is full, the event is dropped")
+
+// ErrClosed is returned by Logger.Log when the logger is closed.
+var ErrClosed = errors.New("audit logger is closed")
+
+// Outcome is the result of the job.
+type Outcome string
+
+const (
+	OutcomeSuccess  Outcome = "success"  // the video is downloaded and sent to the user
+	OutcomeRejected Outcome = "rejected" // the request is rejected (e.g., invalid link)
+	OutcomeFailed   Outcome = "failed"   // the download or upload failed
+	OutcomeCanceled Outcome = "canceled" // the job is canceled (e.g., the bot is stopping)
+)
+
+// Event field names (keys of the JSON object).
+const (
+	FieldTime       = "time"
+	FieldUserID     = "user_id"
+	FieldChatID     = "chat_id"
+	FieldURL        = "url"
+	FieldExtractor  = "extractor"
+	FieldFormat     = "format"
+	FieldBytes      = "bytes"
+	FieldDurations  = "durations"
+	FieldOutcome    = "outcome"
+	FieldErrorClass = "error_class"
+)
+
+// Fields returns the names of all the event fields.
+func Fields() []string {
+	return []string{
+		FieldTime, FieldUserID, FieldChatID, FieldURL, FieldExtractor, FieldFormat, FieldBytes, FieldDurations,
+		FieldOutcome, FieldErrorClass,
+	}
+}
+
+type (
+	// Event describes a single job (a download request from the user).
+	Event struct {
+		Time       time.Time                // when the job was started
+		UserID     int64                    // Telegram user ID
+		ChatID     int64                    // Telegram chat ID
+		URL        string                   // requested URL
+		Extractor  string                   // yt-dlp extractor (e.g., "youtube")
+		Format     string                   // format ID(s) selected by yt-dlp (e.g., "137+140")
+		Bytes      int64                    // size of the sent file
+		Durations  map[string]time.Duration // durations per phase (e.g., "queue", "download", "upload", "total")
+		Outcome    Outcome                  // the job result
+		ErrorClass string                   // class of the error (for the failed jobs)
+	}
+
+	// Logger writes the events to the sink, one JSON object per event. The events are queued and written in
+	// the background, so a slow sink never blocks the caller. It's safe for concurrent use.
+	Logger struct {
+		sink       Sink
+		fields     []string // fields to write
+		hashKey    []byte   // key to hash the user IDs with (nil = no hashing)
+		redactURLs bool
+		queueSize  int
+		onError    func(error) // called on the sink write errors (nil = ignore)
+
+		mu      sync.RWMutex  // guards the queue closing
+		closed  bool          // the queue is closed
+		queue   chan []byte   // encoded events waiting to be written
+		done    chan struct{} // closed when the queue is drained
+		dropped atomic.Uint64 // number of the dropped events
+	}
+
+	// Option is a function that configures the Logger.
+	Option func(*Logger)
+)
+
+// WithFields sets the event fields to write (all fields are written by default; unknown names cause an error
+// in NewLogger).
+func WithFields(fields ...string) Option { return func(l *Logger) { l.fields = fields } }
+
+// WithUserIDHashing replaces the user and chat IDs with their HMAC-SHA256 hashes (computed with the given
+// key), so the events of the same user can be correlated without revealing the user.
+func WithUserIDHashing(key string) Option { return func(l *Logger) { l.hashKey = []byte(key) } }
+
+// WithURLRedaction removes the credentials, query and fragment from the URLs (they may contain tokens).
+func WithURLRedaction() Option { return func(l *Logger) { l.redactURLs = true } }
+
+// WithQueueSize sets the number of the events waiting to be written; when the queue is full, the new events
+// are dropped (DefaultQueueSize by default).
+func WithQueueSize(n int) Option { return func(l *Logger) { l.queueSize = n } }
+
+// WithErrorHandler sets the function to call when the sink fails to write an event.
+func WithErrorHandler(fn func(error)) Option { return func(l *Logger) { l.onError = fn } }
+
+// NewLogger creates a new audit logger that writes the events to the sink. The logger must be closed to flush
+// the queued events.
+func NewLogger(sink Sink, opts ...Option) (*Logger, error) {
+	var l = &Logger{sink: sink, fields: Fields(), queueSize: DefaultQueueSize}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	for _, f := range l.fields {
+		if !slices.Contains(Fields(), f) {
+			return nil, fmt.Errorf("unknown audit field: %q", f)
+		}
+	}
+
+	if l.queueSize < 1 {
+		return nil, fmt.Errorf("invalid audit queue size: %d", l.queueSize)
+	}
+
+	l.queue, l.done = make(chan []byte, l.queueSize), make(chan struct{})
+
+	go l.drain()
+
+	return l, nil
+}
+
+// Log queues the event to be written to the sink. It never blocks: if the queue is full, the event is dropped
+// (and counted, see Dropped) and ErrQueueFull is returned.
+func (l *Logger) Log(e Event) error {
+	var buf bytes.Buffer
+
+	var enc = json.NewEncoder(&buf)
+
+	enc.SetEscapeHTML(false) // keep the URLs readable
+
+	if err := enc.Encode(l.encode(e)); err != nil {
+		return err
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if l.closed {
+		return ErrClosed
+	}
+
+	select {
+	case l.queue <- bytes.TrimSuffix(buf.Bytes(), []byte("\n")):
+		return nil
+	default:
+		l.dropped.Add(1)
+
+		return ErrQueueFull
+	}
+}
+
+// Dropped returns the number of the events dropped because the queue was full.
+func (l *Logger) Dropped() uint64 { return l.dropped.Load() }
+
+// Close stops accepting the events, waits until the queued ones are written and closes the sink.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+
+	if l.closed {
+		l.mu.Unlock()
+
+		return nil
+	}
+
+	l.closed = true
+	close(l.queue)
+	l.mu.Unlock()
+
+	<-l.done
+
+	return l.sink.Close()
+}
+
+// drain writes the queued events to the sink until the queue is closed.
+func (l *Logger) drain() {
+	defer close(l.done)
+
+	for event := range l.queue {
+		if err := l.sink.Write(event); err != nil && l.onError != nil {
+			l.onError(err)
+		}
+	}
+}
+
+// encode converts the event into the map of the enabled fields (the keys are sorted by the JSON encoder).
+func (l *Logger) encode(e Event) map[string]any {
+	var out = make(map[string]any, len(l.fields))
+
+	for _, f := range l.fields {
+		switch f {
+		case FieldTime:
+			out[f] = e.Time.UTC().Format(time.RFC3339Nano)
+		case FieldUserID:
+			out[f] = l.hashID(e.UserID)
+		case FieldChatID:
+			out[f] = l.hashID(e.ChatID)
+		case FieldURL:
+			out[f] = l.redactURL(e.URL)
+		case FieldExtractor:
+			out[f] = e.Extractor
+		case FieldFormat:
+			out[f] = e.Format
+		case FieldBytes:
+			out[f] = e.Bytes
+		case FieldDurations:
+			var ms = make(map[string]int64, len(e.Durations))
+
+			for phase, d := range e.Durations {
+				ms[phase] = d.Milliseconds()
+			}
+
+			out[f] = ms
+		case FieldOutcome:
+			out[f] = e.Outcome
+		case FieldErrorClass:
+			out[f] = e.ErrorClass
+		}
+	}
+
+	return out
+}
+
+// hashID returns the ID as is, or its hash (if the hashing is enabled).
+func (l *Logger) hashID(id int64) any {
+	if l.hashKey == nil {
+		return id
+	}
+
+	var mac = hmac.New(sha256.New, l.hashKey)
+
+	_, _ = mac.Write([]byte(strconv.FormatInt(id, 10)))
+
+	return hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// redactURL returns the URL as is, or without the sensitive parts (if the redaction is enabled).
+func (l *Logger) redactURL(s string) string {
+	if !l.redactURLs {
+		return s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return ""
+	}
+
+	u.User, u.RawQuery, u.Fragment, u.RawFragment, u.ForceQuery = nil, "", "", "", false
+
+	return u.String()
+}
@@ -0,0 +1,266 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/syslog"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// syslogTag is the tag of the syslog messages.
+const syslogTag = "video-dl-bot"
+
+// httpTimeout is the timeout of the HTTP sink requests.
+const httpTimeout = 10 * time.Second
+
+type (
+	// Sink is the destination of the audit events. Write receives a single JSON-encoded event (without the
+	// trailing newline).
+	Sink interface {
+		Write(event []byte) error
+		Close() error
+	}
+
+	// FileRotation configures the rotation of the audit log file.
+	FileRotation struct {
+		MaxSize    int64 // the file is rotated when it exceeds this size, in bytes (0 = no rotation)
+		MaxBackups int   // number of the rotated files to keep (e.g., "audit.jsonl.1", "audit.jsonl.2", ...)
+	}
+)
+
+// OpenSink opens the sink by its specification:
+//
+//   - "stdout" or "stderr" - JSON lines to the standard output/error;
+//   - "syslog" - local syslog daemon; "syslog://host:514" (UDP) or "syslog+tcp://host:514" - remote one;
+//   - "http://..." or "https://..." - every event is POSTed to the URL;
+//   - anything else (optionally prefixed with "file://") - path to the JSON lines file (rotated by size).
+func OpenSink(spec string, rotation FileRotation) (Sink, error) {
+	switch {
+	case spec == "":
+		return nil, errors.New("audit sink is not specified")
+	case spec == "stdout":
+		return NewWriterSink(os.Stdout), nil
+	case spec == "stderr":
+		return NewWriterSink(os.Stderr), nil
+	case spec == "syslog":
+		return NewSyslogSink("", "")
+	case strings.HasPrefix(spec, "syslog://"), strings.HasPrefix(spec, "syslog+udp://"),
+		strings.HasPrefix(spec, "syslog+tcp://"):
+		u, err := url.Parse(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid syslog address: %w", err)
+		}
+
+		var network = "udp"
+		if u.Scheme == "syslog+tcp" {
+			network = "tcp"
+		}
+
+		return NewSyslogSink(network, u.Host)
+	case strings.HasPrefix(spec, "http://"), strings.HasPrefix(spec, "https://"):
+		if u, err := url.ParseRequestURI(spec); err != nil {
+			return nil, fmt.Errorf("invalid audit endpoint: %w", err)
+		} else if u.Host == "" {
+			return nil, errors.New("invalid audit endpoint: host is missing")
+		}
+
+		return NewHTTPSink(spec, &http.Client{Timeout: httpTimeout}), nil
+	}
+
+	return NewFileSink(strings.TrimPrefix(spec, "file://"), rotation)
+}
+
+// WriterSink writes the events to the writer as JSON lines.
+type WriterSink struct{ w io.Writer }
+
+var _ Sink = (*WriterSink)(nil) // ensure the interface is implemented
+
+// NewWriterSink creates a new sink that writes the events to the writer.
+func NewWriterSink(w io.Writer) *WriterSink { return &WriterSink{w: w} }
+
+// Write implements the Sink interface.
+func (s *WriterSink) Write(event []byte) error {
+	_, err := s.w.Write(append(event, '\n'))
+
+	return err
+}
+
+// Close implements the Sink interface (the writer is not closed).
+func (s *WriterSink) Close() error { return nil }
+
+// FileSink writes the events to the file as JSON lines, rotating it by size. It's safe for concurrent use.
+type FileSink struct {
+	path     string
+	rotation FileRotation
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+var _ Sink = (*FileSink)(nil) // ensure the interface is implemented
+
+// NewFileSink opens (or creates) the file for appending the events.
+func NewFileSink(path string, rotation FileRotation) (*FileSink, error) {
+	var s = FileSink{path: path, rotation: rotation}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil { //nolint:mnd
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// Write implements the Sink interface.
+func (s *FileSink) Write(event []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var line = append(event, '\n')
+
+	if s.rotation.MaxSize > 0 && s.size > 0 && s.size+int64(len(line)) > s.rotation.MaxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(line)
+	s.size += int64(n)
+
+	return err
+}
+
+// Close implements the Sink interface.
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+// open opens the file for appending.
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o640) //nolint:mnd
+	if err != nil {
+		return fmt.Errorf("failed to open audit log file: %w", err)
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+
+		return err
+	}
+
+	s.file, s.size = f, stat.Size()
+
+	return nil
+}
+
+// rotate shifts the backups ("file.1" -> "file.2", ...), moves the current file to "file.1" (or removes it, if
+// no backups are kept), and opens the new one.
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	if s.rotation.MaxBackups <= 0 {
+		if err := os.Remove(s.path); err != nil {
+			return err
+		}
+
+		return s.open()
+	}
+
+	_ = os.Remove(fmt.Sprintf("%s.%d", s.path, s.rotation.MaxBackups)) // the oldest one
+
+	for i := s.rotation.MaxBackups - 1; i > 0; i-- {
+		if err := os.Rename(fmt.Sprintf("%s.%d", s.path, i), fmt.Sprintf("%s.%d", s.path, i+1)); err != nil &&
+			!os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	if err := os.Rename(s.path, s.path+".1"); err != nil {
+		return err
+	}
+
+	return s.open()
+}
+
+// SyslogSink writes the events to the syslog (with the "info" severity).
+type SyslogSink struct{ w *syslog.Writer }
+
+var _ Sink = (*SyslogSink)(nil) // ensure the interface is implemented
+
+// NewSyslogSink connects to the syslog daemon (empty network and address mean the local one).
+func NewSyslogSink(network, addr string) (*SyslogSink, error) {
+	w, err := syslog.Dial(network, addr, syslog.LOG_INFO|syslog.LOG_DAEMON, syslogTag)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+
+	return &SyslogSink{w: w}, nil
+}
+
+// Write implements the Sink interface.
+func (s *SyslogSink) Write(event []byte) error { return s.w.Info(string(event)) }
+
+// Close implements the Sink interface.
+func (s *SyslogSink) Close() error { return s.w.Close() }
+
+// HTTPSink POSTs every event (as a JSON object) to the endpoint.
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+var _ Sink = (*HTTPSink)(nil) // ensure the interface is implemented
+
+// NewHTTPSink creates a new sink that POSTs the events to the URL.
+func NewHTTPSink(url string, client *http.Client) *HTTPSink {
+	return &HTTPSink{url: url, client: client}
+}
+
+// Write implements the Sink interface.
+func (s *HTTPSink) Write(event []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), httpTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(event))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("audit endpoint returned status code %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Close implements the Sink interface.
+func (s *HTTPSink) Close() error { return nil }
@@ -0,0 +1,129 @@
+package audit_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"gh.tarampamp.am/video-dl-bot/internal/audit"
+)
+
+func TestFileSink(t *testing.T) {
+	t.Parallel()
+
+	var path = filepath.Join(t.TempDir(), "logs", "audit.jsonl")
+
+	sink, err := audit.NewFileSink(path, audit.FileRotation{MaxSize: 10, MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, event := range []string{`"1111"`, `"2222"`, `"3333"`, `"4444"`} { // 7 bytes per line
+		if err = sink.Write([]byte(event)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err = sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for file, want := range map[string]string{
+		path:        "\"4444\"\n",
+		path + ".1": "\"3333\"\n",
+		path + ".2": "\"2222\"\n", // the oldest one ("1111") is removed
+	} {
+		if got, _ := os.ReadFile(file); string(got) != want {
+			t.Errorf("%s: expected %q, got %q", filepath.Base(file), want, got)
+		}
+	}
+
+	if _, err = os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected no more backups, got %v", err)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu       sync.Mutex
+		received []string
+	)
+
+	var srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		received = append(received, string(body))
+		mu.Unlock()
+
+		if string(body) == `"fail"` {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	t.Cleanup(srv.Close)
+
+	sink, err := audit.OpenSink(srv.URL+"/events", audit.FileRotation{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = sink.Write([]byte(`{"foo":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = sink.Write([]byte(`"fail"`)); err == nil {
+		t.Error("expected an error for the non-2xx status code")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if len(received) != 2 || received[0] != `{"foo":1}` {
+		t.Errorf("unexpected events: %v", received)
+	}
+}
+
+func TestOpenSink(t *testing.T) {
+	t.Parallel()
+
+	for spec, wantErr := range map[string]bool{
+		"stdout":                              false,
+		"stderr":                              false,
+		"file://" + t.TempDir() + "/a.jsonl":  false,
+		filepath.Join(t.TempDir(), "b.jsonl"): false,
+		"":                                    true,
+		"http://":                             true,
+		"syslog+tcp://127.0.0.1:1":            true, // nothing listens there
+	} {
+		sink, err := audit.OpenSink(spec, audit.FileRotation{})
+
+		if wantErr {
+			if err == nil {
+				t.Errorf("%q: expected an error", spec)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", spec, err)
+
+			continue
+		}
+
+		_ = sink.Close()
+	}
+}
@@ -0,0 +1,172 @@
+package bot
+
+import (
+	"context"
+	"log/slog"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	tele "gopkg.in/telebot.v4"
+
+	"gh.tarampamp.am/video-dl-bot/internal/audit"
+	"gh.tarampamp.am/video-dl-bot/internal/i18n"
+	ytdlp "gh.tarampamp.am/video-dl-bot/internal/yt-dlp"
+)
+
+const (
+	albumMaxItems        = 10       // max number of the media in the Telegram album
+	albumMaxCaptionLen   = 1024     // max length of the media caption (in runes)
+	telegramMaxVideoSize = 50 << 20 // max size of the video, that the bots can send
+	telegramMaxPhotoSize = 10 << 20 // max size of the photo, that the bots can send
+)
+
+// albumExtractors are the yt-dlp extractors of the multi-entry posts (carousels, image posts and slideshows), whose
+// entries are downloaded and sent as the album. The other multi-entry URLs (e.g., the YouTube playlists or channels)
+// are downloaded as the single video, since the disk space is reserved for the probed entry only.
+var albumExtractors = []string{"instagram", "twitter", "tiktok"} //nolint:gochecknoglobals
+
+// isAlbumExtractor reports whether the entries of the multi-entry post from the extractor are sent as the album.
+func isAlbumExtractor(extractor string) bool {
+	return slices.ContainsFunc(albumExtractors, func(name string) bool { return strings.EqualFold(name, extractor) })
+}
+
+// sendAlbum sends the downloaded media (the entries of the multi-entry post, like the Instagram carousel, a single
+// image, or the video split per chapter) as the album, with the original caption. The files, that are too large for
+// Telegram, are skipped.
+func (b *Bot) sendAlbum(
+	ctx context.Context,
+	l i18n.Localizer,
+	user *tele.User,
+	userMsg *tele.Message,
+	userUrl *url.URL,
+	dl *ytdlp.Downloaded,
+	job *audit.Event,
+) error {
+	var (
+		album         tele.Album
+		size          int64
+		skipped       int
+		hasVideo      bool
+		logUserFields = []any{
+			slog.String("sender_name", user.FirstName),
+			slog.Int64("sender_id", user.ID),
+			slog.String("video_url", userUrl.String()),
+		}
+	)
+
+	for _, item := range dl.Items {
+		stat, err := os.Stat(item.Filepath)
+		if err != nil {
+			b.log.Error("failed to stat downloaded file", append(logUserFields,
+				slog.String("error", err.Error()),
+				slog.String("file_path", item.Filepath),
+			)...)
+
+			return b.reply(userMsg, l.T("file-not-available", nil))
+		}
+
+		var (
+			file    = tele.FromDisk(item.Filepath)
+			caption string
+		)
+
+		if item.Chapter != nil { // the video is split per chapter, each part is captioned with its time range
+			caption = chapterRange(*item.Chapter)
+		}
+
+		switch {
+		case item.Kind == ytdlp.MediaImage && stat.Size() <= telegramMaxPhotoSize:
+			album = append(album, &tele.Photo{File: file})
+		case item.Kind == ytdlp.MediaVideo && stat.Size() <= telegramMaxVideoSize:
+			album, hasVideo = append(album, &tele.Video{File: file, Streaming: true, Caption: caption}), true
+		default:
+			skipped++
+
+			continue
+		}
+
+		size += stat.Size()
+	}
+
+	if len(album) == 0 {
+		job.ErrorClass = "file-size"
+
+		return b.reply(userMsg, l.T("album-too-large", nil))
+	}
+
+	if dl.Items[0].Chapter == nil {
+		album.SetCaption(truncate(albumCaption(dl), albumMaxCaptionLen))
+	}
+
+	_ = b.react(user, userMsg, emojiUploading)
+
+	var action = tele.UploadingPhoto
+	if hasVideo {
+		action = actUploading
+	}
+
+	stopUploadingAction := b.setChatAction(ctx, user, action)
+	defer stopUploadingAction()
+
+	// the album is limited in size, so the larger ones (e.g., the video split per chapter) are sent in parts
+	for part := range slices.Chunk(album, albumMaxItems) {
+		if reply := canceledReply(ctx, l); reply != "" {
+			return b.reply(userMsg, reply) // the rest parts are not sent
+		}
+
+		if err := b.replyWithAlbum(userMsg, part); err != nil {
+			job.ErrorClass = "upload"
+
+			b.log.Error("failed to send the album to Telegram", append(logUserFields,
+				slog.String("error", err.Error()),
+				slog.Int("items", len(album)),
+				slog.Int64("size", size),
+			)...)
+
+			return b.reply(userMsg, l.T("send-failed", i18n.Vars{
+				"size":  formatSize(size),
+				"bytes": size,
+				"error": err.Error(),
+			}))
+		}
+	}
+
+	job.Outcome, job.Bytes = audit.OutcomeSuccess, size
+
+	b.saveHistory(user, userUrl, dl, size, "", "") // the album cannot be re-sent by a single file ID
+
+	if skipped > 0 {
+		return b.reply(userMsg, l.T("album-skipped", i18n.Vars{"count": skipped}))
+	}
+
+	return nil
+}
+
+// albumCaption returns the original caption of the post (the description, or the title, if it's empty).
+func albumCaption(dl *ytdlp.Downloaded) string {
+	if dl.Description != "" {
+		return dl.Description
+	}
+
+	return dl.Title
+}
+
+// replyWithAlbum sends the album either as a reply or a fresh message. A single media is sent as is (the album
+// must have at least two items).
+func (b *Bot) replyWithAlbum(to *tele.Message, album tele.Album) (err error) {
+	if len(album) == 1 {
+		if _, err = b.client.Reply(to, album[0]); err != nil {
+			_, err = b.client.Send(to.Sender, album[0])
+		}
+
+		return err
+	}
+
+	if _, err = b.client.SendAlbum(to.Chat, album, &tele.SendOptions{ReplyTo: to}); err != nil {
+		_, err = b.client.SendAlbum(to.Sender, album)
+	}
+
+	return err
+}
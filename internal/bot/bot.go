@@ -2,15 +2,28 @@ package bot
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	tele "gopkg.in/telebot.v4"
 
+	"gh.tarampamp.am/video-dl-bot/internal/audit"
+	"gh.tarampamp.am/video-dl-bot/internal/cookies"
 	"gh.tarampamp.am/video-dl-bot/internal/filestorage"
+	"gh.tarampamp.am/video-dl-bot/internal/i18n"
+	"gh.tarampamp.am/video-dl-bot/internal/proxy"
+	"gh.tarampamp.am/video-dl-bot/internal/stats"
+	"gh.tarampamp.am/video-dl-bot/internal/storage"
+	"gh.tarampamp.am/video-dl-bot/internal/workspace"
 	ytdlp "gh.tarampamp.am/video-dl-bot/internal/yt-dlp"
 )
 
@@ -30,14 +43,46 @@ const (
 type (
 	// Bot wraps the Telegram bot client.
 	Bot struct {
-		cookiesFile            string // path to the cookies file (if any)
-		jsRuntimes             string // JavaScript runtimes for yt-dlp (e.g., "node", "bun", "deno", "quickjs")
-		maxConcurrentDownloads uint   // maximum number of concurrent downloads allowed
+		cookies      *cookies.Store       // cookies files per domain (optional)
+		jsRuntimes   string               // JavaScript runtimes for yt-dlp (e.g., "node", "bun", "deno", "quickjs")
+		videoConvert ytdlp.Convert        // conversion of the downloaded videos for Telegram (none by default)
+		settings     Settings             // initial settings (can be changed at runtime, see Reload)
+		uploader     filestorage.Uploader // uploads the large files to the file hosting (filebin.net by default)
+		encryptFiles bool                 // pack the uploaded files into the password-protected zip archives
+		retryPolicy  ytdlp.RetryPolicy    // how the failed downloads are retried
+		updates      *updaterState        // yt-dlp updates (optional)
+		db           *storage.DB          // database for the download history (optional)
+		historyLimit int                  // max number of the history records per user (0 = unlimited)
+		audit        *audit.Logger        // audit log of the jobs (optional)
+		stats        *stats.Collector     // usage statistics
+		i18n         *i18n.Bundle         // translations of the replies
+		workspace    *workspace.Manager   // working directory for the downloads (optional)
+		drainTimeout time.Duration        // how long the running downloads are waited for on shutdown
+
+		live         atomic.Pointer[liveState]        // current settings and the related state
+		maintenance  atomic.Pointer[maintenanceState] // maintenance mode (persisted, if the database is set)
+		broadcasting atomic.Bool                      // a broadcast is in progress
+		knownUsers   sync.Map                         // IDs of the users, saved to the database since the start
+		languages    sync.Map                         // languages chosen by the users, if there is no database (by ID)
+		pending      sync.Map                         // download requests waiting for the confirmation (by token)
+		infoCards    sync.Map                         // raw metadata of the "/info" cards (by token)
+		chapterLists sync.Map                         // chapters of the "/chapters" lists (by token)
+		jobs         sync.Map                         // cancel functions of the running download jobs (by token)
+		drainer      *drainer                         // finishes the download jobs gracefully on shutdown
 
 		log    *slog.Logger
 		client *tele.Bot
 	}
 
+	// liveState holds the current settings together with the state that depends on them.
+	liveState struct {
+		Settings
+
+		lim              Limiter                // concurrent downloads limiter (recreated when the limit changes)
+		proxies          *proxy.Router          // proxies for yt-dlp (the pools state is reset on reload)
+		extractorProxies map[string]*proxy.Pool // proxies for the extractor site settings (by the extractor name)
+	}
+
 	// Option defines a functional option type for customizing the Bot.
 	Option func(*Bot)
 )
@@ -45,29 +90,73 @@ type (
 // WithLogger sets a custom logger for the Bot instance.
 func WithLogger(log *slog.Logger) Option { return func(b *Bot) { b.log = log } }
 
-// WithCookiesFile sets the path to a cookies file, used by yt-dlp for authenticated downloads.
-func WithCookiesFile(path string) Option { return func(b *Bot) { b.cookiesFile = path } }
+// WithCookies sets the cookies store, used by yt-dlp for authenticated downloads. It also enables the "/cookies"
+// command for the bot administrators.
+func WithCookies(store *cookies.Store) Option { return func(b *Bot) { b.cookies = store } }
 
 // WithJSRuntimes configures the JavaScript runtimes for yt-dlp, allowing support for sites that require JS execution.
 func WithJSRuntimes(runtimes string) Option { return func(b *Bot) { b.jsRuntimes = runtimes } }
 
-// WithMaxConcurrentDownloads limits the number of concurrent downloads the bot can handle.
-func WithMaxConcurrentDownloads(n uint) Option {
-	return func(b *Bot) { b.maxConcurrentDownloads = max(1, min(100, n)) } //nolint:mnd
-}
+// WithVideoConvert sets the conversion of the downloaded videos into the MP4 files, playable in Telegram (see
+// ytdlp.Convert). FFmpeg is required for the conversion.
+func WithVideoConvert(c ytdlp.Convert) Option { return func(b *Bot) { b.videoConvert = c } }
+
+// WithUploader sets the uploader of the files, that are too large for Telegram (e.g., the S3 or tus one).
+func WithUploader(u filestorage.Uploader) Option { return func(b *Bot) { b.uploader = u } }
+
+// WithEncryptedUploads enables the encryption of the files, uploaded to the file hosting: they are packed into the
+// AES-encrypted zip archives, and the random password is sent to the user along with the link.
+func WithEncryptedUploads() Option { return func(b *Bot) { b.encryptFiles = true } }
+
+// WithRetryPolicy sets the policy for retrying the failed downloads (with fallback formats, without cookies, etc.).
+func WithRetryPolicy(p ytdlp.RetryPolicy) Option { return func(b *Bot) { b.retryPolicy = p } }
+
+// WithAudit sets the audit logger, that receives a structured event per job (download request).
+func WithAudit(l *audit.Logger) Option { return func(b *Bot) { b.audit = l } }
+
+// WithTranslations sets the translations of the bot replies (e.g., with the message templates loaded; the embedded
+// catalogs are used by default).
+func WithTranslations(t *i18n.Bundle) Option { return func(b *Bot) { b.i18n = t } }
+
+// WithDrainTimeout sets how long the running downloads are waited for on shutdown, before they are canceled (zero
+// means they are canceled immediately).
+func WithDrainTimeout(d time.Duration) Option { return func(b *Bot) { b.drainTimeout = d } }
+
+// WithSettings sets the initial bot settings (access lists, limits, custom replies, etc.).
+func WithSettings(s Settings) Option { return func(b *Bot) { b.settings = s } }
 
 // NewBot creates and returns a new instance of Bot.
 func NewBot(ctx context.Context, token string, opts ...Option) (*Bot, error) {
 	const pollerTimeout = 10 * time.Second // default timeout for the long poller
 
 	var bot = Bot{ // set default values
-		log: slog.Default(),
+		log:         slog.Default(),
+		retryPolicy: ytdlp.DefaultRetryPolicy(),
+		stats:       stats.New(),
+		uploader:    filestorage.NewFileBin(),
 	}
 
 	for _, opt := range opts {
 		opt(&bot)
 	}
 
+	if err := bot.Reload(bot.settings); err != nil {
+		return nil, err
+	}
+
+	if err := bot.loadMaintenance(); err != nil {
+		return nil, err
+	}
+
+	if bot.i18n == nil {
+		translations, err := i18n.New()
+		if err != nil {
+			return nil, err
+		}
+
+		bot.i18n = translations
+	}
+
 	client, err := tele.NewBot(tele.Settings{
 		Token:  token,
 		Poller: &tele.LongPoller{Timeout: pollerTimeout},
@@ -85,14 +174,52 @@ func NewBot(ctx context.Context, token string, opts ...Option) (*Bot, error) {
 	}
 
 	bot.client = client
+	bot.drainer = newDrainer(ctx)
 
-	var lim = make(Limiter, bot.maxConcurrentDownloads)
+	// the download jobs are not canceled together with the context, but drained on shutdown (see Start)
+	var jobsCtx = bot.drainer.ctx
+
+	// deny access for the users that are not allowed to use the bot
+	client.Use(bot.accessMiddleware())
+
+	if bot.db != nil {
+		client.Use(bot.usersMiddleware()) // remember the users for the broadcasts
+	}
 
 	// register command and message handlers
 	client.Handle("/start", bot.handleStartCommand())
 	client.Handle("test", bot.handleTestCommand())
+	client.Handle("/stats", bot.handleStatsCommand(ctx), bot.adminOnly())
+	client.Handle("/maintenance", bot.handleMaintenanceCommand(), bot.adminOnly())
+	client.Handle(&btnConfirmDownload, bot.handleConfirmDownloadButton(jobsCtx))
+	client.Handle(&btnCancelDownload, bot.handleCancelDownloadButton())
+	client.Handle("/info", bot.handleInfoCommand(jobsCtx))
+	client.Handle(&btnInfoJSON, bot.handleInfoJSONButton())
+	client.Handle("/chapters", bot.handleChaptersCommand(jobsCtx))
+	client.Handle(&btnChapter, bot.handleChapterButton(jobsCtx))
+	client.Handle(&btnChaptersSplit, bot.handleChaptersSplitButton(jobsCtx))
+	client.Handle("/cancel", bot.handleCancelCommand())
+	client.Handle(&btnCancelJob, bot.handleCancelJobButton())
+
+	if bot.updates != nil {
+		client.Handle("/update_ytdlp", bot.handleUpdateCommand(ctx), bot.adminOnly())
+	}
+
+	if bot.db != nil {
+		bot.registerHistoryHandlers()
+		bot.registerLinksHandlers(ctx)
+		client.Handle("/broadcast", bot.handleBroadcastCommand(ctx), bot.adminOnly())
+	}
+
+	client.Handle("/language", bot.handleLanguageCommand())
+	client.Handle(&btnLanguage, bot.handleLanguageButton())
+
+	if bot.cookies != nil {
+		client.Handle(cookiesCommand, bot.handleCookiesCommand(), bot.adminOnly())
+		client.Handle(tele.OnDocument, bot.handleDocument())
+	}
 
-	var msgHandler = bot.handleMessages(ctx, lim)
+	var msgHandler = bot.handleMessages(jobsCtx)
 
 	// handle multiple event types with the same message handler
 	for _, event := range [...]string{tele.OnText, tele.OnForward, tele.OnReply} {
@@ -102,16 +229,90 @@ func NewBot(ctx context.Context, token string, opts ...Option) (*Bot, error) {
 	return &bot, nil
 }
 
+// Reload applies the new settings at runtime. Downloads that are already in progress are not affected.
+func (b *Bot) Reload(s Settings) error {
+	router, err := s.proxyRouter()
+	if err != nil {
+		return err
+	}
+
+	pools, err := s.extractorPools()
+	if err != nil {
+		return err
+	}
+
+	var state = liveState{Settings: s.normalize(), proxies: router, extractorProxies: pools}
+
+	// keep the limiter if the limit hasn't changed, otherwise the new one is used for the new downloads only,
+	// and the downloads in progress release the slots of the old one
+	if prev := b.live.Load(); prev != nil && prev.MaxConcurrentDownloads == state.MaxConcurrentDownloads {
+		state.lim = prev.lim
+	} else {
+		state.lim = make(Limiter, state.MaxConcurrentDownloads)
+	}
+
+	b.live.Store(&state)
+
+	return nil
+}
+
+// state returns the current settings and the related state.
+func (b *Bot) state() *liveState { return b.live.Load() }
+
+// accessMiddleware returns a middleware that denies access for the users that are not allowed to use the bot.
+func (b *Bot) accessMiddleware() tele.MiddlewareFunc {
+	return func(next tele.HandlerFunc) tele.HandlerFunc {
+		return func(c tele.Context) error {
+			var user = c.Sender()
+
+			if user == nil || b.state().IsAllowed(user.ID) {
+				return next(c)
+			}
+
+			b.log.Info("access denied",
+				slog.String("sender_name", user.FirstName),
+				slog.Int64("sender_id", user.ID),
+			)
+
+			if msg := c.Message(); msg != nil {
+				return b.reply(msg, b.state().Message(MsgAccessDenied, b.tr(user).T("access-denied", nil)))
+			}
+
+			return nil
+		}
+	}
+}
+
 // Start begins polling updates from Telegram. Blocks until context is canceled.
 func (b *Bot) Start(ctx context.Context) {
 	var stopped = make(chan struct{})
 
+	if b.cookies != nil {
+		go b.watchCookiesExpiry(ctx)
+		go b.rescanCookies(ctx)
+	}
+
+	if b.updates != nil {
+		go b.scheduleYtDlpUpdates(ctx)
+	}
+
+	if b.workspace != nil {
+		b.cleanWorkspace(0) // the files left by the previous run (before any download starts, so none is removed)
+
+		go b.sweepWorkspace(ctx)
+	}
+
+	if b.db != nil {
+		go b.expireLinks(ctx)
+	}
+
 	// stop bot when context is canceled
 	go func() {
 		defer close(stopped)
 
 		<-ctx.Done()
-		b.client.Stop()
+		b.client.Stop() // no new updates are received since now
+		b.drain()
 	}()
 
 	// blocking call that listens to updates
@@ -123,199 +324,554 @@ func (b *Bot) Start(ctx context.Context) {
 // handleStartCommand returns a handler for the "/start" command.
 func (b *Bot) handleStartCommand() tele.HandlerFunc {
 	return func(c tele.Context) (err error) {
-		return b.reply(c.Message(), fmt.Sprintf(`Hello %s! I can help you download videos from hundreds of websites.
+		var user = c.Sender()
 
-Please send or forward me a video URL, and I'll do my best to download it for you!`,
-			c.Sender().FirstName,
-		))
+		return b.reply(c.Message(), b.state().Message(MsgStart, b.tr(user).T("start", i18n.Vars{"name": user.FirstName})))
 	}
 }
 
 // handleTestCommand returns a handler for a simple "test" command.
 func (b *Bot) handleTestCommand() tele.HandlerFunc {
 	return func(c tele.Context) (err error) {
-		return b.reply(
-			c.Message(),
-			"Just send me a video URL or forward a message containing a link, "+
-				"and I'll download it - that would be the perfect test!",
-		)
+		return b.reply(c.Message(), b.tr(c.Sender()).T("test", nil))
 	}
 }
 
 // handleMessages processes incoming user messages and attempts to download video content.
-func (b *Bot) handleMessages(pCtx context.Context, lim Limiter) tele.HandlerFunc { //nolint:funlen
-	const errWrongMessageReplyMd2 = "Please provide a valid video link\\." +
-		"\n" +
-		"\n" +
-		"Examples:\n" +
-		"\\- `https://www\\.youtube\\.com/watch?v=dQw4w9WgXcQ`\n" +
-		"\\- `youtu\\.be/dQw4w9WgXcQ`\n" +
-		"\n" +
-		"You can also share a link to an Instagram reel, TikTok video, or any other video you'd like to download\\. " +
-		"Hundreds of sites are supported, so feel free to give it a try\\!"
-
+func (b *Bot) handleMessages(pCtx context.Context) tele.HandlerFunc {
 	return func(c tele.Context) error {
-		ctx, cancel := context.WithCancel(pCtx)
-		defer cancel()
+		return b.processDownload(pCtx, c, downloadRequest{msg: c.Message(), text: c.Text()})
+	}
+}
 
-		var (
-			user, userMsg       = c.Sender(), c.Message()
-			userUrl, userUrlErr = ExtractLink(c.Text())
+// processDownload downloads the video by the link from the request, and sends it to the user.
+func (b *Bot) processDownload( //nolint:funlen,gocognit
+	pCtx context.Context,
+	c tele.Context,
+	req downloadRequest,
+) error {
+	// during the maintenance, only the administrators can download
+	if notice, enabled := b.maintenanceNotice(b.tr(c.Sender())); enabled && !b.state().IsAdmin(c.Sender().ID) {
+		return b.reply(req.msg, notice)
+	}
+
+	// during the shutdown, the new requests are not accepted
+	jobDone, accepted := b.drainer.Accept()
+	if !accepted {
+		return b.reply(req.msg, b.tr(c.Sender()).T("shutting-down", nil))
+	}
+	defer jobDone()
+
+	ctx, cancel := context.WithCancelCause(pCtx)
+	defer cancel(nil)
+
+	var (
+		state               = b.state()
+		user, userMsg       = c.Sender(), req.msg
+		l                   = b.tr(user)
+		userUrl, userUrlErr = ExtractLink(req.text)
+		job                 = audit.Event{ // the outcome and the details are updated as the job goes on
+			Time:      time.Now(),
+			UserID:    user.ID,
+			ChatID:    c.Chat().ID,
+			Durations: make(map[string]time.Duration),
+			Outcome:   audit.OutcomeFailed,
+		}
+		tracker = b.stats.Track()
+		viaLink bool // the video is uploaded to the file hosting
+	)
+
+	defer func() { b.finishJob(ctx, &job, tracker, viaLink) }()
+
+	// invalid link - inform user and react
+	if userUrlErr != nil {
+		job.Outcome, job.ErrorClass = audit.OutcomeRejected, "invalid-link"
+
+		_ = b.react(user, userMsg, emojiBadRequest)
+
+		b.log.Info("received invalid link from user",
+			slog.String("sender_name", user.FirstName),
+			slog.Int64("sender_id", user.ID),
+			slog.String("message_text", req.text),
 		)
 
-		// invalid link - inform user and react
-		if userUrlErr != nil {
-			_ = b.react(user, userMsg, emojiBadRequest)
+		return b.reply(userMsg, state.Message(MsgInvalidLink, l.T("invalid-link-md", nil)), &tele.SendOptions{
+			ParseMode:             tele.ModeMarkdownV2,
+			DisableWebPagePreview: true,
+			DisableNotification:   true,
+		})
+	}
+
+	b.log.Info("received video download request",
+		slog.String("sender_name", user.FirstName),
+		slog.Int64("sender_id", user.ID),
+		slog.String("video_url", userUrl.String()),
+	)
+
+	job.URL = userUrl.String()
+
+	// the site may be denied by the domain, there is no need to wait for the free slot
+	var site = state.Site(userUrl.Hostname())
+
+	if site != nil && site.Deny {
+		job.Outcome, job.ErrorClass = audit.OutcomeRejected, "site-denied"
+
+		return b.reply(userMsg, l.T("site-denied", nil))
+	}
+
+	// the job can be canceled by the user since now (while waiting for the free slot too)
+	jobToken, unregisterJob := b.registerJob(user, cancel)
+	defer unregisterJob()
+
+	// limit concurrent downloads via semaphore (the queued jobs are canceled, when the shutdown starts)
+	var leaveQueue = b.drainer.WhileQueued(cancel)
+
+	if err := state.lim.Acquire(ctx); err != nil {
+		leaveQueue()
 
-			b.log.Info("received invalid link from user",
+		switch {
+		case canceledByUser(ctx):
+			return b.reply(userMsg, l.T("download-canceled", nil))
+		case shuttingDown(ctx):
+			return b.reply(userMsg, l.T("shutting-down", nil))
+		}
+
+		return err
+	}
+	defer state.lim.Release()
+
+	if leaveQueue(); shuttingDown(ctx) { // the shutdown has started right after the slot is acquired
+		return b.reply(userMsg, l.T("shutting-down", nil))
+	}
+
+	tracker.Start()
+
+	job.Durations["queue"] = time.Since(job.Time)
+
+	// clear any previous reactions once we're done
+	defer func() { _ = b.clearReactions(user, userMsg) }()
+
+	// indicate download in progress
+	_ = b.react(user, userMsg, emojiDownloading)
+	stopDownloadingAction := b.setChatAction(ctx, user, actDownloading)
+
+	defer stopDownloadingAction()
+
+	var ytDlpOpts = b.ytDlpOptions(state, site, userUrl.Hostname())
+
+	// failed reports the failed download (or probe) to the user
+	var failed = func(dlErr error) error {
+		if reply := canceledReply(ctx, l); reply != "" {
+			b.log.Info("the download is canceled",
+				slog.String("reason", context.Cause(ctx).Error()),
 				slog.String("sender_name", user.FirstName),
 				slog.Int64("sender_id", user.ID),
-				slog.String("message_text", c.Text()),
+				slog.String("video_url", userUrl.String()),
 			)
 
-			return b.reply(userMsg, errWrongMessageReplyMd2, &tele.SendOptions{
-				ParseMode:             tele.ModeMarkdownV2,
-				DisableWebPagePreview: true,
-				DisableNotification:   true,
-			})
+			return b.reply(userMsg, reply)
 		}
 
-		b.log.Info("received video download request",
+		job.ErrorClass = ytdlp.ErrorClass(dlErr)
+
+		b.log.Error("failed to download video",
+			slog.String("error", dlErr.Error()),
+			slog.String("error_class", ytdlp.ErrorClass(dlErr)),
 			slog.String("sender_name", user.FirstName),
 			slog.Int64("sender_id", user.ID),
 			slog.String("video_url", userUrl.String()),
 		)
 
-		// limit concurrent downloads via semaphore
-		if err := lim.Acquire(ctx); err != nil {
-			return err
+		if errors.Is(dlErr, ytdlp.ErrExtractor) {
+			b.reportExtractorError(pCtx)
 		}
-		defer lim.Release()
-
-		// clear any previous reactions once we're done
-		defer func() { _ = b.clearReactions(user, userMsg) }()
-
-		// indicate download in progress
-		_ = b.react(user, userMsg, emojiDownloading)
-		stopDownloadingAction := b.setChatAction(ctx, user, actDownloading)
 
-		defer stopDownloadingAction()
+		var reply = state.Message(MsgDownloadFailed, l.T("download-failed", nil))
 
-		var ytDlpOpts []ytdlp.Option
+		var retryErr *ytdlp.RetryError
 
-		if b.cookiesFile != "" {
-			ytDlpOpts = append(ytDlpOpts, ytdlp.WithCookiesFile(b.cookiesFile))
+		if errors.As(dlErr, &retryErr) {
+			reply += "\n\n" + l.T("download-attempts", nil) + "\n" + retryErr.Summary()
 		}
 
-		if b.jsRuntimes != "" {
-			ytDlpOpts = append(ytDlpOpts, ytdlp.WithJSRuntimes(b.jsRuntimes))
+		if errors.Is(dlErr, ytdlp.ErrFileSize) {
+			job.Outcome = audit.OutcomeRejected
+			reply = l.T("file-size-limit", nil)
 		}
 
-		// download the video
-		dl, dlErr := ytdlp.Download(ctx, userUrl.String(), ytDlpOpts...)
-		if dlErr != nil {
-			b.log.Error("failed to download video",
-				slog.String("error", dlErr.Error()),
-				slog.String("sender_name", user.FirstName),
-				slog.Int64("sender_id", user.ID),
-				slog.String("video_url", userUrl.String()),
-			)
+		return b.reply(userMsg, reply)
+	}
+
+	// probe the video to check the site policy, that depends on the video metadata (extractor, duration, etc.)
+	var probeStart = time.Now()
+
+	info, probeErr := b.probe(ctx, user, userUrl.String(), state.pool(userUrl.Hostname(), site), ytDlpOpts)
+
+	job.Durations["probe"] = time.Since(probeStart)
+
+	if probeErr != nil {
+		return failed(probeErr)
+	}
 
-			return b.reply(userMsg, "❌ Failed to download video")
+	job.Extractor = info.Extractor
+
+	// a single chapter is checked against the policy by its own duration (and the estimated size)
+	if req.section != nil {
+		var length = req.section.End - req.section.Start
+
+		if info.Duration > 0 {
+			info.FileSize = int64(float64(info.FileSize) * float64(length) / float64(info.Duration))
 		}
 
-		stopDownloadingAction()
+		info.Duration = length
+	}
+
+	site = state.Policy(userUrl.Hostname(), info.Extractor)
+
+	if class, reply := state.policyRejection(l, site, info); class != "" {
+		job.Outcome, job.ErrorClass = audit.OutcomeRejected, class
+
+		b.log.Info("the video is rejected by the site policy",
+			slog.String("reason", class),
+			slog.String("extractor", info.Extractor),
+			slog.Duration("duration", info.Duration),
+			slog.Bool("is_live", info.IsLive),
+			slog.String("sender_name", user.FirstName),
+			slog.Int64("sender_id", user.ID),
+			slog.String("video_url", userUrl.String()),
+		)
+
+		return b.reply(userMsg, reply)
+	}
+
+	// long videos and live streams are downloaded only after the user confirms it
+	if class, question, button := state.confirmation(l, site, info); class != "" && !req.confirmed {
+		job.Outcome, job.ErrorClass = audit.OutcomeRejected, class
+
+		return b.askConfirmation(l, req, question, button)
+	}
+
+	ytDlpOpts = b.ytDlpOptions(state, site, userUrl.Hostname())
+
+	// only the entries of the posts are downloaded (not the playlists or channels, that could be huge)
+	if isAlbumExtractor(info.Extractor) {
+		ytDlpOpts = append(ytDlpOpts, ytdlp.WithMaxItems(albumMaxItems))
+	}
+
+	if info.IsLive {
+		ytDlpOpts = append(ytDlpOpts, ytdlp.WithLiveRecording(state.recordDuration(site)))
+	}
+
+	switch {
+	case req.section != nil:
+		ytDlpOpts = append(ytDlpOpts, ytdlp.WithSection(req.section.Start, req.section.End))
+	case req.splitChapters:
+		ytDlpOpts = append(ytDlpOpts, ytdlp.WithSplitChapters())
+	}
+
+	// reserve the disk space, so the concurrent downloads cannot fill the disk
+	if b.workspace != nil {
+		reservation, resErr := b.workspace.Reserve(state.reservationSize(site, info, b.encryptFiles))
+		if resErr != nil {
+			job.Outcome, job.ErrorClass = audit.OutcomeRejected, "disk-space"
 
-		// stat the file to get size info
-		stat, statErr := os.Stat(dl.Filepath)
-		if statErr != nil {
-			b.log.Error("failed to stat downloaded video file",
-				slog.String("error", statErr.Error()),
-				slog.String("file_path", dl.Filepath),
+			b.log.Warn("not enough disk space for the download",
+				slog.String("error", resErr.Error()),
 				slog.String("sender_name", user.FirstName),
 				slog.Int64("sender_id", user.ID),
 				slog.String("video_url", userUrl.String()),
 			)
 
-			return b.reply(userMsg, "❌ Downloaded video file not available")
+			return b.reply(userMsg, l.T("disk-space", nil))
 		}
 
-		b.log.Debug("successfully downloaded video",
-			slog.String("file_path", dl.Filepath),
+		defer reservation.Release() // after the downloaded file is removed (the defers are called in reverse order)
+	}
+
+	// the status message with the "Cancel" button is kept until the job is finished (uploading can be canceled too)
+	defer b.sendJobStatus(userMsg, l.T("download-status", nil), l.T("cancel-button", nil), jobToken)()
+
+	// download the video
+	var downloadStart = time.Now()
+
+	dl, dlErr := b.download(ctx, user, userUrl.String(), state.pool(userUrl.Hostname(), site), ytDlpOpts)
+
+	job.Durations["download"] = time.Since(downloadStart)
+
+	if dlErr != nil {
+		return failed(dlErr)
+	}
+
+	defer func() { _ = dl.Cleanup() }() // clean up the downloaded file after sending
+
+	stopDownloadingAction()
+
+	job.Extractor, job.Format = dl.Extractor, dl.FormatID
+
+	// the multi-entry posts (e.g., the Instagram carousels) and the images are sent as the album
+	if !dl.IsVideo() {
+		return b.sendAlbum(ctx, l, user, userMsg, userUrl, dl, &job)
+	}
+
+	var video = dl.Items[0]
+
+	// stat the file to get size info
+	stat, statErr := os.Stat(video.Filepath)
+	if statErr != nil {
+		b.log.Error("failed to stat downloaded video file",
+			slog.String("error", statErr.Error()),
+			slog.String("file_path", video.Filepath),
 			slog.String("sender_name", user.FirstName),
 			slog.Int64("sender_id", user.ID),
 			slog.String("video_url", userUrl.String()),
-			slog.Int64("file_size", stat.Size()),
 		)
 
-		defer func() { _ = os.Remove(dl.Filepath) }() // clean up the downloaded file after sending
+		return b.reply(userMsg, l.T("file-not-available", nil))
+	}
 
-		// open the downloaded file
-		fp, fpErr := os.Open(dl.Filepath)
-		if fpErr != nil {
-			return fpErr
-		}
+	b.log.Debug("successfully downloaded video",
+		slog.String("file_path", video.Filepath),
+		slog.String("sender_name", user.FirstName),
+		slog.Int64("sender_id", user.ID),
+		slog.String("video_url", userUrl.String()),
+		slog.Int64("file_size", stat.Size()),
+	)
+
+	// open the downloaded file
+	fp, fpErr := os.Open(video.Filepath)
+	if fpErr != nil {
+		return fpErr
+	}
+
+	defer func() { _ = fp.Close() }()
+
+	// indicate upload in progress
+	_ = b.react(user, userMsg, emojiUploading)
+	stopUploadingAction := b.setChatAction(ctx, user, actUploading)
 
-		defer func() { _ = fp.Close() }()
+	defer stopUploadingAction()
 
-		// indicate upload in progress
-		_ = b.react(user, userMsg, emojiUploading)
-		stopUploadingAction := b.setChatAction(ctx, user, actUploading)
+	var (
+		fileSizeMb  = float64(stat.Size()) / 1024 / 1024 // file size in MB
+		uploadStart = time.Now()
+	)
 
-		defer stopUploadingAction()
+	defer func() { job.Durations["upload"] = time.Since(uploadStart) }()
 
-		var fileSizeMb = float64(stat.Size()) / 1024 / 1024 // file size in MB
+	// telegram upload limit is 50MB
+	if fileSizeMb <= 50 { //nolint:mnd
+		sent, err := b.replyWithVideo(userMsg, tele.Video{
+			File:    tele.FromReader(fp),
+			Caption: truncate(videoCaption(dl, req.section), albumMaxCaptionLen),
+		})
+		if err != nil {
+			job.ErrorClass = "upload"
 
-		// telegram upload limit is 50MB
-		if fileSizeMb <= 50 { //nolint:mnd
-			if err := b.replyWithVideo(userMsg, tele.Video{File: tele.FromReader(fp)}); err != nil {
-				b.log.Error("failed to upload video to Telegram",
-					slog.String("error", err.Error()),
+			b.log.Error("failed to upload video to Telegram",
+				slog.String("error", err.Error()),
+				slog.Int64("file_size", stat.Size()),
+				slog.String("sender_name", user.FirstName),
+				slog.Int64("sender_id", user.ID),
+				slog.String("video_url", userUrl.String()),
+			)
+
+			return b.reply(userMsg, l.T("send-failed", i18n.Vars{
+				"size":  formatSize(stat.Size()),
+				"bytes": stat.Size(),
+				"error": err.Error(),
+			}))
+		}
+
+		job.Outcome, job.Bytes = audit.OutcomeSuccess, stat.Size()
+
+		if sent != nil && sent.Video != nil {
+			b.saveHistory(user, userUrl, dl, stat.Size(), sent.Video.FileID, "")
+		}
+	} else {
+		// upload to file hosting if file is too large
+		var (
+			upload   io.ReadSeeker = fp
+			filename               = uploadFilename(dl.Title, filepath.Ext(video.Filepath))
+			password string        // password of the encrypted archive
+		)
+
+		if b.encryptFiles {
+			archive, pass, encErr := encryptUpload(fp, stat.Size(), filename)
+			if encErr != nil {
+				job.ErrorClass = "encrypt"
+
+				b.log.Error("failed to encrypt video file",
+					slog.String("error", encErr.Error()),
 					slog.Int64("file_size", stat.Size()),
 					slog.String("sender_name", user.FirstName),
 					slog.Int64("sender_id", user.ID),
 					slog.String("video_url", userUrl.String()),
 				)
 
-				return b.reply(userMsg, fmt.Sprintf(
-					"❌ Failed to send video (%.2f MB): %s",
-					fileSizeMb,
-					err.Error(),
-				))
+				return b.reply(userMsg, l.T("hosting-upload-failed", nil))
 			}
-		} else {
-			// upload to file hosting if file is too large
-			fileUrl, urlErr := filestorage.UploadToFileBin(ctx, fp, fmt.Sprintf("video%s", filepath.Ext(dl.Filepath)))
-			if urlErr != nil {
-				b.log.Error("failed to upload video file to file hosting",
-					slog.String("error", urlErr.Error()),
-					slog.Int64("file_size", stat.Size()),
-					slog.String("sender_name", user.FirstName),
-					slog.Int64("sender_id", user.ID),
-					slog.String("video_url", userUrl.String()),
-				)
 
-				return b.reply(userMsg, "❌ Failed to upload video to file hosting")
+			defer func() { _ = archive.Close() }()
+
+			upload, password = archive, pass
+			filename = strings.TrimSuffix(filename, filepath.Ext(filename)) + ".zip"
+		}
+
+		progress, stopProgress := b.trackUploadProgress(ctx, l, userMsg)
+
+		obj, urlErr := b.uploader.Upload(ctx, upload, filename, progress)
+
+		stopProgress()
+
+		if urlErr != nil {
+			if reply := canceledReply(ctx, l); reply != "" {
+				return b.reply(userMsg, reply)
 			}
 
-			return b.replyWithLink(
-				userMsg,
-				fmt.Sprintf(
-					"[Your video](%s) is ready for download _\\(the link will expire in a couple of days\\)_:",
-					userUrl.String(),
-				),
-				fmt.Sprintf("🚀 Download video (%.2f MB)", fileSizeMb),
-				fileUrl,
-				&tele.SendOptions{
-					ParseMode:             tele.ModeMarkdownV2,
-					DisableWebPagePreview: true,
-				},
+			job.ErrorClass = "upload"
+
+			b.log.Error("failed to upload video file to file hosting",
+				slog.String("error", urlErr.Error()),
+				slog.Int64("file_size", stat.Size()),
+				slog.String("sender_name", user.FirstName),
+				slog.Int64("sender_id", user.ID),
+				slog.String("video_url", userUrl.String()),
 			)
+
+			return b.reply(userMsg, l.T("hosting-upload-failed", nil))
+		}
+
+		job.Outcome, job.Bytes, viaLink = audit.OutcomeSuccess, stat.Size(), true
+
+		b.saveHistory(user, userUrl, dl, stat.Size(), "", obj.URL)
+
+		var (
+			expiresAt = b.saveLink(user, userUrl, dl, stat.Size(), obj)
+			vars      = i18n.Vars{"url": userUrl.String(), "expires": expiresAt.UTC().Format(linkExpiryFormat)}
+			text      = l.T("hosting-link-md", vars)
+		)
+
+		if password != "" {
+			vars["password"] = password
+			text = l.T("hosting-link-encrypted-md", vars)
 		}
 
-		stopUploadingAction()
+		return b.replyWithLink(
+			userMsg,
+			text,
+			l.T("hosting-button", i18n.Vars{"size": formatSize(stat.Size()), "bytes": stat.Size()}),
+			obj.URL,
+			&tele.SendOptions{
+				ParseMode:             tele.ModeMarkdownV2,
+				DisableWebPagePreview: true,
+			},
+		)
+	}
+
+	stopUploadingAction()
+
+	return nil
+}
+
+// download downloads the video using the retry policy and the proxies from the pool. If the site rate-limits or
+// blocks the current proxy, the next one from the pool is tried (each proxy is tried once at most).
+func (b *Bot) download(
+	ctx context.Context,
+	user *tele.User,
+	videoUrl string,
+	pool *proxy.Pool,
+	opts []ytdlp.Option,
+) (*ytdlp.Downloaded, error) {
+	var (
+		policy = b.userRetryPolicy(user, videoUrl)
+		dl     *ytdlp.Downloaded
+	)
+
+	err := b.withProxies(pool, videoUrl, func(proxyUrl string) (dlErr error) {
+		dl, dlErr = ytdlp.DownloadWithRetry(ctx, videoUrl, policy, append(slices.Clip(opts), ytdlp.WithProxy(proxyUrl))...)
+
+		return dlErr
+	})
+
+	return dl, err
+}
+
+// userRetryPolicy returns the retry policy for the user request, that logs the failed attempts.
+func (b *Bot) userRetryPolicy(user *tele.User, videoUrl string) ytdlp.RetryPolicy {
+	var policy = b.retryPolicy
+
+	policy.OnAttempt = func(a ytdlp.Attempt) {
+		b.log.Warn("download attempt failed",
+			slog.Int("attempt", a.Number),
+			slog.String("step", a.Step),
+			slog.String("error_class", ytdlp.ErrorClass(a.Err)),
+			slog.String("error", a.Err.Error()),
+			slog.Duration("duration", a.Duration),
+			slog.String("sender_name", user.FirstName),
+			slog.Int64("sender_id", user.ID),
+			slog.String("video_url", videoUrl),
+		)
+	}
+
+	return policy
+}
+
+// withProxies calls the function with the current proxy of the pool, switching to the next one (and calling the
+// function again) when the site blocks the current proxy, until all the proxies are tried.
+func (b *Bot) withProxies(pool *proxy.Pool, videoUrl string, fn func(proxyUrl string) error) error {
+	for attempt := 1; ; attempt++ {
+		var current = pool.Current()
+
+		err := fn(current)
+		if err == nil {
+			return nil
+		}
+
+		if !errors.Is(err, ytdlp.ErrRateLimited) && !errors.Is(err, ytdlp.ErrForbidden) {
+			return err
+		}
+
+		pool.Fail(current)
+
+		if attempt >= pool.Len() {
+			return err
+		}
+
+		b.log.Warn("the site blocks the proxy, trying the next one",
+			slog.String("error", err.Error()),
+			slog.String("proxy", proxy.Redact(current)),
+			slog.String("next_proxy", proxy.Redact(pool.Current())),
+			slog.String("video_url", videoUrl),
+		)
+	}
+}
+
+// finishJob records the finished job to the statistics and the audit log (if it's enabled).
+func (b *Bot) finishJob(ctx context.Context, job *audit.Event, tracker *stats.Tracker, viaLink bool) {
+	switch {
+	case job.Outcome == audit.OutcomeFailed && ctx.Err() != nil:
+		job.Outcome, job.ErrorClass = audit.OutcomeCanceled, "canceled"
+	case job.Outcome == audit.OutcomeFailed && job.ErrorClass == "":
+		job.ErrorClass = "other"
+	}
+
+	job.Durations["total"] = time.Since(job.Time)
+
+	tracker.Finish(stats.Job{
+		UserID:     job.UserID,
+		Extractor:  job.Extractor,
+		Outcome:    string(job.Outcome),
+		ErrorClass: job.ErrorClass,
+		Bytes:      job.Bytes,
+		Link:       viaLink,
+	})
+
+	if b.audit == nil {
+		return
+	}
 
-		return nil
+	if err := b.audit.Log(*job); err != nil {
+		b.log.Warn("failed to queue the audit event", slog.String("error", err.Error()))
 	}
 }
 
@@ -329,11 +885,11 @@ func (b *Bot) reply(to *tele.Message, msg string, opts ...any) (err error) {
 	return
 }
 
-// replyWithVideo sends a video file either as a reply or a fresh message.
-func (b *Bot) replyWithVideo(to *tele.Message, v tele.Video) (err error) {
-	_, err = b.client.Reply(to, &v)
+// replyWithVideo sends a video file either as a reply or a fresh message, and returns the sent message.
+func (b *Bot) replyWithVideo(to *tele.Message, v tele.Video) (sent *tele.Message, err error) {
+	sent, err = b.client.Reply(to, &v)
 	if err != nil {
-		_, err = b.client.Send(to.Sender, &v)
+		sent, err = b.client.Send(to.Sender, &v)
 	}
 
 	return
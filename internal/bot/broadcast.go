@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"gh.tarampamp.am/video-dl-bot/internal/i18n"
+)
+
+const (
+	broadcastRate       = 25 // messages per second (Telegram allows about 30 messages per second to different users)
+	broadcastMaxRetries = 3  // max number of the retries per user, when Telegram asks to slow down
+)
+
+// broadcastReport is the delivery report of the broadcast.
+type broadcastReport struct {
+	Total, Delivered, Unreachable, Failed int
+}
+
+// usersMiddleware returns a middleware that remembers the users of the bot (the broadcast recipients).
+func (b *Bot) usersMiddleware() tele.MiddlewareFunc {
+	return func(next tele.HandlerFunc) tele.HandlerFunc {
+		return func(c tele.Context) error {
+			if user := c.Sender(); user != nil && !user.IsBot {
+				if _, known := b.knownUsers.Load(user.ID); !known { // avoid writing to the database on every message
+					if _, err := b.db.TouchUser(user.ID); err != nil {
+						b.log.Error("failed to save the user",
+							slog.String("error", err.Error()),
+							slog.Int64("sender_id", user.ID),
+						)
+					} else {
+						b.knownUsers.Store(user.ID, struct{}{})
+					}
+				}
+			}
+
+			return next(c)
+		}
+	}
+}
+
+// handleBroadcastCommand returns a handler for the "/broadcast <text>" command, that sends the text to all the
+// known users. The sending is done in the background, and the delivery report is sent back when it's done.
+func (b *Bot) handleBroadcastCommand(ctx context.Context) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		var (
+			msg  = c.Message()
+			l    = b.tr(c.Sender())
+			text = strings.TrimSpace(msg.Payload)
+		)
+
+		if text == "" {
+			return b.reply(msg, l.T("broadcast-usage", nil))
+		}
+
+		if !b.broadcasting.CompareAndSwap(false, true) {
+			return b.reply(msg, l.T("broadcast-busy", nil))
+		}
+
+		ids, err := b.db.UserIDs()
+		if err != nil {
+			b.broadcasting.Store(false)
+
+			return err
+		}
+
+		b.log.Info("broadcast started", slog.Int64("admin_id", c.Sender().ID), slog.Int("users", len(ids)))
+
+		go func() {
+			defer b.broadcasting.Store(false)
+
+			var start = time.Now()
+
+			report := b.broadcast(ctx, ids, text)
+
+			b.log.Info("broadcast finished",
+				slog.Int("delivered", report.Delivered),
+				slog.Int("unreachable", report.Unreachable),
+				slog.Int("failed", report.Failed),
+				slog.Duration("duration", time.Since(start)),
+			)
+
+			_ = b.reply(msg, l.T("broadcast-finished", i18n.Vars{
+				"duration":    time.Since(start).Truncate(time.Second),
+				"delivered":   report.Delivered,
+				"total":       report.Total,
+				"unreachable": report.Unreachable,
+				"failed":      report.Failed,
+			}))
+		}()
+
+		return b.reply(msg, l.T("broadcast-started", i18n.Vars{"count": len(ids)}))
+	}
+}
+
+// broadcast sends the text to the users, respecting the Telegram rate limits. When the context is canceled, the
+// rest of the users are counted as failed.
+func (b *Bot) broadcast(ctx context.Context, ids []int64, text string) (report broadcastReport) {
+	var ticker = time.NewTicker(time.Second / broadcastRate)
+	defer ticker.Stop()
+
+	report.Total = len(ids)
+
+	for i, id := range ids {
+		select {
+		case <-ctx.Done():
+			report.Failed += len(ids) - i
+
+			return report
+		case <-ticker.C:
+		}
+
+		switch err := b.sendWithRetries(ctx, id, text); {
+		case err == nil:
+			report.Delivered++
+		case isUnreachable(err):
+			report.Unreachable++
+		default:
+			report.Failed++
+
+			b.log.Warn("failed to broadcast the message", slog.String("error", err.Error()), slog.Int64("user_id", id))
+		}
+	}
+
+	return report
+}
+
+// sendWithRetries sends the text to the user, waiting and retrying when Telegram asks to slow down.
+func (b *Bot) sendWithRetries(ctx context.Context, id int64, text string) error {
+	for attempt := 0; ; attempt++ {
+		_, err := b.client.Send(&tele.User{ID: id}, text)
+
+		var flood tele.FloodError
+
+		if err == nil || !errors.As(err, &flood) || attempt >= broadcastMaxRetries {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(flood.RetryAfter) * time.Second):
+		}
+	}
+}
+
+// isUnreachable reports whether the error means that the user cannot receive messages from the bot (e.g., the bot
+// was blocked, or the user account was deactivated).
+func isUnreachable(err error) bool {
+	var tErr *tele.Error
+
+	return errors.As(err, &tErr) && tErr.Code == http.StatusForbidden
+}
@@ -0,0 +1,108 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+
+	tele "gopkg.in/telebot.v4"
+
+	"gh.tarampamp.am/video-dl-bot/internal/i18n"
+)
+
+// btnCancelJob is the inline button of the download status message, that cancels the running download.
+var btnCancelJob = tele.InlineButton{Unique: "job_cancel"} //nolint:gochecknoglobals // data: job token
+
+// errCanceledByUser is the cause of the job context cancellation by the user (the "Cancel" button or "/cancel").
+var errCanceledByUser = errors.New("canceled by the user")
+
+// runningJob is the download job in progress, that can be canceled by the user who requested it.
+type runningJob struct {
+	userID int64
+	cancel context.CancelCauseFunc
+}
+
+// registerJob keeps the cancel function of the user job, and returns the token for the "Cancel" button. The
+// unregister function must be called when the job is finished.
+func (b *Bot) registerJob(user *tele.User, cancel context.CancelCauseFunc) (token string, unregister func()) {
+	token = rand.Text()
+
+	b.jobs.Store(token, runningJob{userID: user.ID, cancel: cancel})
+
+	return token, func() { b.jobs.Delete(token) }
+}
+
+// cancelJobs cancels the user jobs (all of them, if the token is empty), and returns the number of canceled ones.
+func (b *Bot) cancelJobs(user *tele.User, token string) (canceled int) {
+	b.jobs.Range(func(t, v any) bool {
+		var job = v.(runningJob) //nolint:forcetypeassert
+
+		if job.userID == user.ID && (token == "" || t == token) {
+			job.cancel(errCanceledByUser)
+			b.jobs.Delete(t)
+
+			canceled++
+		}
+
+		return true
+	})
+
+	return canceled
+}
+
+// canceledByUser reports whether the job context is canceled by the user (not by the bot shutdown, for example).
+func canceledByUser(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errCanceledByUser)
+}
+
+// canceledReply returns the reply to the user, whose job is canceled by them or by the bot shutdown (empty, if the
+// job is not canceled, or canceled for another reason).
+func canceledReply(ctx context.Context, l i18n.Localizer) string {
+	switch {
+	case canceledByUser(ctx):
+		return l.T("download-canceled", nil)
+	case shuttingDown(ctx):
+		return l.T("shutdown-canceled", nil)
+	}
+
+	return ""
+}
+
+// sendJobStatus replies to the user message with the download status and the "Cancel" button of the job. The
+// returned function deletes the status message.
+func (b *Bot) sendJobStatus(to *tele.Message, text, button, token string) (remove func()) {
+	status, err := b.client.Reply(to, text,
+		&tele.SendOptions{DisableNotification: true},
+		&tele.ReplyMarkup{InlineKeyboard: [][]tele.InlineButton{{inlineButton(btnCancelJob, button, token)}}},
+	)
+	if err != nil {
+		return func() {}
+	}
+
+	return func() { _ = b.client.Delete(status) }
+}
+
+// handleCancelJobButton returns a handler for the "Cancel" button of the download status message.
+func (b *Bot) handleCancelJobButton() tele.HandlerFunc {
+	return func(c tele.Context) error {
+		var l = b.tr(c.Sender())
+
+		if b.cancelJobs(c.Sender(), c.Callback().Data) == 0 {
+			return c.Respond(&tele.CallbackResponse{Text: l.T("cancel-nothing", nil)})
+		}
+
+		return c.Respond(&tele.CallbackResponse{Text: l.T("cancel-requested", nil)})
+	}
+}
+
+// handleCancelCommand returns a handler for the "/cancel" command, that cancels all the user downloads in progress
+// (including the queued ones).
+func (b *Bot) handleCancelCommand() tele.HandlerFunc {
+	return func(c tele.Context) error {
+		if b.cancelJobs(c.Sender(), "") == 0 {
+			return b.reply(c.Message(), b.tr(c.Sender()).T("cancel-nothing", nil))
+		}
+
+		return nil // the canceled jobs reply to their messages
+	}
+}
@@ -0,0 +1,165 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"gh.tarampamp.am/video-dl-bot/internal/i18n"
+	ytdlp "gh.tarampamp.am/video-dl-bot/internal/yt-dlp"
+)
+
+const (
+	chaptersMaxButtons   = 50  // max number of the chapter buttons (Telegram allows 100 buttons per message)
+	chapterButtonMaxLen  = 48  // chapter buttons text is truncated to this length (in runes)
+	chapterDataSeparator = ":" // separates the list token and the chapter index in the button data
+)
+
+// Inline buttons of the "/chapters" list.
+var (
+	btnChapter       = tele.InlineButton{Unique: "chapter"}        //nolint:gochecknoglobals // data: token:index
+	btnChaptersSplit = tele.InlineButton{Unique: "chapters_split"} //nolint:gochecknoglobals // data: list token
+)
+
+// chapterList is the list of the video chapters, sent to the user by the "/chapters" command. It's kept in memory
+// for the buttons, and can be used many times (until it expires).
+type chapterList struct {
+	msg      *tele.Message // the user message with the command (the replies are sent to it)
+	link     string        // the video link
+	chapters []ytdlp.Chapter
+	expires  time.Time
+}
+
+// handleChaptersCommand returns a handler for the "/chapters <url>" command, that lists the video chapters as the
+// buttons: a single chapter can be downloaded, or the whole video split per chapter.
+func (b *Bot) handleChaptersCommand(pCtx context.Context) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		var l = b.tr(c.Sender())
+
+		info, link, err := b.probeCommandLink(pCtx, c, "chapters-usage")
+		if info == nil {
+			return err
+		}
+
+		if len(info.Chapters) == 0 {
+			return b.reply(c.Message(), l.T("chapters-none", nil))
+		}
+
+		var (
+			token  = b.storeChapterList(c.Message(), link.String(), info.Chapters)
+			markup tele.ReplyMarkup
+		)
+
+		for i, ch := range info.Chapters[:min(len(info.Chapters), chaptersMaxButtons)] {
+			markup.InlineKeyboard = append(markup.InlineKeyboard, []tele.InlineButton{
+				inlineButton(btnChapter, truncate(chapterLine(ch), chapterButtonMaxLen),
+					token+chapterDataSeparator+strconv.Itoa(i),
+				),
+			})
+		}
+
+		markup.InlineKeyboard = append(markup.InlineKeyboard, []tele.InlineButton{
+			inlineButton(btnChaptersSplit, l.T("chapters-split-button", i18n.Vars{"count": len(info.Chapters)}), token),
+		})
+
+		return b.reply(c.Message(), l.T("chapters-list", i18n.Vars{
+			"title": truncate(info.Title, infoMaxTitleLen),
+			"count": len(info.Chapters),
+		}), &markup)
+	}
+}
+
+// handleChapterButton returns a handler for the chapter button, that downloads the single chapter.
+func (b *Bot) handleChapterButton(pCtx context.Context) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		var token, index, _ = strings.Cut(c.Callback().Data, chapterDataSeparator)
+
+		list, ok := b.chapterList(token, c.Sender())
+		i, err := strconv.Atoi(index)
+
+		if !ok || err != nil || i < 0 || i >= len(list.chapters) {
+			return c.Respond(&tele.CallbackResponse{Text: b.tr(c.Sender()).T("confirmation-expired", nil)})
+		}
+
+		_ = c.Respond()
+
+		var chapter = list.chapters[i]
+
+		return b.processDownload(pCtx, c, downloadRequest{msg: list.msg, text: list.link, section: &chapter})
+	}
+}
+
+// handleChaptersSplitButton returns a handler for the "all chapters" button, that downloads the video split per
+// chapter.
+func (b *Bot) handleChaptersSplitButton(pCtx context.Context) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		list, ok := b.chapterList(c.Callback().Data, c.Sender())
+		if !ok {
+			return c.Respond(&tele.CallbackResponse{Text: b.tr(c.Sender()).T("confirmation-expired", nil)})
+		}
+
+		_ = c.Respond()
+
+		return b.processDownload(pCtx, c, downloadRequest{msg: list.msg, text: list.link, splitChapters: true})
+	}
+}
+
+// storeChapterList keeps the chapters for the buttons of the list, and returns the token for the buttons.
+func (b *Bot) storeChapterList(msg *tele.Message, link string, chapters []ytdlp.Chapter) string {
+	var now = time.Now()
+
+	// forget the expired lists
+	b.chapterLists.Range(func(token, list any) bool {
+		if now.After(list.(chapterList).expires) { //nolint:forcetypeassert
+			b.chapterLists.Delete(token)
+		}
+
+		return true
+	})
+
+	var token = rand.Text()
+
+	b.chapterLists.Store(token, chapterList{msg: msg, link: link, chapters: chapters, expires: now.Add(confirmationTTL)})
+
+	return token
+}
+
+// chapterList returns the list of the chapters by the token, if it belongs to the user and has not expired.
+func (b *Bot) chapterList(token string, user *tele.User) (chapterList, bool) {
+	v, ok := b.chapterLists.Load(token)
+	list, _ := v.(chapterList)
+
+	if !ok || list.msg.Sender == nil || user == nil || list.msg.Sender.ID != user.ID || time.Now().After(list.expires) {
+		return chapterList{}, false
+	}
+
+	return list, true
+}
+
+// chapterLine returns the chapter start timestamp with its title (e.g., "1:30 Main part").
+func chapterLine(ch ytdlp.Chapter) string { return i18n.FormatDuration(ch.Start) + " " + ch.Title }
+
+// chapterRange returns the chapter time range with its title (e.g., "1:30-4:15 Main part").
+func chapterRange(ch ytdlp.Chapter) string {
+	return i18n.FormatDuration(ch.Start) + "-" + i18n.FormatDuration(ch.End) + " " + ch.Title
+}
+
+// videoCaption returns the caption of the video: the time range of the downloaded chapter (section), or the
+// timestamps of the video chapters (empty, if there are no chapters).
+func videoCaption(dl *ytdlp.Downloaded, section *ytdlp.Chapter) string {
+	if section != nil {
+		return chapterRange(*section)
+	}
+
+	var lines = make([]string, 0, len(dl.Chapters))
+
+	for _, ch := range dl.Chapters {
+		lines = append(lines, chapterLine(ch))
+	}
+
+	return strings.Join(lines, "\n")
+}
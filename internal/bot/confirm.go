@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"gh.tarampamp.am/video-dl-bot/internal/i18n"
+	ytdlp "gh.tarampamp.am/video-dl-bot/internal/yt-dlp"
+)
+
+// confirmationTTL is how long the download requests wait for the user confirmation.
+const confirmationTTL = time.Hour
+
+// Inline buttons of the download confirmation (e.g., for the long videos).
+var (
+	btnConfirmDownload = tele.InlineButton{Unique: "download_confirm"} //nolint:gochecknoglobals // data: request token
+	btnCancelDownload  = tele.InlineButton{Unique: "download_cancel"}  //nolint:gochecknoglobals // data: request token
+)
+
+type (
+	// downloadRequest is a request to download the video by the link from the user message.
+	downloadRequest struct {
+		msg       *tele.Message // the user message (the replies are sent to it)
+		text      string        // text with the link
+		confirmed bool          // the user confirmed the download (of the long video or the live stream)
+
+		section       *ytdlp.Chapter // download only this chapter of the video (see "/chapters")
+		splitChapters bool           // split the video per chapter, and send them as the album (see "/chapters")
+	}
+
+	// pendingRequest is a download request, that waits for the user confirmation.
+	pendingRequest struct {
+		downloadRequest
+
+		expires time.Time
+	}
+)
+
+// askConfirmation replies to the user message with the question and the confirmation buttons. The request is kept
+// in memory until the user answers (or the confirmation expires).
+func (b *Bot) askConfirmation(l i18n.Localizer, req downloadRequest, question, button string) error {
+	var now = time.Now()
+
+	// forget the expired requests
+	b.pending.Range(func(token, p any) bool {
+		if now.After(p.(pendingRequest).expires) { //nolint:forcetypeassert
+			b.pending.Delete(token)
+		}
+
+		return true
+	})
+
+	var token = rand.Text()
+
+	b.pending.Store(token, pendingRequest{downloadRequest: req, expires: now.Add(confirmationTTL)})
+
+	return b.reply(req.msg, question, &tele.ReplyMarkup{InlineKeyboard: [][]tele.InlineButton{{
+		inlineButton(btnConfirmDownload, button, token),
+		inlineButton(btnCancelDownload, l.T("cancel-button", nil), token),
+	}}})
+}
+
+// takePending removes the pending request with the given token, and returns it, if it belongs to the user.
+func (b *Bot) takePending(token string, user *tele.User) (pendingRequest, bool) {
+	v, ok := b.pending.Load(token)
+	if !ok {
+		return pendingRequest{}, false
+	}
+
+	var req = v.(pendingRequest) //nolint:forcetypeassert
+
+	if req.msg.Sender == nil || user == nil || req.msg.Sender.ID != user.ID {
+		return pendingRequest{}, false // only the user who sent the link can answer
+	}
+
+	b.pending.Delete(token)
+
+	return req, true
+}
+
+// handleConfirmDownloadButton returns a handler for the download confirmation button, that downloads the video.
+func (b *Bot) handleConfirmDownloadButton(pCtx context.Context) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		req, ok := b.takePending(c.Callback().Data, c.Sender())
+		if !ok || time.Now().After(req.expires) {
+			return c.Respond(&tele.CallbackResponse{Text: b.tr(c.Sender()).T("confirmation-expired", nil)})
+		}
+
+		_ = c.Respond()
+		_ = c.Delete() // the question is not needed anymore
+
+		req.confirmed = true
+
+		return b.processDownload(pCtx, c, req.downloadRequest)
+	}
+}
+
+// handleCancelDownloadButton returns a handler for the download cancellation button.
+func (b *Bot) handleCancelDownloadButton() tele.HandlerFunc {
+	return func(c tele.Context) error {
+		if _, ok := b.takePending(c.Callback().Data, c.Sender()); !ok {
+			return c.Respond()
+		}
+
+		_ = c.Respond()
+
+		return c.Delete()
+	}
+}
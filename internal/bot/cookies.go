@@ -0,0 +1,294 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"gh.tarampamp.am/video-dl-bot/internal/cookies"
+	"gh.tarampamp.am/video-dl-bot/internal/i18n"
+)
+
+const (
+	cookiesCommand         = "/cookies"
+	cookiesMaxFileSize     = 1 << 20        // cookies files are small, 1 MiB is more than enough
+	cookiesExpiryWindow    = 72 * time.Hour // admins are warned when cookies expire within this window
+	cookiesExpiryCheckFreq = 12 * time.Hour // how often the cookies expiration is checked
+	cookiesRescanFreq      = time.Minute    // how often the source cookies files are checked for changes
+)
+
+// adminOnly returns a middleware that allows the handler for the bot administrators only.
+func (b *Bot) adminOnly() tele.MiddlewareFunc {
+	return func(next tele.HandlerFunc) tele.HandlerFunc {
+		return func(c tele.Context) error {
+			if user := c.Sender(); user != nil && b.state().IsAdmin(user.ID) {
+				return next(c)
+			}
+
+			return b.reply(c.Message(), b.tr(c.Sender()).T("admin-only", nil))
+		}
+	}
+}
+
+// handleCookiesCommand returns a handler for the "/cookies [domain]" command. Being sent as a reply to a message
+// with a document, it uploads the cookies file (for the domain, or the default one); otherwise, it lists the
+// current cookies files.
+func (b *Bot) handleCookiesCommand() tele.HandlerFunc {
+	return func(c tele.Context) error {
+		var msg = c.Message()
+
+		if msg.ReplyTo != nil && msg.ReplyTo.Document != nil {
+			return b.uploadCookies(c, msg.ReplyTo.Document, c.Args())
+		}
+
+		return b.reply(msg, b.cookiesList(b.tr(c.Sender()), time.Now()))
+	}
+}
+
+// handleDocument returns a handler for the documents. A document with the "/cookies [domain]" caption, sent by the
+// administrator, is uploaded as a cookies file; other documents are ignored.
+func (b *Bot) handleDocument() tele.HandlerFunc {
+	return func(c tele.Context) error {
+		var msg = c.Message()
+
+		if msg.Document == nil {
+			return nil
+		}
+
+		var args = strings.Fields(msg.Caption)
+
+		// the command may be addressed to the bot ("/cookies@my_bot")
+		if len(args) == 0 || strings.SplitN(args[0], "@", 2)[0] != cookiesCommand { //nolint:mnd
+			return nil
+		}
+
+		if user := c.Sender(); user == nil || !b.state().IsAdmin(user.ID) {
+			return b.reply(msg, b.tr(c.Sender()).T("admin-only", nil))
+		}
+
+		return b.uploadCookies(c, msg.Document, args[1:])
+	}
+}
+
+// uploadCookies downloads the document, validates it and replaces the cookies file for the domain (from the args)
+// or the default one.
+func (b *Bot) uploadCookies(c tele.Context, doc *tele.Document, args []string) error {
+	var (
+		msg, user = c.Message(), c.Sender()
+		l         = b.tr(user)
+		domain    = cookies.DefaultDomain
+		tooLarge  = l.T("cookies-too-large", i18n.Vars{"size": formatSize(cookiesMaxFileSize)})
+	)
+
+	if len(args) > 0 {
+		d, err := cookies.NormalizeDomain(args[0])
+		if err != nil {
+			return b.reply(msg, l.T("cookies-invalid-domain", nil))
+		}
+
+		domain = d
+	}
+
+	if doc.FileSize > cookiesMaxFileSize {
+		return b.reply(msg, tooLarge)
+	}
+
+	rc, err := b.client.File(&doc.File)
+	if err != nil {
+		return b.reply(msg, l.T("cookies-download-failed", i18n.Vars{"error": err.Error()}))
+	}
+
+	defer func() { _ = rc.Close() }()
+
+	content, err := io.ReadAll(io.LimitReader(rc, cookiesMaxFileSize+1))
+	if err != nil {
+		return b.reply(msg, l.T("cookies-download-failed", i18n.Vars{"error": err.Error()}))
+	}
+
+	if len(content) > cookiesMaxFileSize {
+		return b.reply(msg, tooLarge)
+	}
+
+	f, importErr := b.cookies.Import(domain, content)
+	if f == nil {
+		return b.reply(msg, l.T("cookies-rejected", i18n.Vars{"error": importErr.Error()}))
+	}
+
+	b.log.Info("cookies file updated",
+		slog.String("domain", domainName(b.tr(nil), domain)), // in the default language
+		slog.Int("cookies", len(f.Cookies)),
+		slog.String("sender_name", user.FirstName),
+		slog.Int64("sender_id", user.ID),
+	)
+
+	var reply = l.T("cookies-updated", i18n.Vars{
+		"domain":  domainName(l, domain),
+		"details": describeCookies(l, *f, time.Now()),
+	})
+
+	if importErr != nil {
+		b.log.Warn("cookies file is not persisted", slog.String("error", importErr.Error()))
+
+		reply += "\n\n" + l.T("cookies-not-persisted", i18n.Vars{"error": importErr.Error()})
+	}
+
+	return b.reply(msg, reply)
+}
+
+// cookiesList returns the human-readable list of the current cookies files.
+func (b *Bot) cookiesList(l i18n.Localizer, now time.Time) string {
+	var (
+		files = b.cookies.List()
+		sb    strings.Builder
+	)
+
+	if len(files) == 0 {
+		sb.WriteString(l.T("cookies-none", nil))
+	} else {
+		sb.WriteString(l.T("cookies-list", nil) + "\n")
+
+		for _, f := range files {
+			sb.WriteString(fmt.Sprintf("\n• %s: %s", domainName(l, f.Domain), describeCookies(l, f, now)))
+		}
+	}
+
+	sb.WriteString("\n\n" + l.T("cookies-upload-hint", nil))
+
+	return sb.String()
+}
+
+// rescanCookies periodically imports the changed source cookies files (e.g., updated in the cookies directory
+// without the bot restart). Blocks until the context is canceled.
+func (b *Bot) rescanCookies(ctx context.Context) {
+	var ticker = time.NewTicker(cookiesRescanFreq)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		updated, err := b.cookies.Rescan()
+		if err != nil {
+			b.log.Warn("failed to import the changed cookies files", slog.String("error", err.Error()))
+		}
+
+		for _, f := range updated {
+			b.log.Info("cookies file updated",
+				slog.String("domain", domainName(b.tr(nil), f.Domain)),
+				slog.String("source", f.Source),
+			)
+		}
+	}
+}
+
+// watchCookiesExpiry periodically checks the cookies files and warns the administrators about the files that
+// expire soon (once per file). Blocks until the context is canceled.
+func (b *Bot) watchCookiesExpiry(ctx context.Context) {
+	var (
+		ticker   = time.NewTicker(cookiesExpiryCheckFreq)
+		notified = make(map[string]struct{}) // paths of the files the admins were warned about
+	)
+
+	defer ticker.Stop()
+
+	for {
+		var now = time.Now()
+
+		for _, f := range b.cookies.List() {
+			expiresAt, ok := f.ExpiresAt()
+			if _, done := notified[f.Path]; done || !ok || expiresAt.Sub(now) > cookiesExpiryWindow {
+				continue
+			}
+
+			notified[f.Path] = struct{}{} // the uploaded file gets a new path, so the warning is repeated for it
+
+			b.log.Warn("cookies expire soon",
+				slog.String("domain", domainName(b.tr(nil), f.Domain)),
+				slog.Time("expires_at", expiresAt),
+			)
+
+			var caption, key = cookiesCommand, "cookies-expire-soon"
+
+			switch {
+			case cookies.IsSiteDomain(f.Domain): // can be updated in the configuration only
+				key = "cookies-expire-soon-site"
+			case f.Domain != cookies.DefaultDomain:
+				caption += " " + f.Domain
+			}
+
+			b.notifyAdmins(func(l i18n.Localizer) string {
+				return l.T(key, i18n.Vars{
+					"domain":  domainName(l, f.Domain),
+					"expiry":  expiryText(l, expiresAt, now),
+					"caption": caption,
+				})
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// notifyAdmins sends the message to all the bot administrators (in their languages).
+func (b *Bot) notifyAdmins(msg func(l i18n.Localizer) string) {
+	for _, id := range b.state().Admins {
+		var admin = &tele.User{ID: id}
+
+		if _, err := b.client.Send(admin, msg(b.tr(admin))); err != nil {
+			b.log.Error("failed to notify the administrator",
+				slog.String("error", err.Error()),
+				slog.Int64("admin_id", id),
+			)
+		}
+	}
+}
+
+// domainName returns the domain name for the user-facing messages.
+func domainName(l i18n.Localizer, domain string) string {
+	if domain == cookies.DefaultDomain {
+		return l.T("cookies-default-file", nil)
+	}
+
+	return domain
+}
+
+// describeCookies returns a short description of the cookies file (cookies count and expiration).
+func describeCookies(l i18n.Localizer, f cookies.File, now time.Time) string {
+	var desc = l.T("cookies-count", i18n.Vars{"count": len(f.Cookies)})
+
+	if expiresAt, ok := f.ExpiresAt(); ok {
+		desc += ", " + expiryText(l, expiresAt, now)
+	} else {
+		desc += ", " + l.T("cookies-session-only", nil)
+	}
+
+	return desc
+}
+
+// expiryText returns the human-readable expiration time (e.g., "expire in 5 days (2006-01-02)").
+func expiryText(l i18n.Localizer, expiresAt, now time.Time) string {
+	const day = 24 * time.Hour
+
+	var date = expiresAt.UTC().Format(time.DateOnly)
+
+	switch left := expiresAt.Sub(now); {
+	case left <= 0:
+		return l.T("cookies-expired", i18n.Vars{"date": date})
+	case left < day:
+		return l.T("cookies-expire-hours", i18n.Vars{"hours": int(left.Hours()), "date": date})
+	default:
+		return l.T("cookies-expire-days", i18n.Vars{"days": int(left / day), "date": date})
+	}
+}
@@ -0,0 +1,36 @@
+package bot
+
+import (
+	"context"
+
+	tele "gopkg.in/telebot.v4"
+
+	ytdlp "gh.tarampamp.am/video-dl-bot/internal/yt-dlp"
+)
+
+// NewDrainerForTest creates a new drainer for the tests.
+func NewDrainerForTest(ctx context.Context) *drainer { return newDrainer(ctx) } //nolint:revive
+
+// JobsContext returns the parent context of the jobs.
+func (d *drainer) JobsContext() context.Context { return d.ctx }
+
+// ShuttingDownForTest reports whether the job context is canceled because of the bot shutdown.
+func ShuttingDownForTest(ctx context.Context) bool { return shuttingDown(ctx) }
+
+// StoreInfoCardForTest stores the "/info" card of the user, and returns its token.
+func StoreInfoCardForTest(b *Bot, userID int64) string {
+	return b.storeInfoCard(&tele.User{ID: userID}, &ytdlp.Info{ID: "foo"})
+}
+
+// InfoCardsForTest returns the tokens of the kept "/info" cards by the user IDs.
+func InfoCardsForTest(b *Bot) map[int64][]string {
+	var out = make(map[int64][]string)
+
+	b.infoCards.Range(func(token, card any) bool {
+		out[card.(infoCard).userID] = append(out[card.(infoCard).userID], token.(string)) //nolint:forcetypeassert
+
+		return true
+	})
+
+	return out
+}
@@ -0,0 +1,266 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	tele "gopkg.in/telebot.v4"
+
+	"gh.tarampamp.am/video-dl-bot/internal/i18n"
+	"gh.tarampamp.am/video-dl-bot/internal/storage"
+	ytdlp "gh.tarampamp.am/video-dl-bot/internal/yt-dlp"
+)
+
+const (
+	historyPageSize      = 5  // number of the history records per page
+	historyMaxTitleLen   = 48 // titles in the list are truncated to this length
+	historyMaxButtonText = 24 // titles on the buttons are truncated to this length
+)
+
+// Inline buttons of the history list (the data is set per button).
+var ( //nolint:gochecknoglobals
+	btnHistoryPage   = tele.InlineButton{Unique: "history_page"}   // data: page number
+	btnHistorySend   = tele.InlineButton{Unique: "history_send"}   // data: record ID
+	btnHistoryDelete = tele.InlineButton{Unique: "history_delete"} // data: record ID and page number
+)
+
+// WithHistory enables the download history, stored in the database (the "/history" and "/forget_me" commands).
+// If the limit is positive, only the given number of the latest records is kept per user.
+func WithHistory(db *storage.DB, limit int) Option {
+	return func(b *Bot) { b.db, b.historyLimit = db, limit }
+}
+
+// registerHistoryHandlers registers the handlers of the history commands and buttons.
+func (b *Bot) registerHistoryHandlers() {
+	b.client.Handle("/history", b.handleHistoryCommand())
+	b.client.Handle("/forget_me", b.handleForgetMeCommand())
+	b.client.Handle(&btnHistoryPage, b.handleHistoryPage())
+	b.client.Handle(&btnHistorySend, b.handleHistorySend())
+	b.client.Handle(&btnHistoryDelete, b.handleHistoryDelete())
+}
+
+// handleHistoryCommand returns a handler for the "/history" command.
+func (b *Bot) handleHistoryCommand() tele.HandlerFunc {
+	return func(c tele.Context) error {
+		text, markup, err := b.historyPage(c.Sender(), 0)
+		if err != nil {
+			return err
+		}
+
+		return b.reply(c.Message(), text, markup)
+	}
+}
+
+// handleForgetMeCommand returns a handler for the "/forget_me" command, that removes the user's history.
+func (b *Bot) handleForgetMeCommand() tele.HandlerFunc {
+	return func(c tele.Context) error {
+		var user = c.Sender()
+
+		removed, err := b.db.DeleteUserHistory(user.ID)
+		if err != nil {
+			return err
+		}
+
+		b.log.Info("user history removed",
+			slog.String("sender_name", user.FirstName),
+			slog.Int64("sender_id", user.ID),
+			slog.Int("records", removed),
+		)
+
+		return b.reply(c.Message(), b.tr(user).T("forget-me-done", i18n.Vars{"count": removed}))
+	}
+}
+
+// handleHistoryPage returns a handler for the history navigation buttons.
+func (b *Bot) handleHistoryPage() tele.HandlerFunc {
+	return func(c tele.Context) error {
+		page, _ := strconv.Atoi(c.Callback().Data)
+
+		text, markup, err := b.historyPage(c.Sender(), page)
+		if err != nil {
+			return err
+		}
+
+		_ = c.Respond()
+
+		return c.Edit(text, markup)
+	}
+}
+
+// handleHistorySend returns a handler for the "Send again" buttons.
+func (b *Bot) handleHistorySend() tele.HandlerFunc {
+	return func(c tele.Context) error {
+		id, _ := strconv.ParseUint(c.Callback().Data, 10, 64)
+
+		var l = b.tr(c.Sender())
+
+		rec, err := b.db.HistoryRecord(c.Sender().ID, id)
+		if errors.Is(err, storage.ErrNotFound) {
+			return c.Respond(&tele.CallbackResponse{Text: l.T("history-record-removed", nil)})
+		} else if err != nil {
+			return err
+		}
+
+		_ = c.Respond()
+
+		switch {
+		case rec.FileID != "":
+			_, err = b.client.Send(c.Sender(), &tele.Video{File: tele.File{FileID: rec.FileID}, Caption: rec.URL})
+		case rec.Link != "":
+			_, err = b.client.Send(c.Sender(), l.T("history-link-may-expire", nil),
+				&tele.ReplyMarkup{InlineKeyboard: [][]tele.InlineButton{{{
+					Text: l.T("hosting-button", i18n.Vars{"size": formatSize(rec.Size), "bytes": rec.Size}),
+					URL:  rec.Link,
+				}}}},
+			)
+		default:
+			_, err = b.client.Send(c.Sender(), l.T("history-file-gone", i18n.Vars{"url": rec.URL}))
+		}
+
+		return err
+	}
+}
+
+// handleHistoryDelete returns a handler for the "Delete" buttons.
+func (b *Bot) handleHistoryDelete() tele.HandlerFunc {
+	return func(c tele.Context) error {
+		var (
+			rawID, rawPage, _ = strings.Cut(c.Callback().Data, ",")
+			id, _             = strconv.ParseUint(rawID, 10, 64)
+			page, _           = strconv.Atoi(rawPage)
+		)
+
+		if err := b.db.DeleteHistory(c.Sender().ID, id); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return err
+		}
+
+		text, markup, err := b.historyPage(c.Sender(), page)
+		if err != nil {
+			return err
+		}
+
+		_ = c.Respond(&tele.CallbackResponse{Text: b.tr(c.Sender()).T("history-deleted", nil)})
+
+		return c.Edit(text, markup)
+	}
+}
+
+// historyPage renders the page of the user's history (the page number is zero-based and clamped to the last one).
+func (b *Bot) historyPage(user *tele.User, page int) (string, *tele.ReplyMarkup, error) {
+	var userID, l = user.ID, b.tr(user)
+
+	page = max(page, 0)
+
+	list, total, err := b.db.History(userID, page*historyPageSize, historyPageSize)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var pages = (total + historyPageSize - 1) / historyPageSize
+
+	if len(list) == 0 && total > 0 { // the page doesn't exist anymore (e.g., records were removed)
+		page = pages - 1
+
+		if list, total, err = b.db.History(userID, page*historyPageSize, historyPageSize); err != nil {
+			return "", nil, err
+		}
+	}
+
+	var markup = tele.ReplyMarkup{}
+
+	if total == 0 {
+		return l.T("history-empty", nil), &markup, nil
+	}
+
+	var text strings.Builder
+
+	text.WriteString(l.T("history-page", i18n.Vars{"page": page + 1, "pages": pages}) + "\n")
+
+	for i, rec := range list {
+		var n = page*historyPageSize + i + 1
+
+		fmt.Fprintf(&text, "\n%d. %s\n%s", n, truncate(historyTitle(rec), historyMaxTitleLen), rec.URL)
+		fmt.Fprintf(&text, "\n%s · %s · %s\n", rec.Extractor, formatSize(rec.Size), rec.CreatedAt.Format("2006-01-02"))
+
+		markup.InlineKeyboard = append(markup.InlineKeyboard, []tele.InlineButton{
+			inlineButton(btnHistorySend, fmt.Sprintf("🔁 %d. %s", n, truncate(historyTitle(rec), historyMaxButtonText)),
+				strconv.FormatUint(rec.ID, 10),
+			),
+			inlineButton(btnHistoryDelete, "🗑", fmt.Sprintf("%d,%d", rec.ID, page)),
+		})
+	}
+
+	var nav []tele.InlineButton
+
+	if page > 0 {
+		nav = append(nav, inlineButton(btnHistoryPage, "⬅️", strconv.Itoa(page-1)))
+	}
+
+	if page+1 < pages {
+		nav = append(nav, inlineButton(btnHistoryPage, "➡️", strconv.Itoa(page+1)))
+	}
+
+	if len(nav) > 0 {
+		markup.InlineKeyboard = append(markup.InlineKeyboard, nav)
+	}
+
+	return text.String(), &markup, nil
+}
+
+// saveHistory adds the successful download to the user's history (if the history is enabled).
+func (b *Bot) saveHistory(user *tele.User, videoUrl *url.URL, dl *ytdlp.Downloaded, size int64, fileID, link string) {
+	if b.db == nil {
+		return
+	}
+
+	var rec = storage.HistoryRecord{
+		UserID:    user.ID,
+		URL:       videoUrl.String(),
+		Title:     dl.Title,
+		Extractor: dl.Extractor,
+		Size:      size,
+		FileID:    fileID,
+		Link:      link,
+	}
+
+	if err := b.db.AddHistory(&rec, b.historyLimit); err != nil {
+		b.log.Error("failed to save the download history",
+			slog.String("error", err.Error()),
+			slog.Int64("sender_id", user.ID),
+			slog.String("video_url", videoUrl.String()),
+		)
+	}
+}
+
+// historyTitle returns the title of the history record (or the URL, if the title is unknown).
+func historyTitle(rec storage.HistoryRecord) string {
+	if rec.Title != "" {
+		return rec.Title
+	}
+
+	return rec.URL
+}
+
+// inlineButton returns a copy of the callback button with the given text and data.
+func inlineButton(btn tele.InlineButton, text, data string) tele.InlineButton {
+	btn.Text, btn.Data = text, data
+
+	return btn
+}
+
+// formatSize returns the human-readable file size (in MB).
+func formatSize(size int64) string { return fmt.Sprintf("%.2f MB", float64(size)/1024/1024) } //nolint:mnd
+
+// truncate truncates the string to the given number of runes (adding an ellipsis).
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+
+	return string([]rune(s)[:n-1]) + "…"
+}
@@ -0,0 +1,350 @@
+package bot
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"gh.tarampamp.am/video-dl-bot/internal/i18n"
+	ytdlp "gh.tarampamp.am/video-dl-bot/internal/yt-dlp"
+)
+
+const (
+	infoCardTTL          = time.Hour    // how long the raw metadata of the "/info" card is kept for the "JSON" button
+	infoMaxUserCards     = 5            // max number of the kept cards per user (the oldest ones are forgotten)
+	infoMaxCards         = 200          // max number of the kept cards of all the users
+	infoMaxTitleLen      = 256          // max length of the title in the card (in runes)
+	infoMaxTags          = 15           // max number of the tags in the card
+	infoMaxChapters      = 30           // max number of the chapters in the card
+	infoMaxCardLen       = 4096         // max length of the card (the Telegram message limit, in runes)
+	infoUploadDateFormat = "2006-01-02" // format of the upload date in the card
+)
+
+// btnInfoJSON is the inline button of the "/info" card, that sends the raw metadata JSON.
+var btnInfoJSON = tele.InlineButton{Unique: "info_json"} //nolint:gochecknoglobals // data: card token
+
+// infoCard is the raw metadata of the "/info" card, kept for the "JSON" button.
+type infoCard struct {
+	userID  int64  // the user who requested the card
+	videoID string // used for the file name
+	raw     []byte // the metadata JSON, printed by yt-dlp
+	expires time.Time
+}
+
+// handleInfoCommand returns a handler for the "/info <url>" command, that shows the video metadata without
+// downloading it.
+func (b *Bot) handleInfoCommand(pCtx context.Context) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		var (
+			user, msg = c.Sender(), c.Message()
+			l         = b.tr(user)
+		)
+
+		info, _, err := b.probeCommandLink(pCtx, c, "info-usage")
+		if info == nil {
+			return err
+		}
+
+		var markup = &tele.ReplyMarkup{InlineKeyboard: [][]tele.InlineButton{{
+			inlineButton(btnInfoJSON, l.T("info-json-button", nil), b.storeInfoCard(user, info)),
+		}}}
+
+		return b.reply(msg, truncate(formatInfoCard(l, info), infoMaxCardLen), markup,
+			&tele.SendOptions{DisableWebPagePreview: true},
+		)
+	}
+}
+
+// probeCommandLink probes the video by the link from the command payload (e.g., "/info <url>"), like the download
+// requests are probed. If there is no link (the usage message is replied), or the video cannot be probed (the user
+// is notified), nil info is returned with the reply error.
+func (b *Bot) probeCommandLink(pCtx context.Context, c tele.Context, usageKey string) (*ytdlp.Info, *url.URL, error) {
+	var (
+		user, msg = c.Sender(), c.Message()
+		l         = b.tr(user)
+		state     = b.state()
+	)
+
+	if notice, enabled := b.maintenanceNotice(l); enabled && !state.IsAdmin(user.ID) {
+		return nil, nil, b.reply(msg, notice)
+	}
+
+	link, err := ExtractLink(msg.Payload)
+	if err != nil {
+		return nil, nil, b.reply(msg, l.T(usageKey, nil))
+	}
+
+	var site = state.Site(link.Hostname())
+
+	if site != nil && site.Deny {
+		return nil, nil, b.reply(msg, l.T("site-denied", nil))
+	}
+
+	// the probes are drained on shutdown like the downloads
+	jobDone, accepted := b.drainer.Accept()
+	if !accepted {
+		return nil, nil, b.reply(msg, l.T("shutting-down", nil))
+	}
+	defer jobDone()
+
+	ctx, cancel := context.WithCancelCause(pCtx)
+	defer cancel(nil)
+
+	// probing is much cheaper than downloading, but it's still a yt-dlp process, so the limit is shared
+	var leaveQueue = b.drainer.WhileQueued(cancel)
+
+	err = state.lim.Acquire(ctx)
+
+	if leaveQueue(); shuttingDown(ctx) {
+		if err == nil {
+			state.lim.Release()
+		}
+
+		return nil, nil, b.reply(msg, l.T("shutting-down", nil))
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+	defer state.lim.Release()
+
+	stopTypingAction := b.setChatAction(ctx, user, tele.Typing)
+	defer stopTypingAction()
+
+	info, err := b.probe(ctx, user, link.String(), state.pool(link.Hostname(), site),
+		b.ytDlpOptions(state, site, link.Hostname()),
+	)
+	if err != nil {
+		if shuttingDown(ctx) {
+			return nil, nil, b.reply(msg, l.T("shutdown-canceled", nil))
+		}
+
+		return nil, nil, b.reply(msg, l.T("info-failed", nil))
+	}
+
+	b.log.Info("video details requested",
+		slog.String("extractor", info.Extractor),
+		slog.String("sender_name", user.FirstName),
+		slog.Int64("sender_id", user.ID),
+		slog.String("video_url", link.String()),
+	)
+
+	return info, link, nil
+}
+
+// handleInfoJSONButton returns a handler for the "JSON" button of the "/info" card, that sends the raw metadata as
+// a document.
+func (b *Bot) handleInfoJSONButton() tele.HandlerFunc {
+	return func(c tele.Context) error {
+		var user = c.Sender()
+
+		v, ok := b.infoCards.Load(c.Callback().Data)
+		card, _ := v.(infoCard)
+
+		if !ok || card.userID != user.ID || time.Now().After(card.expires) {
+			return c.Respond(&tele.CallbackResponse{Text: b.tr(user).T("confirmation-expired", nil)})
+		}
+
+		var data bytes.Buffer
+
+		if err := json.Indent(&data, card.raw, "", "  "); err != nil {
+			data.Reset()
+			data.Write(card.raw) // send as is
+		}
+
+		_ = c.Respond()
+
+		return c.Reply(&tele.Document{
+			File:     tele.FromReader(&data),
+			FileName: uploadFilename(card.videoID, ".info.json"),
+			MIME:     "application/json",
+		})
+	}
+}
+
+// storeInfoCard keeps the raw metadata for the "JSON" button of the card, and returns the token for the button.
+// The expired cards are forgotten, as well as the oldest ones over the per-user and global limits (the metadata
+// can be large).
+func (b *Bot) storeInfoCard(user *tele.User, info *ytdlp.Info) string {
+	type kept struct {
+		token   any
+		userID  int64
+		expires time.Time
+	}
+
+	var (
+		now  = time.Now()
+		all  []kept
+		mine int
+	)
+
+	// forget the expired cards
+	b.infoCards.Range(func(token, v any) bool {
+		var card = v.(infoCard) //nolint:forcetypeassert
+
+		if now.After(card.expires) {
+			b.infoCards.Delete(token)
+
+			return true
+		}
+
+		all = append(all, kept{token: token, userID: card.userID, expires: card.expires})
+
+		if card.userID == user.ID {
+			mine++
+		}
+
+		return true
+	})
+
+	// forget the oldest cards (all the cards have the same TTL), leaving the room for the new one
+	slices.SortFunc(all, func(a, b kept) int { return a.expires.Compare(b.expires) })
+
+	var total = len(all)
+
+	for _, card := range all {
+		var own = card.userID == user.ID
+
+		if (own && mine >= infoMaxUserCards) || total >= infoMaxCards {
+			b.infoCards.Delete(card.token)
+			total--
+
+			if own {
+				mine--
+			}
+		}
+	}
+
+	var token = rand.Text()
+
+	b.infoCards.Store(token, infoCard{
+		userID:  user.ID,
+		videoID: info.ID,
+		raw:     info.InfoJSON,
+		expires: now.Add(infoCardTTL),
+	})
+
+	return token
+}
+
+// formatInfoCard renders the video metadata card (plain text). The unknown values are omitted.
+func formatInfoCard(l i18n.Localizer, info *ytdlp.Info) string {
+	var header = []string{"🎬 " + truncate(info.Title, infoMaxTitleLen)}
+
+	if info.Uploader != "" {
+		header = append(header, l.T("info-uploader", i18n.Vars{"name": info.Uploader}))
+	}
+
+	if info.ChannelURL != "" {
+		header = append(header, info.ChannelURL)
+	}
+
+	if !info.UploadDate.IsZero() {
+		header = append(header, l.T("info-uploaded", i18n.Vars{"date": info.UploadDate.Format(infoUploadDateFormat)}))
+	}
+
+	switch {
+	case info.IsLive:
+		header = append(header, l.T("info-live", nil))
+	case info.Duration > 0:
+		header = append(header, l.T("info-duration", i18n.Vars{"duration": i18n.FormatDuration(info.Duration)}))
+	}
+
+	header = append(header, joinNonEmpty(" · ",
+		ifPositive(info.ViewCount, l.T("info-views", i18n.Vars{"count": info.ViewCount})),
+		ifPositive(info.LikeCount, l.T("info-likes", i18n.Vars{"count": info.LikeCount})),
+	))
+
+	var resolution, fps string
+
+	if info.Width > 0 && info.Height > 0 {
+		resolution = fmt.Sprintf("%dx%d", info.Width, info.Height)
+	}
+
+	if info.FPS > 0 {
+		fps = strconv.FormatFloat(info.FPS, 'f', -1, 64) + " fps"
+	}
+
+	var details []string
+
+	if video := joinNonEmpty(", ", resolution, fps, info.VideoCodec); video != "" {
+		details = append(details, l.T("info-video", i18n.Vars{"details": video}))
+	}
+
+	if info.AudioCodec != "" {
+		details = append(details, l.T("info-audio", i18n.Vars{"codec": info.AudioCodec}))
+	}
+
+	if info.FormatID != "" {
+		details = append(details, l.T("info-format", i18n.Vars{"format": info.FormatID}))
+	}
+
+	if info.FileSize > 0 {
+		details = append(details, l.T("info-size", i18n.Vars{"size": formatSize(info.FileSize), "bytes": info.FileSize}))
+	}
+
+	if thumb := info.BestThumbnail(); thumb != "" {
+		details = append(details, l.T("info-thumbnail", i18n.Vars{"url": thumb}))
+	}
+
+	if len(info.Tags) > 0 {
+		var tags = info.Tags[:min(len(info.Tags), infoMaxTags)]
+
+		details = append(details, l.T("info-tags", i18n.Vars{"tags": strings.Join(tags, ", ")}))
+	}
+
+	var chapters []string
+
+	if len(info.Chapters) > 0 {
+		chapters = append(chapters, l.T("info-chapters", i18n.Vars{"count": len(info.Chapters)}))
+
+		for i, ch := range info.Chapters {
+			if i == infoMaxChapters {
+				chapters = append(chapters, "…")
+
+				break
+			}
+
+			chapters = append(chapters, chapterLine(ch))
+		}
+	}
+
+	return joinNonEmpty("\n\n",
+		joinNonEmpty("\n", header...),
+		joinNonEmpty("\n", details...),
+		joinNonEmpty("\n", chapters...),
+		info.WebpageURL,
+	)
+}
+
+// ifPositive returns the string, if the number is positive, or an empty string otherwise.
+func ifPositive(n int64, s string) string {
+	if n > 0 {
+		return s
+	}
+
+	return ""
+}
+
+// joinNonEmpty joins the non-empty strings with the separator.
+func joinNonEmpty(sep string, parts ...string) string {
+	var nonEmpty = make([]string, 0, len(parts))
+
+	for _, p := range parts {
+		if p != "" {
+			nonEmpty = append(nonEmpty, p)
+		}
+	}
+
+	return strings.Join(nonEmpty, sep)
+}
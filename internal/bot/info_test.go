@@ -0,0 +1,61 @@
+package bot_test
+
+import (
+	"slices"
+	"testing"
+
+	"gh.tarampamp.am/video-dl-bot/internal/bot"
+)
+
+func TestBot_StoreInfoCard(t *testing.T) {
+	t.Parallel()
+
+	t.Run("per user", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			b      = new(bot.Bot)
+			tokens []string
+		)
+
+		for range 10 {
+			tokens = append(tokens, bot.StoreInfoCardForTest(b, 1))
+		}
+
+		bot.StoreInfoCardForTest(b, 2)
+
+		var cards = bot.InfoCardsForTest(b)
+
+		if got := cards[1]; len(got) != 5 || !slices.Contains(got, tokens[len(tokens)-1]) {
+			t.Errorf("expected the 5 newest cards of the user, got %v", got)
+		}
+
+		if got := cards[2]; len(got) != 1 {
+			t.Errorf("the cards of another user are affected: %v", got)
+		}
+	})
+
+	t.Run("global", func(t *testing.T) {
+		t.Parallel()
+
+		var b = new(bot.Bot)
+
+		for id := range 250 {
+			bot.StoreInfoCardForTest(b, int64(id))
+		}
+
+		var total int
+
+		for _, tokens := range bot.InfoCardsForTest(b) {
+			total += len(tokens)
+		}
+
+		if total != 200 {
+			t.Errorf("expected 200 cards, got %d", total)
+		}
+
+		if _, ok := bot.InfoCardsForTest(b)[249]; !ok {
+			t.Error("the newest card is forgotten")
+		}
+	})
+}
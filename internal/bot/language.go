@@ -0,0 +1,157 @@
+package bot
+
+import (
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+
+	tele "gopkg.in/telebot.v4"
+
+	"gh.tarampamp.am/video-dl-bot/internal/i18n"
+	"gh.tarampamp.am/video-dl-bot/internal/storage"
+)
+
+// languageAuto is the "/language" command argument to use the Telegram client language.
+const languageAuto = "auto"
+
+// btnLanguage is the inline button of the language list (the data is a language code, or "auto").
+var btnLanguage = tele.InlineButton{Unique: "language"} //nolint:gochecknoglobals
+
+// tr returns the localizer for the user: the language chosen with the "/language" command (if any), or the Telegram
+// client one. Without the database, the chosen languages are kept in memory (until the restart).
+func (b *Bot) tr(user *tele.User) i18n.Localizer {
+	if user == nil {
+		return b.i18n.Localizer(i18n.DefaultLanguage)
+	}
+
+	var lang = user.LanguageCode
+
+	if b.db != nil {
+		if u, err := b.db.User(user.ID); err == nil && u.Language != "" {
+			lang = u.Language
+		}
+	} else if v, ok := b.languages.Load(user.ID); ok {
+		lang = v.(string) //nolint:forcetypeassert
+	}
+
+	return b.i18n.Localizer(lang)
+}
+
+// handleLanguageCommand returns a handler for the "/language [code|auto]" command, that overrides the language of
+// the bot replies (being sent without arguments, it shows the list of the supported languages).
+func (b *Bot) handleLanguageCommand() tele.HandlerFunc {
+	return func(c tele.Context) error {
+		var (
+			user = c.Sender()
+			lang = strings.ToLower(strings.TrimSpace(c.Message().Payload))
+		)
+
+		if lang == "" {
+			var l = b.tr(user)
+
+			return b.reply(c.Message(), l.T("language-choose", i18n.Vars{"language": l.T("language-name", nil)}),
+				b.languageMarkup(l),
+			)
+		}
+
+		if lang != languageAuto && !slices.Contains(b.i18n.Languages(), lang) {
+			return b.reply(c.Message(), b.tr(user).T("language-unknown", i18n.Vars{
+				"language":  lang,
+				"languages": strings.Join(append(b.i18n.Languages(), languageAuto), ", "),
+			}))
+		}
+
+		if err := b.setLanguage(user, lang); err != nil {
+			return err
+		}
+
+		var l = b.tr(user)
+
+		return b.reply(c.Message(), l.T("language-set", i18n.Vars{"language": l.T("language-name", nil)}))
+	}
+}
+
+// handleLanguageButton returns a handler for the language list buttons.
+func (b *Bot) handleLanguageButton() tele.HandlerFunc {
+	return func(c tele.Context) error {
+		var user, lang = c.Sender(), c.Callback().Data
+
+		if lang != languageAuto && !slices.Contains(b.i18n.Languages(), lang) {
+			return c.Respond()
+		}
+
+		if err := b.setLanguage(user, lang); err != nil {
+			return err
+		}
+
+		var (
+			l    = b.tr(user)
+			text = l.T("language-set", i18n.Vars{"language": l.T("language-name", nil)})
+		)
+
+		_ = c.Respond(&tele.CallbackResponse{Text: text})
+
+		return c.Edit(text)
+	}
+}
+
+// setLanguage saves the language chosen by the user ("auto" resets it to the Telegram client one). Without the
+// database, the language is kept in memory.
+func (b *Bot) setLanguage(user *tele.User, lang string) error {
+	if lang == languageAuto {
+		lang = ""
+	}
+
+	switch {
+	case b.db != nil:
+		if err := b.saveUserLanguage(user.ID, lang); err != nil {
+			return err
+		}
+	case lang == "":
+		b.languages.Delete(user.ID)
+	default:
+		b.languages.Store(user.ID, lang)
+	}
+
+	b.log.Info("user language changed",
+		slog.String("sender_name", user.FirstName),
+		slog.Int64("sender_id", user.ID),
+		slog.String("language", lang),
+	)
+
+	return nil
+}
+
+// saveUserLanguage saves the language of the user to the database.
+func (b *Bot) saveUserLanguage(id int64, lang string) error {
+	u, err := b.db.User(id)
+	if errors.Is(err, storage.ErrNotFound) {
+		u, err = &storage.User{ID: id}, nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	u.Language = lang
+
+	return b.db.SaveUser(u)
+}
+
+// languageMarkup returns the inline keyboard with the supported languages (one per row, in their own names).
+func (b *Bot) languageMarkup(l i18n.Localizer) *tele.ReplyMarkup {
+	var markup = tele.ReplyMarkup{}
+
+	for _, lang := range b.i18n.Languages() {
+		markup.InlineKeyboard = append(markup.InlineKeyboard, []tele.InlineButton{
+			inlineButton(btnLanguage, b.i18n.Localizer(lang).T("language-name", nil), lang),
+		})
+	}
+
+	markup.InlineKeyboard = append(markup.InlineKeyboard, []tele.InlineButton{
+		inlineButton(btnLanguage, l.T("language-auto", nil), languageAuto),
+	})
+
+	return &markup
+}
@@ -23,6 +23,8 @@ func (lim Limiter) Acquire(ctx context.Context) error {
 	select {
 	case lim <- struct{}{}: // acquire a limiter slot
 		if err := ctx.Err(); err != nil {
+			<-lim // the caller does not release the slot on error
+
 			return err
 		}
 	case <-ctx.Done():
@@ -0,0 +1,98 @@
+package bot_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gh.tarampamp.am/video-dl-bot/internal/bot"
+)
+
+func TestLimiter_Acquire(t *testing.T) {
+	t.Parallel()
+
+	var canceled, cancel = context.WithCancel(context.Background())
+
+	cancel()
+
+	for name, tc := range map[string]struct {
+		giveSize     int
+		giveBusy     int // the slots, acquired before
+		giveCtx      context.Context
+		wantErr      bool
+		wantAcquired int // the slots, acquired after the call
+	}{
+		"free slot": {
+			giveSize:     2,
+			giveBusy:     1,
+			giveCtx:      context.Background(),
+			wantAcquired: 2,
+		},
+		"no free slots": {
+			giveSize:     1,
+			giveBusy:     1,
+			giveCtx:      canceled,
+			wantErr:      true,
+			wantAcquired: 1,
+		},
+		"canceled context does not keep the free slot": {
+			giveSize:     2,
+			giveCtx:      canceled,
+			wantErr:      true,
+			wantAcquired: 0,
+		},
+		"unlimited": {
+			giveCtx: context.Background(),
+		},
+		"unlimited with canceled context": {
+			giveCtx: canceled,
+			wantErr: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var lim = make(bot.Limiter, tc.giveSize)
+
+			for range tc.giveBusy {
+				lim <- struct{}{}
+			}
+
+			var err error
+
+			// the select picks the ready case randomly, so the failing call is repeated
+			for range 100 {
+				if err = lim.Acquire(tc.giveCtx); err == nil {
+					break
+				}
+			}
+
+			if (err != nil) != tc.wantErr {
+				t.Errorf("want error = %t, got %v", tc.wantErr, err)
+			}
+
+			if got := len(lim); got != tc.wantAcquired {
+				t.Errorf("want %d acquired slots, got %d", tc.wantAcquired, got)
+			}
+		})
+	}
+}
+
+func TestLimiter_AcquireWaits(t *testing.T) {
+	t.Parallel()
+
+	var lim = make(bot.Limiter, 1)
+
+	if err := lim.Acquire(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	go func() { time.Sleep(10 * time.Millisecond); lim.Release() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := lim.Acquire(ctx); err != nil {
+		t.Errorf("the slot is not acquired after the release: %v", err)
+	}
+}
@@ -0,0 +1,244 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"gh.tarampamp.am/video-dl-bot/internal/filestorage"
+	"gh.tarampamp.am/video-dl-bot/internal/i18n"
+	"gh.tarampamp.am/video-dl-bot/internal/storage"
+	ytdlp "gh.tarampamp.am/video-dl-bot/internal/yt-dlp"
+)
+
+const (
+	defaultLinkTTL      = 48 * time.Hour   // lifetime of the links, if the storage doesn't report it
+	linksExpireInterval = 30 * time.Minute // how often the expired links are checked
+	linkExpiryFormat    = "2006-01-02 15:04 UTC"
+)
+
+// btnLinkDelete is the "Delete now" button of the links list (data: link ID).
+var btnLinkDelete = tele.InlineButton{Unique: "link_delete"} //nolint:gochecknoglobals
+
+// registerLinksHandlers registers the handlers of the "/links" command and its buttons.
+func (b *Bot) registerLinksHandlers(ctx context.Context) {
+	b.client.Handle("/links", b.handleLinksCommand())
+	b.client.Handle(&btnLinkDelete, b.handleLinkDelete(ctx))
+}
+
+// handleLinksCommand returns a handler for the "/links" command, that lists the user's active links.
+func (b *Bot) handleLinksCommand() tele.HandlerFunc {
+	return func(c tele.Context) error {
+		text, markup, err := b.linksList(c.Sender())
+		if err != nil {
+			return err
+		}
+
+		return b.reply(c.Message(), text, markup, &tele.SendOptions{DisableWebPagePreview: true})
+	}
+}
+
+// handleLinkDelete returns a handler for the "Delete now" buttons, that delete the file from the storage.
+func (b *Bot) handleLinkDelete(pCtx context.Context) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		var (
+			user  = c.Sender()
+			l     = b.tr(user)
+			id, _ = strconv.ParseUint(c.Callback().Data, 10, 64)
+		)
+
+		link, err := b.db.Link(id)
+		if errors.Is(err, storage.ErrNotFound) || (err == nil && link.UserID != user.ID) {
+			return c.Respond(&tele.CallbackResponse{Text: l.T("link-not-found", nil)})
+		} else if err != nil {
+			return err
+		}
+
+		if err = b.deleteLink(pCtx, link); err != nil {
+			b.log.Error("failed to delete the uploaded file",
+				slog.String("error", err.Error()),
+				slog.String("backend", link.Backend),
+				slog.String("object_id", link.ObjectID),
+				slog.Int64("sender_id", user.ID),
+			)
+
+			return c.Respond(&tele.CallbackResponse{Text: l.T("link-delete-failed", nil)})
+		}
+
+		b.log.Info("uploaded file deleted by the user",
+			slog.String("backend", link.Backend),
+			slog.String("object_id", link.ObjectID),
+			slog.Int64("sender_id", user.ID),
+		)
+
+		text, markup, err := b.linksList(user)
+		if err != nil {
+			return err
+		}
+
+		_ = c.Respond(&tele.CallbackResponse{Text: l.T("link-deleted", nil)})
+
+		return c.Edit(text, markup, &tele.SendOptions{DisableWebPagePreview: true})
+	}
+}
+
+// linksList renders the list of the user's active links.
+func (b *Bot) linksList(user *tele.User) (string, *tele.ReplyMarkup, error) {
+	var l = b.tr(user)
+
+	list, err := b.db.Links(user.ID, time.Now())
+	if err != nil {
+		return "", nil, err
+	}
+
+	var markup = tele.ReplyMarkup{}
+
+	if len(list) == 0 {
+		return l.T("links-empty", nil), &markup, nil
+	}
+
+	var text strings.Builder
+
+	text.WriteString(l.T("links-list", i18n.Vars{"count": len(list)}) + "\n")
+
+	for i, link := range list {
+		var title = link.Title
+		if title == "" {
+			title = link.VideoURL
+		}
+
+		fmt.Fprintf(&text, "\n%d. %s\n%s\n%s · %s\n", i+1, truncate(title, historyMaxTitleLen), link.URL,
+			formatSize(link.Size),
+			l.T("links-expires", i18n.Vars{"date": link.ExpiresAt.UTC().Format(linkExpiryFormat)}),
+		)
+
+		markup.InlineKeyboard = append(markup.InlineKeyboard, []tele.InlineButton{
+			inlineButton(btnLinkDelete, l.T("link-delete-button", i18n.Vars{"number": i + 1}),
+				strconv.FormatUint(link.ID, 10),
+			),
+		})
+	}
+
+	return text.String(), &markup, nil
+}
+
+// saveLink records the uploaded file (if the database is set), so it can be listed and deleted later. The link
+// expiration time is returned.
+func (b *Bot) saveLink(
+	user *tele.User,
+	videoUrl *url.URL,
+	dl *ytdlp.Downloaded,
+	size int64,
+	obj *filestorage.Object,
+) time.Time {
+	var expiresAt = obj.ExpiresAt
+
+	if expiresAt.IsZero() {
+		expiresAt = time.Now().Add(defaultLinkTTL)
+	}
+
+	if b.db == nil {
+		return expiresAt
+	}
+
+	var link = storage.Link{
+		UserID:       user.ID,
+		Backend:      b.uploader.Name(),
+		ObjectID:     obj.ID,
+		URL:          obj.URL,
+		VideoURL:     videoUrl.String(),
+		Title:        dl.Title,
+		Size:         size,
+		SelfExpiring: obj.SelfExpiring,
+		ExpiresAt:    expiresAt,
+	}
+
+	if err := b.db.AddLink(&link); err != nil {
+		b.log.Error("failed to save the uploaded file link",
+			slog.String("error", err.Error()),
+			slog.Int64("sender_id", user.ID),
+			slog.String("video_url", videoUrl.String()),
+		)
+	}
+
+	return expiresAt
+}
+
+// deleteLink deletes the file from the storage, and then the link record.
+func (b *Bot) deleteLink(ctx context.Context, link *storage.Link) error {
+	if name := b.uploader.Name(); link.Backend != name {
+		return fmt.Errorf("the file is uploaded to %q, but the current storage is %q", link.Backend, name)
+	}
+
+	if err := b.uploader.Delete(ctx, link.ObjectID); err != nil {
+		return err
+	}
+
+	if err := b.db.DeleteLink(link.ID); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+// expireLinks deletes the expired files from the storages, that don't delete them by themselves (e.g., S3 or
+// tus without the expiration extension), and removes the expired link records. Blocks until the context is
+// canceled.
+func (b *Bot) expireLinks(ctx context.Context) {
+	var ticker = time.NewTicker(linksExpireInterval)
+	defer ticker.Stop()
+
+	for {
+		expired, err := b.db.ExpiredLinks(time.Now())
+		if err != nil {
+			b.log.Error("failed to read the expired links", slog.String("error", err.Error()))
+		}
+
+		var removed int
+
+		for _, link := range expired {
+			switch {
+			case link.SelfExpiring:
+				err = b.db.DeleteLink(link.ID)
+			case link.Backend != b.uploader.Name():
+				b.log.Warn("expired file belongs to another storage, and cannot be deleted",
+					slog.String("backend", link.Backend),
+					slog.String("object_id", link.ObjectID),
+				)
+
+				err = b.db.DeleteLink(link.ID)
+			default:
+				err = b.deleteLink(ctx, &link) // on failure, the link is kept, so the deletion is retried later
+			}
+
+			if err != nil {
+				b.log.Warn("failed to delete the expired file",
+					slog.String("error", err.Error()),
+					slog.String("backend", link.Backend),
+					slog.String("object_id", link.ObjectID),
+				)
+
+				continue
+			}
+
+			removed++
+		}
+
+		if removed > 0 {
+			b.log.Info("expired links removed", slog.Int("count", removed))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
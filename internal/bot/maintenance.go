@@ -0,0 +1,95 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	tele "gopkg.in/telebot.v4"
+
+	"gh.tarampamp.am/video-dl-bot/internal/i18n"
+	"gh.tarampamp.am/video-dl-bot/internal/storage"
+)
+
+// maintenanceStateKey is the key of the maintenance state in the database.
+const maintenanceStateKey = "maintenance"
+
+// maintenanceState describes the maintenance mode. While it's enabled, the download requests of the regular users
+// are answered with the notice; the administrators can use the bot as usual.
+type maintenanceState struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message,omitempty"` // custom notice (if empty, the MsgMaintenance reply is used)
+}
+
+// loadMaintenance restores the maintenance state, saved in the database (if it's enabled).
+func (b *Bot) loadMaintenance() error {
+	var m maintenanceState
+
+	if b.db != nil {
+		if err := b.db.LoadState(maintenanceStateKey, &m); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return fmt.Errorf("failed to load the maintenance state: %w", err)
+		}
+	}
+
+	b.maintenance.Store(&m)
+
+	return nil
+}
+
+// maintenanceNotice returns the notice for the users, if the maintenance mode is enabled.
+func (b *Bot) maintenanceNotice(l i18n.Localizer) (string, bool) {
+	var m = b.maintenance.Load()
+	if !m.Enabled {
+		return "", false
+	}
+
+	if m.Message != "" {
+		return m.Message, true
+	}
+
+	return b.state().Message(MsgMaintenance, l.T("maintenance", nil)), true
+}
+
+// handleMaintenanceCommand returns a handler for the "/maintenance on|off [message]" command, that toggles the
+// maintenance mode (being sent without arguments, it shows the current state).
+func (b *Bot) handleMaintenanceCommand() tele.HandlerFunc {
+	return func(c tele.Context) error {
+		var (
+			msg          = c.Message()
+			l            = b.tr(c.Sender())
+			mode, txt, _ = strings.Cut(strings.TrimSpace(msg.Payload), " ")
+			m            maintenanceState
+		)
+
+		switch mode = strings.ToLower(mode); mode {
+		case "on":
+			m = maintenanceState{Enabled: true, Message: strings.TrimSpace(txt)}
+		case "off", "":
+		default:
+			return b.reply(msg, l.T("maintenance-usage", nil))
+		}
+
+		if mode != "" {
+			if b.db != nil {
+				if err := b.db.SaveState(maintenanceStateKey, m); err != nil {
+					return fmt.Errorf("failed to save the maintenance state: %w", err)
+				}
+			}
+
+			b.maintenance.Store(&m)
+
+			b.log.Info("maintenance mode changed",
+				slog.Bool("enabled", m.Enabled),
+				slog.String("message", m.Message),
+				slog.Int64("admin_id", c.Sender().ID),
+			)
+		}
+
+		if notice, enabled := b.maintenanceNotice(l); enabled {
+			return b.reply(msg, l.T("maintenance-on", i18n.Vars{"notice": notice}))
+		}
+
+		return b.reply(msg, l.T("maintenance-off", nil))
+	}
+}
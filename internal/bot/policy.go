@@ -0,0 +1,200 @@
+package bot
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"gh.tarampamp.am/video-dl-bot/internal/i18n"
+	"gh.tarampamp.am/video-dl-bot/internal/proxy"
+	ytdlp "gh.tarampamp.am/video-dl-bot/internal/yt-dlp"
+)
+
+// extractorPools creates the proxy pools for the extractor site settings (the key is a lowercased extractor name).
+func (s Settings) extractorPools() (map[string]*proxy.Pool, error) {
+	var pools = make(map[string]*proxy.Pool)
+
+	for _, site := range s.Sites {
+		if site.Extractor == "" || len(site.Proxies) == 0 {
+			continue
+		}
+
+		pool, err := proxy.NewPool(site.Proxies)
+		if err != nil {
+			return nil, err
+		}
+
+		pools[strings.ToLower(site.Extractor)] = pool
+	}
+
+	return pools, nil
+}
+
+// pool returns the proxy pool for the site settings (the extractor one, if it has the proxies), or for the host.
+func (s *liveState) pool(host string, site *Site) *proxy.Pool {
+	if site != nil && site.Extractor != "" {
+		if pool, ok := s.extractorProxies[strings.ToLower(site.Extractor)]; ok {
+			return pool
+		}
+	}
+
+	return s.proxies.Pool(host)
+}
+
+// maxDuration returns the maximal duration of the videos from the site (0 = unlimited).
+func (s *liveState) maxDuration(site *Site) time.Duration {
+	if site != nil && site.MaxDuration > 0 {
+		return site.MaxDuration
+	}
+
+	return s.MaxDuration
+}
+
+// maxFileSize returns the maximal file size of the videos from the site (0 = unlimited).
+func (s *liveState) maxFileSize(site *Site) int64 {
+	if site != nil && site.MaxFileSize > 0 {
+		return site.MaxFileSize
+	}
+
+	return s.MaxFileSize
+}
+
+// recordDuration returns how long the live streams from the site are recorded (not longer than the maximal
+// duration of the videos; 0 = live streams are not downloaded).
+func (s *liveState) recordDuration(site *Site) time.Duration {
+	if limit := s.maxDuration(site); limit > 0 && limit < s.LiveRecordDuration {
+		return limit
+	}
+
+	return s.LiveRecordDuration
+}
+
+// ytDlpOptions returns the yt-dlp options for the host, with the site settings applied (if any).
+func (b *Bot) ytDlpOptions(state *liveState, site *Site, host string) []ytdlp.Option {
+	var (
+		opts = []ytdlp.Option{
+			ytdlp.WithMinFileSize(state.MinFileSize),
+			ytdlp.WithMaxFileSize(state.MaxFileSize),
+			ytdlp.WithConvert(b.videoConvert),
+		}
+		cookiesFile string
+		jsRuntimes  = b.jsRuntimes
+	)
+
+	if b.cookies != nil {
+		cookiesFile = b.cookies.Lookup(host) // the most specific domain file or the default one
+	}
+
+	if site != nil {
+		if site.CookiesDomain != "" && b.cookies != nil {
+			if path := b.cookies.Path(site.CookiesDomain); path != "" {
+				cookiesFile = path
+			}
+		}
+
+		if site.JSRuntimes != "" {
+			jsRuntimes = site.JSRuntimes
+		}
+
+		if site.Format != "" {
+			opts = append(opts, ytdlp.WithFormat(site.Format))
+		}
+
+		if site.MaxFileSize > 0 {
+			opts = append(opts, ytdlp.WithMaxFileSize(site.MaxFileSize))
+		}
+	}
+
+	if cookiesFile != "" {
+		opts = append(opts, ytdlp.WithCookiesFile(cookiesFile))
+	}
+
+	if jsRuntimes != "" {
+		opts = append(opts, ytdlp.WithJSRuntimes(jsRuntimes))
+	}
+
+	if b.workspace != nil {
+		opts = append(opts, ytdlp.WithWorkDir(b.workspace.Dir()))
+	}
+
+	return opts
+}
+
+// policyRejection checks the probed video against the site policy, and returns the error class and the reply for
+// the user, if the download is not allowed (empty strings otherwise).
+func (s *liveState) policyRejection(l i18n.Localizer, site *Site, info *ytdlp.Info) (class, reply string) {
+	var limit, sizeLimit = s.maxDuration(site), s.maxFileSize(site)
+
+	switch {
+	case site != nil && site.Deny:
+		return "site-denied", l.T("site-denied", nil)
+	case info.IsLive && s.recordDuration(site) <= 0:
+		return "live-stream", l.T("live-not-supported", nil)
+	case !info.IsLive && limit > 0 && info.Duration > limit && !s.ConfirmLongVideos:
+		return "too-long", l.T("video-too-long", i18n.Vars{
+			"duration": i18n.FormatDuration(info.Duration),
+			"limit":    i18n.FormatDuration(limit),
+		})
+	case !info.IsLive && sizeLimit > 0 && info.FileSize > sizeLimit: // the size is estimated, but it's far too large
+		return "too-large", l.T("video-too-large", i18n.Vars{
+			"size":  formatSize(info.FileSize),
+			"limit": formatSize(sizeLimit),
+		})
+	}
+
+	return "", ""
+}
+
+// confirmation checks whether the probed video should be downloaded after the user confirmation only (the live
+// streams, and the long videos if ConfirmLongVideos is set), and returns the error class, the question and the
+// confirmation button text (empty strings if the confirmation is not needed).
+func (s *liveState) confirmation(l i18n.Localizer, site *Site, info *ytdlp.Info) (class, question, button string) {
+	var limit = s.maxDuration(site)
+
+	switch {
+	case info.IsLive:
+		var minutes = max(1, int(s.recordDuration(site).Round(time.Minute).Minutes()))
+
+		return "live-stream", l.T("live-stream", i18n.Vars{"minutes": minutes}),
+			l.T("live-record-button", i18n.Vars{"minutes": minutes})
+	case limit > 0 && info.Duration > limit:
+		return "too-long", l.T("video-too-long-confirm", i18n.Vars{
+			"duration": i18n.FormatDuration(info.Duration),
+			"limit":    i18n.FormatDuration(limit),
+		}), l.T("download-anyway-button", nil)
+	}
+
+	return "", "", ""
+}
+
+// probe extracts the video metadata using the retry policy (like the downloads do), switching to the next proxy
+// when the site blocks the current one.
+func (b *Bot) probe(
+	ctx context.Context,
+	user *tele.User,
+	videoUrl string,
+	pool *proxy.Pool,
+	opts []ytdlp.Option,
+) (info *ytdlp.Info, err error) {
+	err = b.withProxies(pool, videoUrl, func(proxyUrl string) (pErr error) {
+		info, pErr = ytdlp.ProbeWithRetry(ctx, videoUrl, b.userRetryPolicy(user, videoUrl),
+			append(slices.Clip(opts), ytdlp.WithProxy(proxyUrl))...,
+		)
+
+		return pErr
+	})
+	if err != nil {
+		b.log.Warn("failed to probe the video",
+			slog.String("error", err.Error()),
+			slog.String("sender_name", user.FirstName),
+			slog.Int64("sender_id", user.ID),
+			slog.String("video_url", videoUrl),
+		)
+	}
+
+	return info, err
+}
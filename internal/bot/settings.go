@@ -0,0 +1,121 @@
+package bot
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"gh.tarampamp.am/video-dl-bot/internal/hostmatch"
+	"gh.tarampamp.am/video-dl-bot/internal/proxy"
+)
+
+// Names of the bot replies that can be customized (see Settings.Messages).
+const (
+	MsgStart          = "start"           // greeting on the "/start" command (plain text)
+	MsgInvalidLink    = "invalid-link"    // reply to a message without a valid link (MarkdownV2)
+	MsgDownloadFailed = "download-failed" // reply when the video cannot be downloaded (plain text)
+	MsgAccessDenied   = "access-denied"   // reply to users that are not allowed to use the bot (plain text)
+	MsgMaintenance    = "maintenance"     // reply to download requests in the maintenance mode (plain text)
+)
+
+type (
+	// Settings contains the bot settings that can be changed at runtime, without restarting the bot (see
+	// Bot.Reload).
+	Settings struct {
+		MaxConcurrentDownloads uint              // maximum number of concurrent downloads allowed
+		AllowedUsers           []int64           // if not empty, only these users (and admins) can use the bot
+		Admins                 []int64           // IDs of the bot administrators
+		Messages               map[string]string // custom replies (see the Msg* constants for the names)
+		Sites                  []Site            // per-site overrides (the most specific match is used)
+		Proxies                []string          // proxies for yt-dlp, rotated when the site blocks the current one
+		MinFileSize            int64             // smaller files are not downloaded (in bytes; 0 = yt-dlp default)
+		MaxFileSize            int64             // larger files are not downloaded (in bytes; 0 = yt-dlp default)
+		MaxDuration            time.Duration     // longer videos are not downloaded (0 = unlimited; see Site)
+		ConfirmLongVideos      bool              // ask the user to confirm the long videos download, instead of rejecting
+		LiveRecordDuration     time.Duration     // live streams are recorded for this long (0 = not downloaded)
+	}
+
+	// Site contains the policy of a specific site, that overrides the global settings. The site is identified by
+	// the domain or by the yt-dlp extractor name (one of them is set).
+	Site struct {
+		Domain        string        // domain name or glob pattern (e.g., "youtube.com", "*.example.com")
+		Extractor     string        // yt-dlp extractor name (e.g., "youtube", "generic"; see Downloaded.Extractor)
+		Deny          bool          // downloading from the site is not allowed
+		MaxDuration   time.Duration // longer videos are not downloaded (optional; overrides the global limit)
+		MaxFileSize   int64         // larger files are not downloaded (in bytes; optional)
+		CookiesDomain string        // domain of the site cookies file in the cookies store (see cookies.SiteDomain)
+		JSRuntimes    string        // JavaScript runtimes for yt-dlp (optional)
+		Format        string        // yt-dlp format selector (optional)
+		Proxies       []string      // proxies for the site (optional; use "direct" to bypass the global proxies)
+	}
+)
+
+// normalize returns the settings with the values clamped to the allowed ranges.
+func (s Settings) normalize() Settings {
+	s.MaxConcurrentDownloads = max(1, min(100, s.MaxConcurrentDownloads)) //nolint:mnd
+
+	return s
+}
+
+// proxyRouter creates the proxy router for the global and per-site proxies.
+func (s Settings) proxyRouter() (*proxy.Router, error) {
+	var rules = make(map[string][]string, len(s.Sites))
+
+	for _, site := range s.Sites {
+		if site.Domain != "" {
+			rules[site.Domain] = site.Proxies
+		}
+	}
+
+	return proxy.NewRouter(s.Proxies, rules)
+}
+
+// IsAdmin reports whether the user with the given ID is a bot administrator.
+func (s Settings) IsAdmin(userID int64) bool { return slices.Contains(s.Admins, userID) }
+
+// IsAllowed reports whether the user with the given ID is allowed to use the bot.
+func (s Settings) IsAllowed(userID int64) bool {
+	return len(s.AllowedUsers) == 0 || slices.Contains(s.AllowedUsers, userID) || s.IsAdmin(userID)
+}
+
+// Message returns the custom reply with the given name, or the fallback if it's not customized.
+func (s Settings) Message(name, fallback string) string {
+	if msg, ok := s.Messages[name]; ok && msg != "" {
+		return msg
+	}
+
+	return fallback
+}
+
+// Site returns the most specific site settings for the given host, or nil if there are no overrides for it.
+func (s Settings) Site(host string) (found *Site) {
+	for i := range s.Sites {
+		if s.Sites[i].Domain == "" || !hostmatch.Match(s.Sites[i].Domain, host) {
+			continue
+		}
+
+		if found == nil || hostmatch.Specificity(s.Sites[i].Domain) > hostmatch.Specificity(found.Domain) {
+			found = &s.Sites[i]
+		}
+	}
+
+	return found
+}
+
+// Policy returns the site settings for the extractor (the name is compared case-insensitively, and "youtube" also
+// matches the "youtube:tab" extractor), or the settings for the host (see Site) if there are no extractor ones.
+func (s Settings) Policy(host, extractor string) *Site {
+	if extractor != "" {
+		var base, _, _ = strings.Cut(extractor, ":")
+
+		for _, want := range []string{extractor, base} { // the exact match wins
+			for i := range s.Sites {
+				if name := s.Sites[i].Extractor; name != "" && strings.EqualFold(name, want) {
+					return &s.Sites[i]
+				}
+			}
+		}
+	}
+
+	return s.Site(host)
+}
@@ -0,0 +1,57 @@
+package bot_test
+
+import (
+	"testing"
+
+	"gh.tarampamp.am/video-dl-bot/internal/bot"
+)
+
+func TestSettings_Policy(t *testing.T) {
+	t.Parallel()
+
+	var s = bot.Settings{Sites: []bot.Site{
+		{Domain: "youtube.com", Format: "domain"},
+		{Domain: "*.example.com", Deny: true},
+		{Extractor: "youtube", Format: "extractor"},
+		{Extractor: "YouTube:Tab", Format: "tab"},
+		{Extractor: "generic", Deny: true},
+	}}
+
+	for name, tc := range map[string]struct {
+		giveHost, giveExtractor string
+		wantFormat              string
+		wantDeny                bool
+		wantNil                 bool
+	}{
+		"domain":                       {giveHost: "www.youtube.com", wantFormat: "domain"},
+		"extractor wins":               {giveHost: "www.youtube.com", giveExtractor: "youtube", wantFormat: "extractor"},
+		"extractor base":               {giveHost: "youtu.be", giveExtractor: "youtube:playlist", wantFormat: "extractor"},
+		"exact extractor":              {giveHost: "youtu.be", giveExtractor: "youtube:tab", wantFormat: "tab"},
+		"glob":                         {giveHost: "foo.example.com", wantDeny: true},
+		"generic":                      {giveHost: "foo.bar", giveExtractor: "generic", wantDeny: true},
+		"unknown extractor, no domain": {giveHost: "foo.bar", giveExtractor: "vimeo", wantNil: true},
+		"unknown extractor, domain":    {giveHost: "youtube.com", giveExtractor: "vimeo", wantFormat: "domain"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var got = s.Policy(tc.giveHost, tc.giveExtractor)
+
+			if tc.wantNil {
+				if got != nil {
+					t.Errorf("expected no policy, got %+v", *got)
+				}
+
+				return
+			}
+
+			if got == nil {
+				t.Fatal("expected the policy, got nil")
+			}
+
+			if got.Format != tc.wantFormat || got.Deny != tc.wantDeny {
+				t.Errorf("unexpected policy: %+v", *got)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// drainCancelTimeout is how long the jobs, canceled after the drain timeout, are waited for (to notify the users and
+// to clean up the downloaded files).
+const drainCancelTimeout = 15 * time.Second
+
+// errShuttingDown is the cause of the job context cancellation on the bot shutdown.
+var errShuttingDown = errors.New("the bot is shutting down")
+
+// drainer tracks the download jobs, so they can be finished gracefully on the bot shutdown: the new jobs are not
+// accepted, the queued ones are canceled, and the running ones are waited for (and canceled after the timeout).
+type drainer struct {
+	ctx    context.Context         // parent context of the jobs (canceled after the drain timeout)
+	cancel context.CancelCauseFunc // cancels the jobs context
+
+	queueCtx    context.Context    // canceled when the drain starts (the queued jobs are canceled)
+	cancelQueue context.CancelFunc // starts the drain
+
+	mu       sync.Mutex // guards the flag and the jobs counter (so the jobs cannot be added, while waiting for them)
+	draining bool
+	jobs     sync.WaitGroup
+}
+
+// newDrainer creates a new drainer. The jobs context is not canceled together with the parent one (only after the
+// drain timeout), but keeps its values.
+func newDrainer(ctx context.Context) *drainer {
+	var d drainer
+
+	d.ctx, d.cancel = context.WithCancelCause(context.WithoutCancel(ctx))
+	d.queueCtx, d.cancelQueue = context.WithCancel(context.Background())
+
+	return &d
+}
+
+// Accept registers the new job, if the drain has not started yet. The done function must be called when the job is
+// finished.
+func (d *drainer) Accept() (done func(), ok bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.draining {
+		return nil, false
+	}
+
+	d.jobs.Add(1)
+
+	return d.jobs.Done, true
+}
+
+// Drain stops accepting the new jobs, cancels the queued ones, and waits for the running ones up to the timeout.
+// After the timeout, the rest jobs are canceled (and waited for a bit more). It reports whether all the jobs have
+// finished in time.
+func (d *drainer) Drain(timeout time.Duration) (completed bool) {
+	d.mu.Lock()
+	d.draining = true
+	d.mu.Unlock()
+
+	d.cancelQueue()
+
+	var done = make(chan struct{})
+
+	go func() { d.jobs.Wait(); close(done) }()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+	}
+
+	d.cancel(errShuttingDown)
+
+	select {
+	case <-done:
+	case <-time.After(drainCancelTimeout):
+	}
+
+	return false
+}
+
+// WhileQueued cancels the job context (with the errShuttingDown cause), if the drain starts. The returned function
+// stops it, and must be called when the job leaves the queue.
+func (d *drainer) WhileQueued(cancel context.CancelCauseFunc) (stop func() bool) {
+	return context.AfterFunc(d.queueCtx, func() { cancel(errShuttingDown) })
+}
+
+// shuttingDown reports whether the job context is canceled because of the bot shutdown.
+func shuttingDown(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errShuttingDown)
+}
+
+// drain finishes the download jobs gracefully (see drainer.Drain).
+func (b *Bot) drain() {
+	b.log.Info("waiting for the running downloads to finish", slog.Duration("timeout", b.drainTimeout))
+
+	if !b.drainer.Drain(b.drainTimeout) {
+		b.log.Warn("the running downloads are canceled, as the drain timeout is exceeded")
+
+		return
+	}
+
+	b.log.Info("the running downloads are finished")
+}
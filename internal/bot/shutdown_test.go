@@ -0,0 +1,150 @@
+package bot_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gh.tarampamp.am/video-dl-bot/internal/bot"
+)
+
+func TestDrainer_Drain(t *testing.T) {
+	t.Parallel()
+
+	const forever = time.Minute // the job is finished only when it's canceled
+
+	for name, tc := range map[string]struct {
+		giveJobs      []time.Duration // how long the running jobs take
+		giveQueued    bool            // the jobs are waiting in the queue
+		giveLeftQueue bool            // the jobs have left the queue before the drain
+		giveTimeout   time.Duration
+		wantCompleted bool
+		wantCanceled  bool          // the jobs are canceled because of the shutdown
+		wantMaxTime   time.Duration // the drain takes no longer than this
+	}{
+		"no jobs": {
+			giveTimeout:   time.Minute,
+			wantCompleted: true,
+			wantMaxTime:   time.Second,
+		},
+		"jobs finished in time": {
+			giveJobs:      []time.Duration{10 * time.Millisecond, 20 * time.Millisecond},
+			giveTimeout:   time.Minute,
+			wantCompleted: true,
+			wantMaxTime:   time.Second,
+		},
+		"running jobs are canceled after the timeout": {
+			giveJobs:      []time.Duration{10 * time.Millisecond, forever},
+			giveTimeout:   50 * time.Millisecond,
+			wantCompleted: false,
+			wantCanceled:  true,
+			wantMaxTime:   time.Second,
+		},
+		"zero timeout cancels the jobs immediately": {
+			giveJobs:     []time.Duration{forever},
+			wantCanceled: true,
+			wantMaxTime:  time.Second,
+		},
+		"queued jobs are canceled right away": {
+			giveJobs:      []time.Duration{forever, forever},
+			giveQueued:    true,
+			giveTimeout:   time.Minute,
+			wantCompleted: true,
+			wantCanceled:  true,
+			wantMaxTime:   time.Second,
+		},
+		"jobs left the queue are not canceled": {
+			giveJobs:      []time.Duration{20 * time.Millisecond},
+			giveQueued:    true,
+			giveLeftQueue: true,
+			giveTimeout:   time.Minute,
+			wantCompleted: true,
+			wantMaxTime:   time.Second,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				d    = bot.NewDrainerForTest(t.Context())
+				jobs = make([]context.Context, 0, len(tc.giveJobs))
+			)
+
+			for _, duration := range tc.giveJobs {
+				done, ok := d.Accept()
+				if !ok {
+					t.Fatal("the job is not accepted")
+				}
+
+				ctx, cancel := context.WithCancelCause(d.JobsContext())
+				t.Cleanup(func() { cancel(nil) })
+
+				if tc.giveQueued {
+					if leaveQueue := d.WhileQueued(cancel); tc.giveLeftQueue {
+						leaveQueue()
+					}
+				}
+
+				jobs = append(jobs, ctx)
+
+				go func() {
+					defer done()
+
+					select {
+					case <-ctx.Done():
+					case <-time.After(duration):
+					}
+				}()
+			}
+
+			var start = time.Now()
+
+			if got := d.Drain(tc.giveTimeout); got != tc.wantCompleted {
+				t.Errorf("want completed = %t, got %t", tc.wantCompleted, got)
+			}
+
+			if elapsed := time.Since(start); elapsed > tc.wantMaxTime {
+				t.Errorf("the drain took too long: %s", elapsed)
+			}
+
+			var canceled bool
+
+			for _, ctx := range jobs {
+				canceled = canceled || bot.ShuttingDownForTest(ctx)
+			}
+
+			if canceled != tc.wantCanceled {
+				t.Errorf("want canceled = %t, got %t", tc.wantCanceled, canceled)
+			}
+		})
+	}
+}
+
+func TestDrainer_AcceptAfterDrain(t *testing.T) {
+	t.Parallel()
+
+	var d = bot.NewDrainerForTest(t.Context())
+
+	done, ok := d.Accept()
+	if !ok {
+		t.Fatal("the job is not accepted before the drain")
+	}
+
+	done()
+
+	if !d.Drain(time.Second) {
+		t.Error("the drain is not completed")
+	}
+
+	if _, ok = d.Accept(); ok {
+		t.Error("the job is accepted after the drain")
+	}
+
+	// the parent context cancellation does not cancel the jobs (they are drained instead)
+	ctx, cancel := context.WithCancel(t.Context())
+	cancel()
+
+	if err := bot.NewDrainerForTest(ctx).JobsContext().Err(); err != nil {
+		t.Errorf("the jobs context is canceled with the parent one: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"gh.tarampamp.am/video-dl-bot/internal/i18n"
+	"gh.tarampamp.am/video-dl-bot/internal/stats"
+	ytdlp "gh.tarampamp.am/video-dl-bot/internal/yt-dlp"
+)
+
+const (
+	statsTopN           = 5               // number of the top extractors/users/error classes to show
+	statsVersionTimeout = 5 * time.Second // timeout for getting the yt-dlp version
+)
+
+// handleStatsCommand returns a handler for the "/stats" command, that shows the usage statistics.
+func (b *Bot) handleStatsCommand(ctx context.Context) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		var l = b.tr(c.Sender())
+
+		vCtx, cancel := context.WithTimeout(ctx, statsVersionTimeout)
+		defer cancel()
+
+		version, err := ytdlp.Version(vCtx)
+		if err != nil {
+			version = l.T("stats-ytdlp-unknown", i18n.Vars{"class": ytdlp.ErrorClass(err)})
+		}
+
+		var lim = b.state().lim
+
+		return b.reply(c.Message(), statsText(l, b.stats.Snapshot(), version, len(lim), cap(lim)))
+	}
+}
+
+// statsText renders the statistics snapshot.
+func statsText(l i18n.Localizer, s stats.Snapshot, ytDlpVersion string, busySlots, totalSlots int) string {
+	var users = make([]string, 0, statsTopN)
+
+	for _, u := range s.TopUsers[:min(len(s.TopUsers), statsTopN)] {
+		users = append(users, fmt.Sprintf("%d (%d)", u.UserID, u.Count))
+	}
+
+	return strings.Join([]string{
+		l.T("stats-title", nil),
+		"",
+		l.T("stats-uptime", i18n.Vars{"uptime": s.Uptime.Truncate(time.Second)}),
+		l.T("stats-ytdlp", i18n.Vars{"version": ytDlpVersion}),
+		l.T("stats-in-flight", i18n.Vars{"count": s.InFlight, "busy": busySlots, "total": totalSlots}),
+		l.T("stats-queued", i18n.Vars{"count": s.Queued}),
+		l.T("stats-total", i18n.Vars{"count": s.TotalJobs}),
+		l.T("stats-served", i18n.Vars{"size": formatSize(s.BytesServed)}),
+		l.T("stats-links", i18n.Vars{"count": s.Links, "count7d": s.Links7d}),
+		"",
+		l.T("stats-downloads-24h", i18n.Vars{"counts": countsText(l, s.Downloads24h)}),
+		l.T("stats-downloads-7d", i18n.Vars{"counts": countsText(l, s.Downloads7d)}),
+		l.T("stats-top-users", i18n.Vars{"users": orNone(l, strings.Join(users, ", "))}),
+		"",
+		l.T("stats-failures", i18n.Vars{
+			"count": s.Failures7d,
+			"rate":  strconv.FormatFloat(s.FailureRate*100, 'f', 1, 64), //nolint:mnd
+		}),
+		l.T("stats-failures-by-class", i18n.Vars{"counts": countsText(l, s.FailuresByClass)}),
+	}, "\n")
+}
+
+// countsText renders the top counters (e.g., "youtube: 10, tiktok: 5"), summing the rest.
+func countsText(l i18n.Localizer, list []stats.Count) string {
+	var parts = make([]string, 0, statsTopN+1)
+
+	for i, c := range list {
+		if i == statsTopN {
+			var rest int
+
+			for _, r := range list[i:] {
+				rest += r.Count
+			}
+
+			parts = append(parts, l.T("stats-others", i18n.Vars{"count": rest}))
+
+			break
+		}
+
+		var name = c.Name
+		if name == "" {
+			name = l.T("stats-unknown", nil)
+		}
+
+		parts = append(parts, fmt.Sprintf("%s: %d", name, c.Count))
+	}
+
+	return orNone(l, strings.Join(parts, ", "))
+}
+
+// orNone returns the string, or "none" if it's empty.
+func orNone(l i18n.Localizer, s string) string {
+	if s == "" {
+		return l.T("stats-none", nil)
+	}
+
+	return s
+}
@@ -0,0 +1,212 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+
+	"gh.tarampamp.am/video-dl-bot/internal/i18n"
+	ytdlp "gh.tarampamp.am/video-dl-bot/internal/yt-dlp"
+)
+
+const (
+	extractorErrorsBurst  = 5                // number of the extractor errors that triggers the automatic update
+	extractorErrorsWindow = 15 * time.Minute // ...within this time window
+	autoUpdateCooldown    = time.Hour        // minimal interval between the automatic updates (by errors burst)
+)
+
+// ytDlpVersionRe is used to validate the yt-dlp release versions (e.g., "2025.01.15", "2025.01.15.1").
+var ytDlpVersionRe = regexp.MustCompile(`^\d{4}\.\d{2}\.\d{2}(\.\d+)?$`)
+
+// ValidateYtDlpVersion checks the yt-dlp release version (empty string and "latest" mean the latest release).
+func ValidateYtDlpVersion(v string) error {
+	if v == "" || v == "latest" || ytDlpVersionRe.MatchString(v) {
+		return nil
+	}
+
+	return fmt.Errorf("invalid yt-dlp version %q (expected something like \"2025.01.15\" or \"latest\")", v)
+}
+
+// updaterState holds the state of the yt-dlp updates, triggered by the bot.
+type updaterState struct {
+	updater  *ytdlp.Updater
+	version  string        // version to install (empty for the latest one)
+	interval time.Duration // interval of the scheduled updates (0 = disabled)
+
+	mu             sync.Mutex
+	extractorErrs  []time.Time // times of the recent extractor errors
+	lastAutoUpdate time.Time   // when the automatic update (by errors burst) was triggered the last time
+}
+
+// WithYtDlpUpdater enables the yt-dlp updates: the pinned version (or the latest one, if the version is empty) is
+// installed with the given interval (0 disables the scheduled updates; the pinned version is installed at startup
+// anyway), after a burst of the extractor errors, and by the "/update_ytdlp" command of the bot administrators.
+func WithYtDlpUpdater(u *ytdlp.Updater, version string, interval time.Duration) Option {
+	return func(b *Bot) { b.updates = &updaterState{updater: u, version: version, interval: interval} }
+}
+
+// handleUpdateCommand returns a handler for the "/update_ytdlp [version|latest|rollback]" command.
+func (b *Bot) handleUpdateCommand(ctx context.Context) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		var (
+			msg     = c.Message()
+			l       = b.tr(c.Sender())
+			version = b.updates.version
+		)
+
+		if args := c.Args(); len(args) > 0 {
+			version = strings.ToLower(args[0])
+		}
+
+		if version == "rollback" {
+			res, err := b.updates.updater.Rollback(ctx)
+			if err != nil {
+				return b.reply(msg, l.T("update-failed", i18n.Vars{"error": err.Error()}))
+			}
+
+			b.log.Info("yt-dlp rolled back", slog.String("version", res.Version), slog.String("path", res.Path))
+
+			return b.reply(msg, l.T("update-rolled-back", i18n.Vars{
+				"version":  res.Version,
+				"previous": res.PreviousVersion,
+			}))
+		}
+
+		if err := ValidateYtDlpVersion(version); err != nil {
+			return b.reply(msg, l.T("update-invalid-version", i18n.Vars{"version": version}))
+		}
+
+		_ = b.reply(msg, l.T("update-in-progress", nil))
+
+		res, err := b.updateYtDlp(ctx, version, "command")
+		if err != nil {
+			return b.reply(msg, l.T("update-failed", i18n.Vars{"error": err.Error()}))
+		}
+
+		return b.reply(msg, updateResultText(l, res))
+	}
+}
+
+// scheduleYtDlpUpdates installs the pinned (or the latest) yt-dlp version at startup and then periodically (if
+// the interval is set). Without the pinned version and the interval, it does nothing. Blocks until the context
+// is canceled.
+func (b *Bot) scheduleYtDlpUpdates(ctx context.Context) {
+	if b.updates.version == "" && b.updates.interval <= 0 {
+		return
+	}
+
+	var tick <-chan time.Time
+
+	if b.updates.interval > 0 {
+		var ticker = time.NewTicker(b.updates.interval)
+		defer ticker.Stop()
+
+		tick = ticker.C
+	}
+
+	for {
+		if res, err := b.updateYtDlp(ctx, b.updates.version, "schedule"); err == nil && res.Updated {
+			b.notifyAdmins(func(l i18n.Localizer) string {
+				return l.T("update-scheduled", i18n.Vars{"result": updateResultText(l, res)})
+			})
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-tick: // nil channel (no scheduled updates) blocks forever
+		}
+	}
+}
+
+// reportExtractorError records the extractor error, and triggers the yt-dlp update when there are too many of
+// them in a short time (extractors are often broken by the site changes and fixed in the new releases).
+func (b *Bot) reportExtractorError(ctx context.Context) {
+	if b.updates == nil {
+		return
+	}
+
+	var now = time.Now()
+
+	b.updates.mu.Lock()
+
+	var recent = b.updates.extractorErrs[:0]
+
+	for _, t := range b.updates.extractorErrs {
+		if now.Sub(t) < extractorErrorsWindow {
+			recent = append(recent, t)
+		}
+	}
+
+	b.updates.extractorErrs = append(recent, now)
+
+	var trigger = len(b.updates.extractorErrs) >= extractorErrorsBurst &&
+		now.Sub(b.updates.lastAutoUpdate) >= autoUpdateCooldown
+
+	if trigger {
+		b.updates.lastAutoUpdate, b.updates.extractorErrs = now, nil
+	}
+
+	b.updates.mu.Unlock()
+
+	if !trigger {
+		return
+	}
+
+	go func() {
+		res, err := b.updateYtDlp(ctx, b.updates.version, "extractor errors")
+
+		switch {
+		case err != nil:
+			b.notifyAdmins(func(l i18n.Localizer) string {
+				return l.T("update-errors-burst-failed", i18n.Vars{"error": err.Error()})
+			})
+		case res.Updated:
+			b.notifyAdmins(func(l i18n.Localizer) string {
+				return l.T("update-errors-burst", i18n.Vars{"result": updateResultText(l, res)})
+			})
+		}
+	}()
+}
+
+// updateYtDlp updates yt-dlp to the given version and logs the result.
+func (b *Bot) updateYtDlp(ctx context.Context, version, reason string) (*ytdlp.UpdateResult, error) {
+	res, err := b.updates.updater.Update(ctx, version)
+	if err != nil {
+		b.log.Error("failed to update yt-dlp",
+			slog.String("error", err.Error()),
+			slog.String("version", version),
+			slog.String("reason", reason),
+		)
+
+		return nil, err
+	}
+
+	if res.Updated {
+		b.log.Info("yt-dlp updated",
+			slog.String("version", res.Version),
+			slog.String("previous_version", res.PreviousVersion),
+			slog.String("path", res.Path),
+			slog.String("reason", reason),
+		)
+	} else {
+		b.log.Debug("yt-dlp is up to date", slog.String("version", res.Version), slog.String("reason", reason))
+	}
+
+	return res, nil
+}
+
+// updateResultText returns the human-readable update result.
+func updateResultText(l i18n.Localizer, res *ytdlp.UpdateResult) string {
+	if !res.Updated {
+		return l.T("update-up-to-date", i18n.Vars{"version": res.Version})
+	}
+
+	return l.T("update-done", i18n.Vars{"version": res.Version, "previous": res.PreviousVersion})
+}
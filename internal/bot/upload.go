@@ -0,0 +1,150 @@
+package bot
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	tele "gopkg.in/telebot.v4"
+
+	"gh.tarampamp.am/video-dl-bot/internal/aeszip"
+	"gh.tarampamp.am/video-dl-bot/internal/i18n"
+)
+
+const (
+	// uploadProgressInterval is how often the upload status message is updated (the first one is sent after this
+	// interval too, so the fast uploads don't produce it at all).
+	uploadProgressInterval = 5 * time.Second
+
+	uploadFilenameMaxLen = 80 // max length of the uploaded file name (in runes, without the extension)
+)
+
+// trackUploadProgress returns the upload progress callback, and starts updating the status message (a reply to the
+// user message) with the progress in the background. The stop function stops the updates, and deletes the status
+// message.
+func (b *Bot) trackUploadProgress(
+	ctx context.Context,
+	l i18n.Localizer,
+	to *tele.Message,
+) (progress func(sent, total int64), stop func()) {
+	var (
+		sent, total atomic.Int64
+		done        = make(chan struct{})
+		wg          sync.WaitGroup
+		status      *tele.Message
+	)
+
+	wg.Go(func() {
+		var (
+			ticker      = time.NewTicker(uploadProgressInterval)
+			lastPercent = int64(-1)
+		)
+
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			var size = total.Load()
+			if size <= 0 {
+				continue
+			}
+
+			var percent = sent.Load() * 100 / size //nolint:mnd
+			if percent == lastPercent {
+				continue
+			}
+
+			lastPercent = percent
+
+			var text = l.T("upload-progress", i18n.Vars{"percent": percent, "size": formatSize(size), "bytes": size})
+
+			if status == nil {
+				status, _ = b.client.Reply(to, text, &tele.SendOptions{DisableNotification: true})
+			} else {
+				_, _ = b.client.Edit(status, text)
+			}
+		}
+	})
+
+	progress = func(s, t int64) { sent.Store(s); total.Store(t) }
+
+	var once sync.Once
+
+	stop = func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+
+			if status != nil {
+				_ = b.client.Delete(status)
+			}
+		})
+	}
+
+	return progress, stop
+}
+
+// uploadFilename returns the name of the uploaded file, derived from the video title: the letters and digits are
+// kept, and the other characters are replaced with the underscores (so the name is safe for the URLs and the file
+// systems). The "video" is used, if nothing is left of the title.
+func uploadFilename(title, ext string) string {
+	var words = strings.FieldsFunc(title, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '-'
+	})
+
+	var name = []rune(strings.Join(words, "_"))
+
+	if len(name) > uploadFilenameMaxLen {
+		name = []rune(strings.TrimRight(string(name[:uploadFilenameMaxLen]), "_"))
+	}
+
+	if len(name) == 0 {
+		return "video" + ext
+	}
+
+	return string(name) + ext
+}
+
+// encryptUpload packs the file into the AES-encrypted zip archive with a random password. The archive is created
+// next to the file (so it's removed together with the downloaded one), and opened for reading. The name is the
+// name of the file inside the archive.
+func encryptUpload(src *os.File, size int64, name string) (_ *os.File, password string, outErr error) {
+	var archive = filepath.Join(filepath.Dir(src.Name()), "encrypted.zip")
+
+	dst, err := os.OpenFile(archive, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0o600) //nolint:mnd
+	if err != nil {
+		return nil, "", err
+	}
+
+	defer func() {
+		if outErr != nil {
+			outErr = errors.Join(outErr, dst.Close(), os.Remove(archive))
+		}
+	}()
+
+	password = rand.Text()
+
+	if err = aeszip.Encrypt(dst, src, size, name, password, time.Now()); err != nil {
+		return nil, "", err
+	}
+
+	if _, err = dst.Seek(0, 0); err != nil {
+		return nil, "", err
+	}
+
+	return dst, password, nil
+}
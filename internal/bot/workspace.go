@@ -0,0 +1,83 @@
+package bot
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"gh.tarampamp.am/video-dl-bot/internal/workspace"
+	ytdlp "gh.tarampamp.am/video-dl-bot/internal/yt-dlp"
+)
+
+const (
+	workspaceSweepInterval = 10 * time.Minute // how often the stale files are removed from the working directory
+	workspaceStaleAge      = 12 * time.Hour   // files older than this are left by the crashed (or killed) downloads
+)
+
+// WithWorkspace sets the working directory manager for the downloads. The downloads reserve the disk space before
+// they start, and the stale files are removed from the directory periodically.
+func WithWorkspace(ws *workspace.Manager) Option { return func(b *Bot) { b.workspace = ws } }
+
+// reservationSize returns the disk space to reserve for the download of the probed video: twice the estimated size
+// (the video and audio parts are merged into a new file), or the maximal file size if the size is unknown. One more
+// file size is added for the encrypted uploads, since the archive is written next to the downloaded file.
+func (s *liveState) reservationSize(site *Site, info *ytdlp.Info, encrypted bool) int64 {
+	var limit = ytdlp.DefaultMaxFileSize
+
+	switch {
+	case site != nil && site.MaxFileSize > 0:
+		limit = site.MaxFileSize
+	case s.MaxFileSize > 0:
+		limit = s.MaxFileSize
+	}
+
+	var size, reserve = limit, limit
+
+	if !info.IsLive && info.FileSize > 0 {
+		size = min(info.FileSize, limit)
+		reserve = 2 * size //nolint:mnd
+	}
+
+	if encrypted {
+		reserve += size
+	}
+
+	return reserve
+}
+
+// sweepWorkspace removes the stale files from the working directory periodically. Blocks until the context is
+// canceled.
+func (b *Bot) sweepWorkspace(ctx context.Context) {
+	var ticker = time.NewTicker(workspaceSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.cleanWorkspace(workspaceStaleAge)
+		}
+	}
+}
+
+// cleanWorkspace removes the files, older than the given age, from the working directory (all of them, if the age
+// is zero - it must be done only before the downloads start, since their files are removed too).
+func (b *Bot) cleanWorkspace(olderThan time.Duration) {
+	removed, err := b.workspace.Sweep(olderThan)
+	if err != nil {
+		b.log.Warn("failed to clean up the working directory", slog.String("error", err.Error()))
+	}
+
+	if len(removed) > 0 {
+		b.log.Info("stale files removed from the working directory", slog.Any("paths", removed))
+	}
+
+	if free, freeErr := workspace.FreeSpace(b.workspace.Dir()); freeErr == nil {
+		b.log.Debug("working directory disk space",
+			slog.Uint64("free", free),
+			slog.Int64("reserved", b.workspace.Reserved()),
+			slog.Int64("budget", b.workspace.Budget()),
+		)
+	}
+}
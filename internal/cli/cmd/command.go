@@ -23,6 +23,11 @@ type Command struct {
 
 	Action func(_ context.Context, _ *Command, args []string) error // Action function executed when the command runs.
 
+	// LoadFile is an optional function that returns a source of flag values (e.g., parsed configuration file).
+	// It's called after the command-line flags are parsed (so the path to the file can be taken from a flag),
+	// and the values from the source have lower priority than the command-line flags and environment variables.
+	LoadFile func(*Command) (FileSource, error)
+
 	initOnce              sync.Once // to ensure initialization is done only once
 	showHelp, showVersion bool      // built-in flags for displaying help and version
 }
@@ -185,6 +190,22 @@ func (c *Command) Run(ctx context.Context, args []string) error { //nolint:funle
 		return err
 	}
 
+	// apply values from the file source (if any) to the flags that were not set explicitly
+	if c.LoadFile != nil {
+		src, err := c.LoadFile(c)
+		if err != nil {
+			return err
+		}
+
+		if src != nil {
+			for _, f := range c.Flags {
+				if err = f.ApplyFile(src); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
 	// validate and execute any flag-specific actions
 	for _, f := range c.Flags {
 		if !f.IsSet() {
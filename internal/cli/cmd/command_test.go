@@ -281,6 +281,59 @@ func TestCommand_Run(t *testing.T) {
 		}
 	})
 
+	t.Run("values from the file", func(t *testing.T) {
+		t.Parallel()
+
+		var (
+			path, value string
+			testErr     = errors.New("invalid value")
+
+			c = &cmd.Command{
+				Name: "some-name",
+				Flags: []cmd.Flagger{
+					&cmd.Flag[string]{
+						Names: []string{"config"},
+						Value: &path,
+					},
+					&cmd.Flag[string]{
+						Names:   []string{"custom-flag", "f"},
+						FileKey: "custom",
+						Validator: func(_ *cmd.Command, s string) error {
+							if s == "invalid" {
+								return testErr
+							}
+
+							return nil
+						},
+						Value: &value,
+					},
+				},
+				LoadFile: func(*cmd.Command) (cmd.FileSource, error) {
+					switch path {
+					case "":
+						return nil, nil
+					case "broken":
+						return nil, errors.New("broken file")
+					}
+
+					return fileSource{"custom": path}, nil
+				},
+			}
+		)
+
+		assertNoError(t, c.Run(ctx, nil))
+		assertEqual(t, value, "")
+
+		assertNoError(t, c.Run(ctx, []string{"--config=from-file"}))
+		assertEqual(t, value, "from-file")
+
+		assertNoError(t, c.Run(ctx, []string{"--config=from-file", "-f=from-flag"}))
+		assertEqual(t, value, "from-flag")
+
+		assertErrorContains(t, c.Run(ctx, []string{"--config=broken"}), "broken file")
+		assertEqual(t, c.Run(ctx, []string{"--config=invalid"}), testErr) // values from the file are validated too
+	})
+
 	t.Run("command action", func(t *testing.T) {
 		t.Parallel()
 
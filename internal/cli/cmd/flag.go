@@ -4,7 +4,10 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"maps"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -20,11 +23,24 @@ type (
 		Apply(*flag.FlagSet)                // Registers the flag with a flag set.
 		Validate(*Command) error            // Validates the flag's value.
 		RunAction(*Command) error           // Executes an associated action if set.
+		ApplyFile(FileSource) error         // Sets the flag's value from the file (if not set in other ways).
+	}
+
+	// FileSource defines an interface for the sources of flag values, loaded from a file (e.g., configuration).
+	FileSource interface {
+		// Lookup returns the raw value for the given key (e.g., "bot.token") and a boolean indicating whether
+		// the key was found.
+		Lookup(key string) (value any, found bool)
 	}
 
 	// FlagType defines supported data types for flags.
+	//
+	// Values of the slice and map types can be provided multiple times using the command-line flags (e.g.,
+	// `--id=1 --id=2,3`), and as comma-separated lists using the environment variables (e.g., `IDS=1,2,3`).
+	// Map items are defined as `key=value` pairs.
 	FlagType interface {
-		bool | int | int64 | string | uint | uint64 | float64 | time.Duration
+		bool | int | int64 | string | uint | uint64 | float64 | time.Duration |
+			[]string | []int64 | map[string]string | *url.URL
 	}
 
 	// Flag represents a command-line flag with metadata and behavior.
@@ -33,9 +49,10 @@ type (
 		Usage        string                  // Flag description (e.g., "Path to the configuration file").
 		Default      T                       // Default value of the flag.
 		EnvVars      []string                // Environment variable names for this flag (e.g., ["CONFIG_FILE"]).
+		FileKey      string                  // Key of the value in the file source (e.g., "bot.token").
 		Validator    func(*Command, T) error // Optional function to validate the value.
 		Action       func(*Command, T) error // Optional function to execute when the flag is set.
-		ValueSetFrom flagValueSource         // Source of the value (default, env, CLI flag).
+		ValueSetFrom flagValueSource         // Source of the value (default, file, env, CLI flag).
 		Value        *T                      // Pointer to store the parsed flag value.
 	}
 )
@@ -50,14 +67,19 @@ var (
 	_ Flagger = (*Flag[uint64])(nil)
 	_ Flagger = (*Flag[float64])(nil)
 	_ Flagger = (*Flag[time.Duration])(nil)
+	_ Flagger = (*Flag[[]string])(nil)
+	_ Flagger = (*Flag[[]int64])(nil)
+	_ Flagger = (*Flag[map[string]string])(nil)
+	_ Flagger = (*Flag[*url.URL])(nil)
 )
 
 type flagValueSource = byte
 
-// Enumerates possible sources for a flag's value.
+// Enumerates possible sources for a flag's value (in order of increasing priority).
 const (
 	FlagValueSourceNone    flagValueSource = iota // Value not set.
 	FlagValueSourceDefault                        // Value set from default.
+	FlagValueSourceFile                           // Value set from file (e.g., configuration file).
 	FlagValueSourceEnv                            // Value set from environment variable.
 	FlagValueSourceFlag                           // Value set from command-line flag.
 )
@@ -72,7 +94,7 @@ func (f *Flag[T]) IsSet() bool {
 	case FlagValueSourceNone, FlagValueSourceDefault:
 		return false
 	default:
-		return *f.Value != f.Default // true if value differs from default
+		return !equal(*f.Value, f.Default) // true if value differs from default
 	}
 }
 
@@ -108,13 +130,13 @@ func (f *Flag[T]) Help() (names string, usage string) {
 	b.WriteString(f.Usage)
 
 	// append default value if present
-	if f.Default != *new(T) {
+	if !isZero(f.Default) {
 		if b.Len() > 0 {
 			b.WriteRune(' ')
 		}
 
 		b.WriteString("(default: ")
-		fmt.Fprintf(&b, "%v", f.Default)
+		b.WriteString(format(f.Default))
 		b.WriteRune(')')
 	}
 
@@ -150,6 +172,8 @@ var (
 	errInvalidUint     = errors.New("must contain only digits (positive numbers only)")
 	errInvalidFloat    = errors.New("must contain only digits with an optional decimal point")
 	errInvalidDuration = errors.New("must be a valid Go duration string (e.g., 1h30m, -2s, 500ms)")
+	errInvalidMap      = errors.New("must be a comma-separated list of key=value pairs (e.g., foo=bar,baz=qux)")
+	errInvalidURL      = errors.New("must be a valid absolute URL (e.g., https://example.com/path)")
 )
 
 // parseString converts a string to the corresponding flag type.
@@ -207,12 +231,148 @@ func (f *Flag[T]) parseString(s string) (T, error) { //nolint:gocyclo
 			return empty, errInvalidDuration
 		}
 
+		return any(v).(T), nil
+	case []string:
+		return any(splitList(s)).(T), nil
+	case []int64:
+		var items = splitList(s)
+
+		v := make([]int64, len(items))
+
+		for i, item := range items {
+			n, err := strconv.ParseInt(item, 10, 64)
+			if err != nil {
+				return empty, errInvalidInt
+			}
+
+			v[i] = n
+		}
+
+		return any(v).(T), nil
+	case map[string]string:
+		var items = splitList(s)
+
+		v := make(map[string]string, len(items))
+
+		for _, item := range items {
+			key, value, ok := strings.Cut(item, "=")
+			if key = strings.TrimSpace(key); !ok || key == "" {
+				return empty, errInvalidMap
+			}
+
+			v[key] = strings.TrimSpace(value)
+		}
+
+		return any(v).(T), nil
+	case *url.URL:
+		v, err := url.Parse(s)
+		if err != nil || v.Scheme == "" || v.Host == "" {
+			return empty, errInvalidURL
+		}
+
 		return any(v).(T), nil
 	}
 
 	return empty, fmt.Errorf("unsupported flag type: %T", empty) // will never happen
 }
 
+// merge combines the current (already set using the command-line flag) value with the new one. It's used for
+// the slice and map types, which can be provided multiple times. For other types, the new value is returned.
+func merge[T FlagType](current, v T) T {
+	switch cur := any(current).(type) {
+	case []string:
+		return any(append(cur, any(v).([]string)...)).(T)
+	case []int64:
+		return any(append(cur, any(v).([]int64)...)).(T)
+	case map[string]string:
+		maps.Copy(cur, any(v).(map[string]string))
+
+		return any(cur).(T)
+	}
+
+	return v
+}
+
+// splitList splits a comma-separated list, trims the items and skips empty ones.
+func splitList(s string) []string {
+	var items = make([]string, 0, strings.Count(s, ",")+1)
+
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// equal reports whether two flag values are equal (slices and maps are compared by their content).
+func equal[T FlagType](a, b T) bool {
+	switch av := any(a).(type) {
+	case []string:
+		return slices.Equal(av, any(b).([]string))
+	case []int64:
+		return slices.Equal(av, any(b).([]int64))
+	case map[string]string:
+		return maps.Equal(av, any(b).(map[string]string))
+	case *url.URL:
+		var bv = any(b).(*url.URL)
+
+		if av == nil || bv == nil {
+			return av == bv
+		}
+
+		return av.String() == bv.String()
+	}
+
+	return any(a) == any(b)
+}
+
+// isZero reports whether the flag value is the zero (or empty) value.
+func isZero[T FlagType](v T) bool {
+	switch tv := any(v).(type) {
+	case []string:
+		return len(tv) == 0
+	case []int64:
+		return len(tv) == 0
+	case map[string]string:
+		return len(tv) == 0
+	case *url.URL:
+		return tv == nil
+	}
+
+	return any(v) == any(*new(T))
+}
+
+// format returns a human-readable representation of the flag value (slices and maps are formatted in the same
+// way as they are expected in the environment variables).
+func format[T FlagType](v T) string {
+	switch tv := any(v).(type) {
+	case []string:
+		return strings.Join(tv, ",")
+	case []int64:
+		var items = make([]string, len(tv))
+
+		for i, n := range tv {
+			items[i] = strconv.FormatInt(n, 10)
+		}
+
+		return strings.Join(items, ",")
+	case map[string]string:
+		var items = make([]string, 0, len(tv))
+
+		for _, key := range slices.Sorted(maps.Keys(tv)) {
+			items = append(items, key+"="+tv[key])
+		}
+
+		return strings.Join(items, ",")
+	case *url.URL:
+		return tv.String()
+	}
+
+	return fmt.Sprintf("%v", v)
+}
+
 // envValue retrieves the flag value from environment variables, if set.
 func (f *Flag[T]) envValue() (
 	value T, // the value
@@ -238,6 +398,47 @@ func (f *Flag[T]) envValue() (
 	return empty, false, "", nil // no environment variable found
 }
 
+// fileValue converts the raw value from the file source to the flag type.
+func (f *Flag[T]) fileValue(raw any) (T, error) {
+	switch v := raw.(type) {
+	case string:
+		return f.parseString(strings.Trim(v, " \t\n\r"))
+	case nil:
+		return f.Default, nil // an empty value in the file means "use the default"
+	case T:
+		return v, nil // already has the required type
+	case []any: // lists
+		var items = make([]string, len(v))
+
+		for i := range v {
+			items[i] = strings.TrimSpace(fmt.Sprint(v[i]))
+		}
+
+		switch any(*new(T)).(type) {
+		case []string:
+			return any(items).(T), nil // items are not split by commas
+		case []int64:
+			return f.parseString(strings.Join(items, ","))
+		}
+
+		return *new(T), fmt.Errorf("lists are not supported for the %T flag", *new(T))
+	case map[string]any: // maps (nested values are not supported)
+		var result = make(map[string]string, len(v))
+
+		for key, item := range v {
+			result[key] = fmt.Sprint(item)
+		}
+
+		if typed, ok := any(result).(T); ok {
+			return typed, nil
+		}
+
+		return *new(T), fmt.Errorf("maps are not supported for the %T flag", *new(T))
+	}
+
+	return f.parseString(fmt.Sprint(raw)) // numbers, booleans, etc.
+}
+
 // setValue assigns the flag's value and records its source.
 func (f *Flag[T]) setValue(v T, src flagValueSource) {
 	if f.Value == nil {
@@ -272,12 +473,18 @@ func (f *Flag[T]) Apply(s *flag.FlagSet) {
 		}
 	default:
 		var fn = func(in string) error {
-			if v, parsingErr := f.parseString(in); parsingErr == nil {
-				f.setValue(v, FlagValueSourceFlag)
-			} else {
+			v, parsingErr := f.parseString(in)
+			if parsingErr != nil {
 				return parsingErr
 			}
 
+			// the flag is provided multiple times - merge the values (for slices and maps)
+			if f.ValueSetFrom == FlagValueSourceFlag {
+				v = merge(*f.Value, v)
+			}
+
+			f.setValue(v, FlagValueSourceFlag)
+
 			return nil
 		}
 
@@ -287,6 +494,38 @@ func (f *Flag[T]) Apply(s *flag.FlagSet) {
 	}
 }
 
+// ApplyFile sets the flag's value from the file source, but only if the value wasn't set using the command-line
+// flag or environment variable. It may be called several times (e.g., on the configuration file reload) - if the
+// key has been removed from the file since the previous call, the value is reset to the default.
+func (f *Flag[T]) ApplyFile(src FileSource) error {
+	if f.FileKey == "" || src == nil {
+		return nil
+	}
+
+	// values from the CLI flags and environment variables have higher priority
+	if f.ValueSetFrom == FlagValueSourceEnv || f.ValueSetFrom == FlagValueSourceFlag {
+		return nil
+	}
+
+	raw, found := src.Lookup(f.FileKey)
+	if !found {
+		if f.ValueSetFrom == FlagValueSourceFile {
+			f.setValue(f.Default, FlagValueSourceDefault)
+		}
+
+		return nil
+	}
+
+	v, err := f.fileValue(raw)
+	if err != nil {
+		return fmt.Errorf("invalid value for the %q key in the file: %w", f.FileKey, err)
+	}
+
+	f.setValue(v, FlagValueSourceFile)
+
+	return nil
+}
+
 // Validate checks if the flag's value is valid.
 func (f *Flag[T]) Validate(c *Command) error {
 	if f.Validator == nil {
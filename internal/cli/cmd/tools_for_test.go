@@ -2,6 +2,7 @@ package cmd_test
 
 import (
 	"math/rand/v2"
+	"reflect"
 	"strings"
 	"sync"
 	"testing"
@@ -57,6 +58,20 @@ func assertEqual[T comparable](t *testing.T, got, want T, msgPrefix ...string) {
 	}
 }
 
+// assertDeepEqual checks if two values are deeply equal (useful for slices and maps).
+func assertDeepEqual(t *testing.T, got, want any) {
+	t.Helper()
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// fileSource is a simple in-memory implementation of the cmd.FileSource interface.
+type fileSource map[string]any
+
+func (s fileSource) Lookup(key string) (any, bool) { v, ok := s[key]; return v, ok }
+
 // rnd is a global pseudo-random number generator seeded with the current time.
 var (
 	rndSeed = time.Now().UnixNano()
@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"gh.tarampamp.am/video-dl-bot/internal/bot"
+	"gh.tarampamp.am/video-dl-bot/internal/config"
+	"gh.tarampamp.am/video-dl-bot/internal/cookies"
+	ytdlp "gh.tarampamp.am/video-dl-bot/internal/yt-dlp"
+)
+
+// extractorKeyPrefix marks the keys of the sites section, that are the yt-dlp extractor names (e.g.,
+// "extractor:youtube"); other keys are the domains or globs.
+const extractorKeyPrefix = "extractor:"
+
+// loadConfig loads the configuration file and validates the values, that are not validated by the flags.
+func loadConfig(path string) (*config.Config, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	for domain, site := range cfg.Sites {
+		if name, ok := strings.CutPrefix(domain, extractorKeyPrefix); ok && strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("site %s: empty extractor name", domain)
+		}
+
+		if err = validateCookiesFile(site.CookiesFile); err != nil {
+			return nil, fmt.Errorf("site %s: %w", domain, err)
+		}
+
+		if err = validateJSRuntimes(site.JSRuntimes); err != nil {
+			return nil, fmt.Errorf("site %s: %w", domain, err)
+		}
+
+		if err = validateProxies(site.Proxy); err != nil {
+			return nil, fmt.Errorf("site %s: %w", domain, err)
+		}
+
+		if site.MaxFileSize != "" {
+			if _, err = ytdlp.ParseSize(site.MaxFileSize); err != nil {
+				return nil, fmt.Errorf("site %s: %w", domain, err)
+			}
+		}
+
+		if site.MaxDuration < 0 {
+			return nil, fmt.Errorf("site %s: max duration cannot be negative", domain)
+		}
+	}
+
+	return cfg, nil
+}
+
+// botSettings builds the bot settings from the options and the configuration file (if loaded).
+func (a *App) botSettings() (bot.Settings, error) {
+	var s = bot.Settings{
+		MaxConcurrentDownloads: a.opt.MaxConcurrentDownloads,
+		AllowedUsers:           a.opt.AllowedUsers,
+		Admins:                 a.opt.Admins,
+		Proxies:                a.opt.Proxies,
+		MaxDuration:            a.opt.MaxDuration,
+		ConfirmLongVideos:      a.opt.ConfirmLongVideos,
+		LiveRecordDuration:     a.opt.LiveRecordDuration,
+	}
+
+	// the sizes are validated by the flags
+	s.MinFileSize, _ = ytdlp.ParseSize(a.opt.MinFileSize)
+	s.MaxFileSize, _ = ytdlp.ParseSize(a.opt.MaxFileSize)
+
+	if a.cfg == nil {
+		return s, nil
+	}
+
+	s.Messages = a.cfg.Messages
+
+	for key, site := range a.cfg.Sites {
+		var botSite = bot.Site{
+			Deny:        site.Deny,
+			MaxDuration: site.MaxDuration,
+			JSRuntimes:  site.JSRuntimes,
+			Format:      site.Format,
+			Proxies:     site.Proxy,
+		}
+
+		// the prefixed key is the extractor name (e.g., "extractor:youtube"), any other is the domain or glob (single
+		// label hosts like "localhost" included)
+		if name, ok := strings.CutPrefix(key, extractorKeyPrefix); ok {
+			botSite.Extractor = strings.TrimSpace(name)
+		} else {
+			botSite.Domain = key
+		}
+
+		if site.MaxFileSize != "" {
+			botSite.MaxFileSize, _ = ytdlp.ParseSize(site.MaxFileSize) // validated on load
+		}
+
+		// the site cookies file is kept in the store (so it's checked for the expiration and updated on change)
+		if site.CookiesFile != "" {
+			botSite.CookiesDomain = cookies.SiteDomain(key)
+
+			if _, err := a.cookies.ImportFile(botSite.CookiesDomain, site.CookiesFile); err != nil {
+				return s, fmt.Errorf("site %s: %w", key, err)
+			}
+		}
+
+		s.Sites = append(s.Sites, botSite)
+	}
+
+	return s, nil
+}
+
+// watchConfig reloads the configuration file on SIGHUP or when the file is changed, and applies the settings that
+// are safe to change at runtime (access lists, limits, custom replies and per-site overrides). Other settings
+// (like the bot token) require a restart. Blocks until the context is canceled.
+func (a *App) watchConfig(ctx context.Context, log *slog.Logger, b *bot.Bot) {
+	const pollInterval = 5 * time.Second
+
+	var sigHup = make(chan os.Signal, 1)
+
+	signal.Notify(sigHup, syscall.SIGHUP)
+	defer signal.Stop(sigHup)
+
+	var ticker = time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	// fileState returns the file modification time and size, used to detect changes
+	var fileState = func() (time.Time, int64) {
+		if stat, err := os.Stat(a.opt.ConfigFile); err == nil {
+			return stat.ModTime(), stat.Size()
+		}
+
+		return time.Time{}, 0
+	}
+
+	var lastMod, lastSize = fileState()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sigHup:
+			log.Info("SIGHUP received, reloading the configuration file")
+		case <-ticker.C:
+			if mod, size := fileState(); mod.IsZero() || (mod.Equal(lastMod) && size == lastSize) {
+				continue // the file is not changed (or not accessible at the moment)
+			} else {
+				lastMod, lastSize = mod, size
+			}
+
+			log.Info("configuration file changed, reloading")
+		}
+
+		if err := a.reloadConfig(b); err != nil {
+			log.Error("failed to reload the configuration file, previous settings are kept",
+				slog.String("error", err.Error()),
+				slog.String("path", a.opt.ConfigFile),
+			)
+
+			continue
+		}
+
+		log.Info("configuration reloaded", slog.String("path", a.opt.ConfigFile))
+	}
+}
+
+// reloadConfig loads the configuration file again and applies the new settings to the bot.
+func (a *App) reloadConfig(b *bot.Bot) error {
+	cfg, err := loadConfig(a.opt.ConfigFile)
+	if err != nil {
+		return err
+	}
+
+	if err = a.reloadFlags(cfg); err != nil {
+		return err
+	}
+
+	a.cfg = cfg
+
+	settings, err := a.botSettings()
+	if err != nil {
+		return err
+	}
+
+	return b.Reload(settings)
+}
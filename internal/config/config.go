@@ -0,0 +1,97 @@
+// Package config provides support for the configuration file (YAML), which can be used instead of (or together
+// with) the command-line flags and environment variables.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// Config represents the configuration file content.
+	//
+	// Values that can also be set using the command-line flags (like the bot token, logging level or access
+	// lists) are not mapped to the struct fields - they are accessible using the Lookup method by their
+	// dot-separated keys (e.g., "bot.token") and applied to the flags with the same meaning.
+	Config struct {
+		Messages map[string]string `yaml:"messages"` // custom bot replies (the key is a message name)
+		Sites    map[string]Site   `yaml:"sites"`    // per-site overrides (the key is a domain, glob or "extractor:<name>")
+
+		raw map[string]any // the raw file content (used for lookups)
+	}
+
+	// Site contains the settings that override the global ones for a specific site (the policy).
+	Site struct {
+		Deny        bool          `yaml:"deny"`         // deny downloading from the site
+		MaxDuration time.Duration `yaml:"max-duration"` // longer videos are not downloaded (e.g., "1h")
+		MaxFileSize string        `yaml:"max-filesize"` // larger files are not downloaded (e.g., "500M")
+		CookiesFile string        `yaml:"cookies-file"` // path to the file with cookies (netscape-formatted)
+		JSRuntimes  string        `yaml:"js-runtimes"`  // JavaScript runtimes for yt-dlp
+		Format      string        `yaml:"format"`       // yt-dlp format selector (e.g., "best")
+		Proxy       []string      `yaml:"proxy"`        // proxies for the site ("direct" to bypass the global ones)
+	}
+)
+
+// Load reads and parses the configuration file from the given path.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the configuration file: %w", err)
+	}
+
+	cfg, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the configuration file %s: %w", path, err)
+	}
+
+	return cfg, nil
+}
+
+// Parse parses the configuration from the given YAML (or JSON) content.
+func Parse(data []byte) (*Config, error) {
+	var cfg Config
+
+	if err := yaml.NewDecoder(bytes.NewReader(data)).Decode(&cfg.raw); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	if len(cfg.raw) == 0 {
+		return &cfg, nil // empty file is a valid configuration
+	}
+
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+
+	return &cfg, nil
+}
+
+// Lookup returns the raw value for the given dot-separated key (e.g., "bot.token") and a boolean indicating
+// whether the key was found. Values of nested maps can be accessed only using the full key path.
+func (c *Config) Lookup(key string) (any, bool) {
+	if c == nil || c.raw == nil {
+		return nil, false
+	}
+
+	var current any = c.raw
+
+	for part := range strings.SplitSeq(key, ".") {
+		m, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		if current, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
@@ -0,0 +1,130 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"gh.tarampamp.am/video-dl-bot/internal/config"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := config.Parse([]byte(`
+log:
+  level: debug
+bot:
+  token: "123:abc"
+limits:
+  max-concurrent-downloads: 3
+access:
+  allowed-users: [1, 2]
+  admins: [3]
+messages:
+  start: Hi there!
+sites:
+  youtube.com:
+    cookies-file: /tmp/yt.txt
+    format: best
+    proxy: [socks5://127.0.0.1:1080, direct]
+  "extractor:generic":
+    deny: true
+  vimeo:
+    max-duration: 1h30m
+    max-filesize: 500M
+`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for key, want := range map[string]any{
+		"log.level":                       "debug",
+		"bot.token":                       "123:abc",
+		"limits.max-concurrent-downloads": 3,
+	} {
+		if got, found := cfg.Lookup(key); !found {
+			t.Errorf("key %q not found", key)
+		} else if got != want {
+			t.Errorf("key %q: got %v, want %v", key, got, want)
+		}
+	}
+
+	for _, key := range []string{"", "log.level.foo", "bot.unknown", "unknown"} {
+		if v, found := cfg.Lookup(key); found {
+			t.Errorf("key %q should not be found, got %v", key, v)
+		}
+	}
+
+	if got, _ := cfg.Lookup("access.allowed-users"); !reflect.DeepEqual(got, []any{1, 2}) {
+		t.Errorf("unexpected allowed users: %v", got)
+	}
+
+	if got := cfg.Messages["start"]; got != "Hi there!" {
+		t.Errorf("unexpected start message: %q", got)
+	}
+
+	if want := (config.Site{
+		CookiesFile: "/tmp/yt.txt",
+		Format:      "best",
+		Proxy:       []string{"socks5://127.0.0.1:1080", "direct"},
+	}); !reflect.DeepEqual(cfg.Sites["youtube.com"], want) {
+		t.Errorf("unexpected site settings: %+v", cfg.Sites["youtube.com"])
+	}
+
+	if want := (config.Site{Deny: true}); !reflect.DeepEqual(cfg.Sites["extractor:generic"], want) {
+		t.Errorf("unexpected site settings: %+v", cfg.Sites["extractor:generic"])
+	}
+
+	if want := (config.Site{MaxDuration: 90 * time.Minute, MaxFileSize: "500M"}); !reflect.DeepEqual(
+		cfg.Sites["vimeo"], want,
+	) {
+		t.Errorf("unexpected site settings: %+v", cfg.Sites["vimeo"])
+	}
+}
+
+func TestParse_Empty(t *testing.T) {
+	t.Parallel()
+
+	cfg, err := config.Parse([]byte("# nothing here\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, found := cfg.Lookup("bot.token"); found {
+		t.Error("key should not be found")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	var path = filepath.Join(t.TempDir(), "config.yml")
+
+	if _, err := config.Load(path); err == nil {
+		t.Error("expected an error for the missing file")
+	}
+
+	if err := os.WriteFile(path, []byte("bot: [broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := config.Load(path); err == nil {
+		t.Error("expected an error for the broken file")
+	}
+
+	if err := os.WriteFile(path, []byte(`{"bot": {"js-runtimes": "node"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if v, _ := cfg.Lookup("bot.js-runtimes"); v != "node" {
+		t.Errorf("unexpected value: %v", v)
+	}
+}
@@ -0,0 +1,108 @@
+// Package cookies provides parsing of the Netscape-formatted cookies files (used by yt-dlp) and a store that
+// manages such files per domain.
+package cookies
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cookie is a single cookie from the Netscape-formatted cookies file.
+type Cookie struct {
+	Domain  string    // domain the cookie belongs to (e.g., ".youtube.com")
+	Path    string    // path the cookie is valid for
+	Secure  bool      // whether the cookie is sent over HTTPS only
+	Expires time.Time // expiration time (zero for session cookies)
+	Name    string    // cookie name
+}
+
+// IsSession reports whether the cookie is a session cookie (it has no expiration time).
+func (c Cookie) IsSession() bool { return c.Expires.IsZero() }
+
+// httpOnlyPrefix is a prefix for the domain of HTTP-only cookies (it's not a comment).
+const httpOnlyPrefix = "#HttpOnly_"
+
+// Parse parses the Netscape-formatted cookies file content. Each non-empty and non-comment line must contain
+// 7 tab-separated fields: domain, include subdomains flag, path, secure flag, expiration time (unix timestamp),
+// name and value. At least one cookie is required.
+func Parse(r io.Reader) ([]Cookie, error) { //nolint:funlen
+	var (
+		scanner = bufio.NewScanner(r)
+		result  []Cookie
+		lineNum int
+	)
+
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024) //nolint:mnd // cookie values can be quite long
+
+	for scanner.Scan() {
+		lineNum++
+
+		var line = strings.TrimRight(scanner.Text(), "\r\n")
+
+		if lineNum == 1 {
+			line = strings.TrimPrefix(line, "\ufeff") // skip the BOM, if any
+		}
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, httpOnlyPrefix) {
+			line = strings.TrimPrefix(line, httpOnlyPrefix)
+		} else if strings.HasPrefix(line, "#") {
+			continue // comment
+		}
+
+		const fieldsCount = 7
+
+		var fields = strings.Split(line, "\t")
+		if len(fields) != fieldsCount {
+			return nil, fmt.Errorf("line %d: expected %d tab-separated fields, got %d", lineNum, fieldsCount, len(fields))
+		}
+
+		var cookie = Cookie{Domain: fields[0], Path: fields[2], Name: fields[5]}
+
+		if cookie.Domain == "" {
+			return nil, fmt.Errorf("line %d: empty domain", lineNum)
+		}
+
+		if !isFlag(fields[1]) || !isFlag(fields[3]) {
+			return nil, fmt.Errorf("line %d: flags must be TRUE or FALSE", lineNum)
+		}
+
+		cookie.Secure = strings.EqualFold(fields[3], "TRUE")
+
+		expires, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid expiration time: %w", lineNum, err)
+		}
+
+		if expires > 0 {
+			cookie.Expires = time.Unix(expires, 0)
+		}
+
+		result = append(result, cookie)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(result) == 0 {
+		return nil, errors.New("no cookies found")
+	}
+
+	return result, nil
+}
+
+// ParseBytes is a shortcut for Parse with the content provided as a byte slice.
+func ParseBytes(content []byte) ([]Cookie, error) { return Parse(bytes.NewReader(content)) }
+
+// isFlag reports whether the value is a valid boolean flag of the cookies file.
+func isFlag(v string) bool { return strings.EqualFold(v, "TRUE") || strings.EqualFold(v, "FALSE") }
@@ -0,0 +1,107 @@
+package cookies_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gh.tarampamp.am/video-dl-bot/internal/cookies"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveContent string
+		wantNames   []string
+		wantErr     string
+	}{
+		"regular file": {
+			giveContent: "# Netscape HTTP Cookie File\n" +
+				"# This is a generated file! Do not edit.\n" +
+				"\n" +
+				".youtube.com\tTRUE\t/\tTRUE\t1900000000\tSID\tfoo\n" +
+				"#HttpOnly_.youtube.com\tTRUE\t/\tFALSE\t0\tYSC\tbar\n",
+			wantNames: []string{"SID", "YSC"},
+		},
+		"bom and crlf": {
+			giveContent: "\ufeff# Netscape HTTP Cookie File\r\n.example.com\tTRUE\t/\tFALSE\t0\ta\tb\r\n",
+			wantNames:   []string{"a"},
+		},
+		"empty value": {
+			giveContent: ".example.com\tTRUE\t/\tFALSE\t0\ta\t\n",
+			wantNames:   []string{"a"},
+		},
+		"empty": {
+			giveContent: "",
+			wantErr:     "no cookies found",
+		},
+		"comments only": {
+			giveContent: "# Netscape HTTP Cookie File\n",
+			wantErr:     "no cookies found",
+		},
+		"wrong fields count": {
+			giveContent: "# comment\n.example.com TRUE / FALSE 0 a b\n",
+			wantErr:     "line 2: expected 7 tab-separated fields, got 1",
+		},
+		"wrong flag": {
+			giveContent: ".example.com\tyes\t/\tFALSE\t0\ta\tb\n",
+			wantErr:     "line 1: flags must be TRUE or FALSE",
+		},
+		"wrong expiration": {
+			giveContent: ".example.com\tTRUE\t/\tFALSE\tnever\ta\tb\n",
+			wantErr:     "line 1: invalid expiration time",
+		},
+		"empty domain": {
+			giveContent: "\tTRUE\t/\tFALSE\t0\ta\tb\n",
+			wantErr:     "line 1: empty domain",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := cookies.Parse(strings.NewReader(tc.giveContent))
+
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(got) != len(tc.wantNames) {
+				t.Fatalf("expected %d cookies, got %d", len(tc.wantNames), len(got))
+			}
+
+			for i, name := range tc.wantNames {
+				if got[i].Name != name {
+					t.Errorf("cookie %d: expected name %q, got %q", i, name, got[i].Name)
+				}
+			}
+		})
+	}
+}
+
+func TestParse_Fields(t *testing.T) {
+	t.Parallel()
+
+	got, err := cookies.ParseBytes([]byte(".youtube.com\tTRUE\t/path\tTRUE\t1900000000\tSID\tfoo\n" +
+		"youtube.com\tFALSE\t/\tFALSE\t0\tYSC\tbar\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if c := got[0]; c.Domain != ".youtube.com" || c.Path != "/path" || !c.Secure || c.IsSession() ||
+		!c.Expires.Equal(time.Unix(1900000000, 0)) {
+		t.Errorf("unexpected first cookie: %+v", c)
+	}
+
+	if c := got[1]; c.Secure || !c.IsSession() {
+		t.Errorf("unexpected second cookie: %+v", c)
+	}
+}
@@ -0,0 +1,485 @@
+package cookies
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gh.tarampamp.am/video-dl-bot/internal/hostmatch"
+)
+
+// DefaultDomain is the domain name used for the default cookies file (used when there is no file for the
+// requested domain).
+const DefaultDomain = ""
+
+// fileExt is the extension of the cookies files in the directory (e.g., "youtube.com.txt").
+const fileExt = ".txt"
+
+// defaultName is the name of the default cookies file in the working directory.
+const defaultName = "default"
+
+// sitePrefix is the prefix of the domain names of the site cookies files (see SiteDomain).
+const sitePrefix = "site:"
+
+type (
+	// Store manages the cookies files per domain. Files are kept in a writable working directory, since yt-dlp
+	// updates the cookies file after each run (the source files may be mounted as read-only). If the directory is
+	// persistent, the files (including the uploaded ones) are restored from it on the next start.
+	//
+	// Every import writes a new file (instead of overwriting the current one), so yt-dlp processes, that are
+	// still running with the previous file, cannot overwrite the new cookies on exit.
+	Store struct {
+		dir string // working directory
+
+		mu     sync.RWMutex
+		srcDir string           // directory where the uploaded files for new domains are persisted (optional)
+		files  map[string]*File // domain -> current file
+	}
+
+	// File describes the current cookies file for the domain.
+	File struct {
+		Domain     string    // domain name (DefaultDomain for the default file)
+		Path       string    // path to the file in the working directory
+		Source     string    // path to the source file (where the updates are persisted; optional)
+		Cookies    []Cookie  // parsed cookies
+		ImportedAt time.Time // when the file was imported
+	}
+)
+
+// domainRe is used to validate domain names (at least one dot is required).
+var domainRe = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]*[a-z0-9])?\.)+[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// NormalizeDomain lowercases the domain name, removes the "www." prefix and validates it.
+func NormalizeDomain(domain string) (string, error) {
+	domain = strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), "."), "www.")
+
+	if !domainRe.MatchString(domain) {
+		return "", fmt.Errorf("invalid domain name: %q", domain)
+	}
+
+	return domain, nil
+}
+
+// SiteDomain returns the domain name, the cookies file of the site settings (e.g., "youtube" or "*.example.com")
+// is imported with. Such files are not used for the other sites (see Lookup), and can be found with Path only.
+func SiteDomain(site string) string { return sitePrefix + site }
+
+// IsSiteDomain reports whether the domain is of the site cookies file (see SiteDomain).
+func IsSiteDomain(domain string) bool { return strings.HasPrefix(domain, sitePrefix) }
+
+// NewStore creates a new store with the given working directory (it's created if it doesn't exist). The files,
+// left in the directory by the previous run, are restored (the site files are removed, since they are imported
+// from the settings again).
+func NewStore(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil { //nolint:mnd
+		return nil, fmt.Errorf("failed to create cookies directory: %w", err)
+	}
+
+	var s = &Store{dir: dir, files: make(map[string]*File)}
+
+	if err := s.restore(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// restore loads the latest file per domain from the working directory, and removes the rest of the files.
+func (s *Store) restore() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read cookies directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		var path = filepath.Join(s.dir, entry.Name())
+
+		domain, importedAt, ok := parseFileName(entry.Name())
+		if !ok {
+			_ = os.Remove(path) // the site file, the temporary one, etc.
+
+			continue
+		}
+
+		content, readErr := os.ReadFile(path)
+		if readErr != nil {
+			continue
+		}
+
+		parsed, parseErr := ParseBytes(content)
+		if parseErr != nil {
+			_ = os.Remove(path)
+
+			continue
+		}
+
+		if prev, found := s.files[domain]; found {
+			if prev.ImportedAt.After(importedAt) {
+				_ = os.Remove(path)
+
+				continue
+			}
+
+			_ = os.Remove(prev.Path)
+		}
+
+		s.files[domain] = &File{Domain: domain, Path: path, Cookies: parsed, ImportedAt: importedAt}
+	}
+
+	return nil
+}
+
+// ImportFile imports the cookies file for the domain (DefaultDomain for the default file). The source path
+// is remembered, so the later updates (see Import) are also written there (if it's writable). The current file
+// for the domain is kept, if it's newer than the source one (e.g., uploaded by an admin before the restart).
+func (s *Store) ImportFile(domain, src string) (*File, error) {
+	f, _, err := s.importFile(domain, src)
+
+	return f, err
+}
+
+// importFile imports the source file, if it's changed since the current file for the domain was imported, and
+// reports whether the file is updated.
+func (s *Store) importFile(domain, src string) (_ *File, updated bool, _ error) {
+	stat, err := os.Stat(src)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read cookies file: %w", err)
+	}
+
+	s.mu.Lock()
+	if cur, found := s.files[domain]; found && !stat.ModTime().After(cur.ImportedAt) {
+		cur.Source = src
+		s.mu.Unlock()
+
+		return cur, false, nil
+	}
+	s.mu.Unlock()
+
+	content, err := os.ReadFile(src)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read cookies file: %w", err)
+	}
+
+	f, err := s.importContent(domain, content, src)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return f, true, nil
+}
+
+// ImportDir imports all the cookies files from the directory. The file names must be the domain names with the
+// ".txt" extension (e.g., "youtube.com.txt"); other files are ignored. The cookies uploaded later for the new
+// domains are persisted to this directory too.
+func (s *Store) ImportDir(dir string) ([]*File, error) {
+	s.mu.Lock()
+	s.srcDir = dir
+	s.mu.Unlock()
+
+	imported, _, err := s.importDir(dir)
+
+	return imported, err
+}
+
+// importDir imports the cookies files from the directory, and returns all of them and the updated ones.
+func (s *Store) importDir(dir string) (imported, updated []*File, _ error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read cookies directory: %w", err)
+	}
+
+	imported = make([]*File, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), fileExt) {
+			continue
+		}
+
+		domain, domainErr := NormalizeDomain(strings.TrimSuffix(entry.Name(), fileExt))
+		if domainErr != nil {
+			continue // not a domain-named file
+		}
+
+		f, ok, importErr := s.importFile(domain, filepath.Join(dir, entry.Name()))
+		if importErr != nil {
+			return nil, nil, fmt.Errorf("%s: %w", entry.Name(), importErr)
+		}
+
+		if imported = append(imported, f); ok {
+			updated = append(updated, f)
+		}
+	}
+
+	return imported, updated, nil
+}
+
+// Rescan imports the source files, changed since they were imported, and the new files of the source directory
+// (see ImportDir). It returns the updated files. The removed source files are ignored (the current ones are kept).
+func (s *Store) Rescan() ([]*File, error) {
+	var sources = make(map[string]string)
+
+	s.mu.RLock()
+	var srcDir = s.srcDir
+
+	for domain, f := range s.files {
+		if f.Source != "" {
+			sources[domain] = f.Source
+		}
+	}
+	s.mu.RUnlock()
+
+	var (
+		updated []*File
+		errs    []error
+	)
+
+	for domain, src := range sources {
+		if srcDir != "" && filepath.Dir(src) == filepath.Clean(srcDir) {
+			continue // the source directory files are imported below
+		}
+
+		f, ok, err := s.importFile(domain, src)
+		switch {
+		case errors.Is(err, fs.ErrNotExist): // the current file is kept
+		case err != nil:
+			errs = append(errs, fmt.Errorf("%s: %w", src, err))
+		case ok:
+			updated = append(updated, f)
+		}
+	}
+
+	if srcDir != "" {
+		_, fromDir, err := s.importDir(srcDir)
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		updated = append(updated, fromDir...)
+	}
+
+	return updated, errors.Join(errs...)
+}
+
+// Import validates the content and atomically replaces the cookies file for the domain. If the file has a
+// source path (or the source directory is known), the content is written there too - the returned error is not
+// nil if this fails (but the new cookies are used anyway).
+func (s *Store) Import(domain string, content []byte) (*File, error) {
+	var src string
+
+	s.mu.RLock()
+	if prev, ok := s.files[domain]; ok {
+		src = prev.Source
+	} else if s.srcDir != "" && domain != DefaultDomain {
+		src = filepath.Join(s.srcDir, domain+fileExt)
+	}
+	s.mu.RUnlock()
+
+	if _, err := ParseBytes(content); err != nil {
+		return nil, fmt.Errorf("invalid cookies file: %w", err)
+	}
+
+	// the source is written first, so it's not newer than the imported file (and is not imported again on rescan)
+	var srcErr error
+
+	if src != "" {
+		if err := writeFileAtomic(src, content); err != nil {
+			srcErr = fmt.Errorf("cookies are updated, but not persisted to %s: %w", src, err)
+		}
+	}
+
+	f, err := s.importContent(domain, content, src)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, srcErr
+}
+
+// importContent validates the content and writes it to a new file in the working directory.
+func (s *Store) importContent(domain string, content []byte, src string) (*File, error) {
+	parsed, err := ParseBytes(content)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cookies file: %w", err)
+	}
+
+	var (
+		now  = time.Now()
+		name = domain
+	)
+
+	switch {
+	case domain == DefaultDomain:
+		name = defaultName
+	case IsSiteDomain(domain): // the site names are not safe for the file names
+		name = fmt.Sprintf("site-%x", sha256.Sum256([]byte(domain)))[:21]
+	}
+
+	var path = filepath.Join(s.dir, name+"."+strconv.FormatInt(now.UnixNano(), 10)+fileExt)
+
+	if err = writeFileAtomic(path, content); err != nil {
+		return nil, err
+	}
+
+	var f = &File{Domain: domain, Path: path, Source: src, Cookies: parsed, ImportedAt: now}
+
+	s.mu.Lock()
+	prev := s.files[domain]
+	s.files[domain] = f
+	s.mu.Unlock()
+
+	if prev != nil {
+		_ = os.Remove(prev.Path) // yt-dlp processes that still use it may recreate it, but it's not a problem
+	}
+
+	return f, nil
+}
+
+// Lookup returns the path to the cookies file for the host: the most specific domain match, or the default
+// file. Returns an empty string if there is no suitable file.
+func (s *Store) Lookup(host string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var found *File
+
+	for domain, f := range s.files {
+		if domain == DefaultDomain || IsSiteDomain(domain) || !hostmatch.Match(domain, host) {
+			continue
+		}
+
+		if found == nil || len(domain) > len(found.Domain) {
+			found = f
+		}
+	}
+
+	if found == nil {
+		found = s.files[DefaultDomain]
+	}
+
+	if found == nil {
+		return ""
+	}
+
+	return found.Path
+}
+
+// Path returns the path to the current cookies file for the domain (exactly), or an empty string if there is
+// no such file.
+func (s *Store) Path(domain string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if f, ok := s.files[domain]; ok {
+		return f.Path
+	}
+
+	return ""
+}
+
+// List returns the current cookies files, sorted by domain (the default file goes first).
+func (s *Store) List() []File {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var list = make([]File, 0, len(s.files))
+
+	for _, f := range s.files {
+		list = append(list, *f)
+	}
+
+	slices.SortFunc(list, func(a, b File) int { return strings.Compare(a.Domain, b.Domain) })
+
+	return list
+}
+
+// ExpiresAt returns the time when most of the persistent (non-session) cookies in the file expire, and a
+// boolean indicating whether the file has any persistent cookies. Short-living cookies (like the tracking ones)
+// usually expire much earlier than the authentication ones, so the median expiration time is used.
+func (f File) ExpiresAt() (time.Time, bool) {
+	var times = make([]time.Time, 0, len(f.Cookies))
+
+	for _, c := range f.Cookies {
+		if !c.IsSession() {
+			times = append(times, c.Expires)
+		}
+	}
+
+	if len(times) == 0 {
+		return time.Time{}, false
+	}
+
+	slices.SortFunc(times, func(a, b time.Time) int { return a.Compare(b) })
+
+	return times[len(times)/2], true
+}
+
+// parseFileName returns the domain and the import time of the file in the working directory (named like
+// "youtube.com.1736899200000000000.txt"), and false if the name is not of such file.
+func parseFileName(name string) (domain string, importedAt time.Time, ok bool) {
+	name, ok = strings.CutSuffix(name, fileExt)
+	if !ok {
+		return "", time.Time{}, false
+	}
+
+	var i = strings.LastIndexByte(name, '.')
+	if i < 0 {
+		return "", time.Time{}, false
+	}
+
+	nanos, err := strconv.ParseInt(name[i+1:], 10, 64)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+
+	if domain = name[:i]; domain == defaultName {
+		return DefaultDomain, time.Unix(0, nanos), true
+	}
+
+	if normalized, normErr := NormalizeDomain(domain); normErr != nil || normalized != domain {
+		return "", time.Time{}, false
+	}
+
+	return domain, time.Unix(0, nanos), true
+}
+
+// writeFileAtomic writes the content to a temporary file in the same directory and renames it to the target path.
+func writeFileAtomic(path string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cookies-*.tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err = tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+
+		return err
+	}
+
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+
+		return err
+	}
+
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,333 @@
+package cookies_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gh.tarampamp.am/video-dl-bot/internal/cookies"
+)
+
+// cookiesFile returns the content of the cookies file with a single cookie, that expires at the given time.
+func cookiesFile(name string, expires time.Time) []byte {
+	return fmt.Appendf(nil, ".example.com\tTRUE\t/\tTRUE\t%d\t%s\tvalue\n", expires.Unix(), name)
+}
+
+func TestNormalizeDomain(t *testing.T) {
+	t.Parallel()
+
+	for give, want := range map[string]string{
+		"youtube.com":       "youtube.com",
+		" WWW.YouTube.com.": "youtube.com",
+		"m.youtube.com":     "m.youtube.com",
+		"youtube":           "", // no dot
+		"*.youtube.com":     "",
+		"you tube.com":      "",
+		"../youtube.com":    "",
+		"":                  "",
+	} {
+		t.Run(give, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := cookies.NormalizeDomain(give)
+
+			if want == "" {
+				if err == nil {
+					t.Errorf("expected an error, got %q", got)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got != want {
+				t.Errorf("expected %q, got %q", want, got)
+			}
+		})
+	}
+}
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+
+	var (
+		srcDir   = t.TempDir()
+		now      = time.Now()
+		defaults = filepath.Join(t.TempDir(), "cookies.txt")
+	)
+
+	for path, content := range map[string][]byte{
+		defaults:                                   cookiesFile("default", now.Add(time.Hour)),
+		filepath.Join(srcDir, "youtube.com.txt"):   cookiesFile("yt", now.Add(time.Hour)),
+		filepath.Join(srcDir, "m.youtube.com.txt"): cookiesFile("m-yt", now.Add(time.Hour)),
+		filepath.Join(srcDir, "readme.md"):         []byte("not a cookies file"),
+		filepath.Join(srcDir, "not-a-domain.txt"):  []byte("ignored"),
+	} {
+		if err := os.WriteFile(path, content, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store, err := cookies.NewStore(filepath.Join(t.TempDir(), "work"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := store.Lookup("youtube.com"); got != "" {
+		t.Errorf("expected no file for the empty store, got %q", got)
+	}
+
+	if _, err = store.ImportFile(cookies.DefaultDomain, defaults); err != nil {
+		t.Fatal(err)
+	}
+
+	imported, err := store.ImportDir(srcDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(imported) != 2 {
+		t.Fatalf("expected 2 imported files, got %d", len(imported))
+	}
+
+	var list = store.List()
+
+	if len(list) != 3 || list[0].Domain != cookies.DefaultDomain || list[1].Domain != "m.youtube.com" {
+		t.Fatalf("unexpected list: %+v", list)
+	}
+
+	// lookup returns the most specific file
+	for host, wantCookie := range map[string]string{
+		"youtube.com":     "yt",
+		"www.youtube.com": "yt",
+		"m.youtube.com":   "m-yt",
+		"vimeo.com":       "default",
+	} {
+		parsed, parseErr := cookies.ParseBytes(mustRead(t, store.Lookup(host)))
+		if parseErr != nil {
+			t.Fatal(parseErr)
+		}
+
+		if parsed[0].Name != wantCookie {
+			t.Errorf("%s: expected cookie %q, got %q", host, wantCookie, parsed[0].Name)
+		}
+	}
+
+	var prevPath = store.Lookup("youtube.com")
+
+	// invalid content is rejected, the current file is kept
+	if _, err = store.Import("youtube.com", []byte("garbage")); err == nil {
+		t.Error("expected an error for the invalid content")
+	}
+
+	if got := store.Lookup("youtube.com"); got != prevPath {
+		t.Errorf("expected the file to be kept, got %q", got)
+	}
+
+	// valid content replaces the file (with the new path) and is persisted to the source
+	f, err := store.Import("youtube.com", cookiesFile("yt-new", now.Add(time.Hour)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Path == prevPath || store.Lookup("youtube.com") != f.Path {
+		t.Errorf("expected the new file path, got %q (previous %q)", f.Path, prevPath)
+	}
+
+	if _, statErr := os.Stat(prevPath); !os.IsNotExist(statErr) {
+		t.Errorf("expected the previous file to be removed, got %v", statErr)
+	}
+
+	if got := string(mustRead(t, filepath.Join(srcDir, "youtube.com.txt"))); got != string(mustRead(t, f.Path)) {
+		t.Errorf("expected the source file to be updated, got %q", got)
+	}
+
+	// new domains are persisted to the source directory
+	if _, err = store.Import("vimeo.com", cookiesFile("vimeo", now.Add(time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, statErr := os.Stat(filepath.Join(srcDir, "vimeo.com.txt")); statErr != nil {
+		t.Errorf("expected the new domain file to be persisted: %v", statErr)
+	}
+}
+
+func TestStore_Restore(t *testing.T) {
+	t.Parallel()
+
+	var (
+		workDir = filepath.Join(t.TempDir(), "work")
+		srcDir  = t.TempDir()
+		now     = time.Now()
+	)
+
+	store, err := cookies.NewStore(workDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = store.Import("vimeo.com", cookiesFile("uploaded", now.Add(time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+
+	var siteFile = filepath.Join(srcDir, "site.txt")
+
+	if err = os.WriteFile(siteFile, cookiesFile("site", now.Add(time.Hour)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = store.ImportFile(cookies.SiteDomain("*"), siteFile); err != nil {
+		t.Fatal(err)
+	}
+
+	// the site files are found by the exact name only
+	if store.Path(cookies.SiteDomain("*")) == "" || store.Lookup("example.com") != "" {
+		t.Error("expected the site file to be available by its name only")
+	}
+
+	// the next run restores the files, except the site ones
+	restored, err := cookies.NewStore(workDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if list := restored.List(); len(list) != 1 || list[0].Domain != "vimeo.com" || list[0].Cookies[0].Name != "uploaded" {
+		t.Fatalf("unexpected restored files: %+v", list)
+	}
+
+	if entries, _ := os.ReadDir(workDir); len(entries) != 1 {
+		t.Errorf("expected the site file to be removed, got %d files", len(entries))
+	}
+
+	// the source file, that is older than the uploaded one, does not replace it
+	var srcFile = filepath.Join(srcDir, "vimeo.com.txt")
+
+	if err = os.WriteFile(srcFile, cookiesFile("stale", now.Add(time.Hour)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = os.Chtimes(srcFile, now.Add(-time.Hour), now.Add(-time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := restored.ImportFile("vimeo.com", srcFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if f.Cookies[0].Name != "uploaded" || f.Source != srcFile {
+		t.Errorf("expected the uploaded file to be kept, got %+v", f)
+	}
+}
+
+func TestStore_Rescan(t *testing.T) {
+	t.Parallel()
+
+	var (
+		srcDir = t.TempDir()
+		now    = time.Now()
+		write  = func(name, cookie string, modTime time.Time) {
+			t.Helper()
+
+			var path = filepath.Join(srcDir, name)
+
+			if err := os.WriteFile(path, cookiesFile(cookie, now.Add(time.Hour)), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := os.Chtimes(path, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+		}
+	)
+
+	write("youtube.com.txt", "yt", now.Add(-time.Hour))
+
+	store, err := cookies.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = store.ImportDir(srcDir); err != nil {
+		t.Fatal(err)
+	}
+
+	if updated, rescanErr := store.Rescan(); rescanErr != nil || len(updated) != 0 {
+		t.Fatalf("expected no updates, got %d (error: %v)", len(updated), rescanErr)
+	}
+
+	// the changed and the new files are imported
+	write("youtube.com.txt", "yt-new", time.Now())
+	write("vimeo.com.txt", "vimeo", time.Now())
+
+	updated, err := store.Rescan()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(updated) != 2 {
+		t.Fatalf("expected 2 updated files, got %d", len(updated))
+	}
+
+	for host, wantCookie := range map[string]string{"youtube.com": "yt-new", "vimeo.com": "vimeo"} {
+		parsed, parseErr := cookies.ParseBytes(mustRead(t, store.Lookup(host)))
+		if parseErr != nil {
+			t.Fatal(parseErr)
+		}
+
+		if parsed[0].Name != wantCookie {
+			t.Errorf("%s: expected cookie %q, got %q", host, wantCookie, parsed[0].Name)
+		}
+	}
+
+	// the uploaded files are persisted to the source directory, but not imported again
+	if _, err = store.Import("youtube.com", cookiesFile("uploaded", now.Add(time.Hour))); err != nil {
+		t.Fatal(err)
+	}
+
+	if updated, err = store.Rescan(); len(updated) != 0 {
+		t.Errorf("expected no updates after the upload, got %d (error: %v)", len(updated), err)
+	}
+}
+
+func TestFile_ExpiresAt(t *testing.T) {
+	t.Parallel()
+
+	var now = time.Unix(1_700_000_000, 0)
+
+	if _, ok := (cookies.File{Cookies: []cookies.Cookie{{Name: "session"}}}).ExpiresAt(); ok {
+		t.Error("expected no expiration time for the session cookies")
+	}
+
+	var f = cookies.File{Cookies: []cookies.Cookie{
+		{Name: "tracking", Expires: now.Add(time.Hour)},
+		{Name: "session"},
+		{Name: "auth1", Expires: now.Add(30 * 24 * time.Hour)},
+		{Name: "auth2", Expires: now.Add(60 * 24 * time.Hour)},
+	}}
+
+	got, ok := f.ExpiresAt()
+	if !ok {
+		t.Fatal("expected the expiration time")
+	}
+
+	if want := now.Add(30 * 24 * time.Hour); !got.Equal(want) {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func mustRead(t *testing.T, path string) []byte {
+	t.Helper()
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return b
+}
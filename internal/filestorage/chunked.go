@@ -0,0 +1,150 @@
+package filestorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// ChunkPolicy describes how the files are split into chunks for the chunked uploads (see Tus and S3), and how the
+// failed chunks are retried. Only the failed chunk is sent again, so a network blip doesn't restart the whole
+// upload.
+type ChunkPolicy struct {
+	Size       int64         // chunk size, in bytes (the chunk is buffered in memory)
+	Attempts   int           // attempts per chunk (1 or less = no retries)
+	Backoff    time.Duration // delay before the second attempt (doubled for each next one)
+	MaxBackoff time.Duration // maximum delay between the attempts (0 = no limit)
+}
+
+// DefaultChunkPolicy returns the chunk policy with reasonable defaults.
+func DefaultChunkPolicy() ChunkPolicy {
+	return ChunkPolicy{
+		Size:       16 << 20,         //nolint:mnd // 16 MiB
+		Attempts:   5,                //nolint:mnd
+		Backoff:    time.Second,      //nolint:mnd
+		MaxBackoff: 30 * time.Second, //nolint:mnd
+	}
+}
+
+// statusError is returned when the server responds with an unexpected status code.
+type statusError struct {
+	Code int
+	Body string
+}
+
+// Error implements the error interface.
+func (e *statusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("unexpected status code: %d", e.Code)
+	}
+
+	return fmt.Sprintf("unexpected status code: %d (%s)", e.Code, e.Body)
+}
+
+// newStatusError creates the error for the unexpected response (the body is read, but not closed).
+func newStatusError(resp *http.Response) error {
+	const maxBody = 1 << 10
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxBody))
+
+	return &statusError{Code: resp.StatusCode, Body: string(body)}
+}
+
+// isTransient reports whether the failed request may succeed, when retried. The network errors and the server-side
+// failures are transient, the client-side ones are not (except for the timeouts, rate limits and conflicts, e.g.,
+// the offset mismatch of the tus upload).
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	if se := new(statusError); errors.As(err, &se) {
+		switch se.Code {
+		case http.StatusRequestTimeout, http.StatusConflict, http.StatusTooManyRequests:
+			return true
+		}
+
+		return se.Code >= http.StatusInternalServerError
+	}
+
+	return true
+}
+
+// retry calls the function until it succeeds, the error is not transient, or the attempts are exhausted. The delay
+// between the attempts grows exponentially.
+func (p ChunkPolicy) retry(ctx context.Context, fn func() error) error {
+	var delay = p.Backoff
+
+	for try := 1; ; try++ {
+		var err = fn()
+
+		switch {
+		case err == nil:
+			return nil
+		case ctx.Err() != nil:
+			return fmt.Errorf("%w: %w", ctx.Err(), err)
+		case !isTransient(err):
+			return err
+		case try >= p.Attempts:
+			return fmt.Errorf("%d attempt(s) failed: %w", try, err)
+		}
+
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return fmt.Errorf("%w: %w", sleepErr, err)
+		}
+
+		if delay *= 2; p.MaxBackoff > 0 && delay > p.MaxBackoff {
+			delay = p.MaxBackoff
+		}
+	}
+}
+
+// readChunk reads the chunk of the file, starting from the offset, into the buffer. The number of the bytes read
+// is limited by the buffer size and the end of the file.
+func readChunk(r io.ReadSeeker, buf []byte, offset int64) (int, error) {
+	if _, err := r.Seek(offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	n, err := io.ReadFull(r, buf)
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		err = nil // the last chunk
+	}
+
+	return n, err
+}
+
+// progressReader reports the progress of the chunk upload (the bytes sent before the chunk are counted too).
+type progressReader struct {
+	r        io.Reader
+	sent     int64
+	total    int64
+	progress func(sent, total int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+
+	if n > 0 && p.progress != nil {
+		p.sent += int64(n)
+		p.progress(p.sent, p.total)
+	}
+
+	return n, err
+}
+
+// sleep waits for the given duration or until the context is canceled.
+func sleep(ctx context.Context, d time.Duration) error {
+	var timer = time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
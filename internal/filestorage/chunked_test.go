@@ -0,0 +1,62 @@
+package filestorage_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"gh.tarampamp.am/video-dl-bot/internal/filestorage"
+)
+
+// testChunkPolicy returns the chunk policy with small chunks and short delays for the tests.
+func testChunkPolicy() filestorage.ChunkPolicy {
+	return filestorage.ChunkPolicy{Size: 64 << 10, Attempts: 3, Backoff: time.Millisecond}
+}
+
+// dropConnection closes the client connection without a response (as if the network failed).
+func dropConnection(t testing.TB, w http.ResponseWriter) {
+	t.Helper()
+
+	conn, _, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		t.Errorf("failed to hijack the connection: %v", err)
+
+		return
+	}
+
+	_ = conn.Close()
+}
+
+func TestOpen(t *testing.T) {
+	t.Parallel()
+
+	for target, wantErr := range map[string]bool{
+		"":                                  false,
+		"filebin":                           false,
+		"filebin+https://filebin.example":   false,
+		"filebin+ftp://filebin.example":     true,
+		"tus+https://tus.example.com/files": false,
+		"tus+/files":                        true,
+		"s3://key:secret@videos/bot?region=eu-central-1&link-expiry=24h":        false,
+		"s3://key:secret@videos?endpoint=http://127.0.0.1:9000&path-style=true": false,
+		"s3://videos":                                 true, // no credentials
+		"s3://key:secret@videos?link-expiry=30d":      true,
+		"s3://key:secret@videos?link-expiry=720h":     true, // more than 7 days
+		"s3://key:secret@videos?path-style=maybe":     true,
+		"s3://key:secret@videos?endpoint=minio.local": true,
+		"ftp://example.com":                           true,
+	} {
+		t.Run(target, func(t *testing.T) {
+			t.Parallel()
+
+			u, err := filestorage.Open(target)
+
+			switch {
+			case wantErr && err == nil:
+				t.Errorf("expected an error, got %T", u)
+			case !wantErr && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
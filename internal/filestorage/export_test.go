@@ -0,0 +1,6 @@
+package filestorage
+
+import "time"
+
+// PresignForTest returns the presigned download URL of the object, signed at the given time.
+func (s *S3) PresignForTest(key string, now time.Time) string { return s.presign(key, now) }
@@ -1,20 +1,30 @@
 package filestorage
 
 import (
+	"cmp"
 	"context"
 	"crypto/sha256"
+	"encoding/json"
 	"fmt"
+	"hash"
 	"io"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 )
 
+// fileBinURL is the base URL of the filebin.net API.
+const fileBinURL = "https://filebin.net"
+
 type (
 	// fileBinOptions holds configuration for the upload operation.
 	fileBinOptions struct {
-		client *http.Client
-		binId  string
+		client   *http.Client
+		binId    string
+		baseURL  string                  // filebin server URL
+		progress func(sent, total int64) // upload progress callback (optional)
+		preHash  bool                    // calculate the hash before the upload, and send it in the header
 	}
 
 	// FileBinOption defines a functional option type for customizing fileBinOptions.
@@ -28,6 +38,30 @@ func WithFileBinHTTPClient(c *http.Client) FileBinOption {
 	}
 }
 
+// WithFileBinURL sets the URL of the filebin server (e.g., the self-hosted one; "https://filebin.net" by default).
+func WithFileBinURL(baseURL string) FileBinOption {
+	return func(opts *fileBinOptions) {
+		opts.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithFileBinProgress sets the callback, that is called while the file is uploaded, with the number of the bytes
+// sent so far and the file size. It's called often, so it should be fast (and throttle itself, if needed).
+func WithFileBinProgress(fn func(sent, total int64)) FileBinOption {
+	return func(opts *fileBinOptions) {
+		opts.progress = fn
+	}
+}
+
+// WithFileBinPreHash makes the upload calculate the file hash before uploading it, and send it in the
+// Content-SHA256 header (so the server verifies it). The file is read twice in this case, so use it only for the
+// servers, that don't report the checksum of the uploaded file.
+func WithFileBinPreHash() FileBinOption {
+	return func(opts *fileBinOptions) {
+		opts.preHash = true
+	}
+}
+
 // Apply sets default values and applies user-provided functional options.
 func (o fileBinOptions) Apply(opts ...FileBinOption) fileBinOptions {
 	// set default client if not provided
@@ -43,6 +77,10 @@ func (o fileBinOptions) Apply(opts ...FileBinOption) fileBinOptions {
 		o.binId = randomString(16) //nolint:mnd // assumes randomString is defined elsewhere
 	}
 
+	if o.baseURL == "" {
+		o.baseURL = fileBinURL
+	}
+
 	// apply all user-supplied options
 	for _, opt := range opts {
 		opt(&o)
@@ -54,15 +92,29 @@ func (o fileBinOptions) Apply(opts ...FileBinOption) fileBinOptions {
 // UploadToFileBin uploads a file to filebin.net and locks the bin for read-only access. The size of the file is
 // determined by seeking to the end of the reader.
 //
+// The file is read once: the SHA256 hash is calculated while the file is uploaded, and compared with the checksum
+// reported by the server afterward. The bin is locked (the upload is committed) only if they match, otherwise the
+// uploaded file is deleted. See WithFileBinPreHash for the servers, that don't report the checksum.
+//
 // https://github.com/espebra/filebin2
 //
 // Returns the public URL of the uploaded file.
-func UploadToFileBin( //nolint:funlen
+func UploadToFileBin(ctx context.Context, r io.ReadSeeker, filename string, opts ...FileBinOption) (string, error) {
+	obj, err := uploadToFileBin(ctx, r, filename, fileBinOptions{}.Apply(opts...))
+	if err != nil {
+		return "", err
+	}
+
+	return obj.URL, nil
+}
+
+// uploadToFileBin uploads a file to the bin (see UploadToFileBin), and returns the uploaded file with the bin ID.
+func uploadToFileBin( //nolint:funlen
 	ctx context.Context,
 	r io.ReadSeeker,
 	filename string,
-	opts ...FileBinOption,
-) (_ string, outErr error) {
+	o fileBinOptions,
+) (_ *Object, outErr error) {
 	// wrap returned error with module-specific prefix
 	defer func() {
 		if outErr != nil {
@@ -71,38 +123,43 @@ func UploadToFileBin( //nolint:funlen
 	}()
 
 	var (
-		o         = fileBinOptions{}.Apply(opts...)                             // initialize options with defaults
-		uploadURL = fmt.Sprintf("https://filebin.net/%s/%s", o.binId, filename) // construct upload URL
+		binURL    = fmt.Sprintf("%s/%s", o.baseURL, o.binId)               // bin URL (used for locking)
+		uploadURL = fmt.Sprintf("%s/%s", binURL, url.PathEscape(filename)) // construct upload URL
 	)
 
 	// calculate the size of the file to be uploaded
 	fileSize, fileSizeErr := getFileSize(r)
 	if fileSizeErr != nil {
-		return "", fmt.Errorf("failed to determine file size: %w", fileSizeErr)
+		return nil, fmt.Errorf("failed to determine file size: %w", fileSizeErr)
 	}
 
-	hash, hashErr := calculateSHA256Hash(r)
-	if hashErr != nil {
-		return "", fmt.Errorf("failed to calculate SHA256 hash: %w", hashErr)
-	}
+	var upload = &hashingReader{r: r, hash: sha256.New(), total: fileSize, progress: o.progress}
 
 	// create HTTP POST request to upload file
-	upReq, upReqErr := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, r)
+	upReq, upReqErr := http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, upload)
 	if upReqErr != nil {
-		return "", upReqErr
+		return nil, upReqErr
 	}
 
 	// set appropriate headers
 	upReq.Header.Set("Content-Type", "application/octet-stream")
 	upReq.Header.Set("Accept", "application/json")
-	upReq.Header.Set("Content-SHA256", hash)
+
+	if o.preHash {
+		hash, hashErr := calculateSHA256Hash(r)
+		if hashErr != nil {
+			return nil, fmt.Errorf("failed to calculate SHA256 hash: %w", hashErr)
+		}
+
+		upReq.Header.Set("Content-SHA256", hash)
+	}
 
 	upReq.ContentLength = fileSize // <-- important
 
 	// perform upload request
 	upResp, upRespErr := o.client.Do(upReq)
 	if upRespErr != nil {
-		return "", upRespErr
+		return nil, upRespErr
 	}
 
 	defer func() { _ = upResp.Body.Close() }()
@@ -117,7 +174,17 @@ func UploadToFileBin( //nolint:funlen
 			body = []byte("failed to read response body")
 		}
 
-		return "", fmt.Errorf("unexpected status code after upload: %d (%s)", upResp.StatusCode, string(body))
+		return nil, fmt.Errorf("unexpected status code after upload: %d (%s)", upResp.StatusCode, string(body))
+	}
+
+	// verify the uploaded file, before the bin is locked
+	expiresAt, err := upload.verify(upResp.Body)
+	if err != nil {
+		_ = upResp.Body.Close()
+
+		deleteFile(o.client, uploadURL, nil) //nolint:contextcheck // the context may be canceled already
+
+		return nil, err
 	}
 
 	_ = upResp.Body.Close()
@@ -126,11 +193,11 @@ func UploadToFileBin( //nolint:funlen
 	lockReq, lockReqErr := http.NewRequestWithContext(
 		ctx,
 		http.MethodPut,
-		fmt.Sprintf("https://filebin.net/%s", o.binId),
+		binURL,
 		http.NoBody,
 	)
 	if lockReqErr != nil {
-		return "", lockReqErr
+		return nil, lockReqErr
 	}
 
 	lockReq.Header.Set("Accept", "application/json")
@@ -138,23 +205,23 @@ func UploadToFileBin( //nolint:funlen
 	// send lock request
 	lockResp, lockRespErr := o.client.Do(lockReq)
 	if lockRespErr != nil {
-		return "", lockRespErr
+		return nil, lockRespErr
 	}
 
 	_ = lockResp.Body.Close()
 
 	// ensure bin locking was successful
 	if lockResp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("unexpected status code after locking bin: %d", lockResp.StatusCode)
+		return nil, fmt.Errorf("unexpected status code after locking bin: %d", lockResp.StatusCode)
 	}
 
 	// parse final public URL
 	u, uErr := url.Parse(uploadURL)
 	if uErr != nil {
-		return "", uErr
+		return nil, uErr
 	}
 
-	return u.String(), nil
+	return &Object{URL: u.String(), ID: o.binId, ExpiresAt: expiresAt, SelfExpiring: true}, nil
 }
 
 // getFileSize calculates the size of the file to be uploaded by seeking to the end of the reader.
@@ -201,3 +268,71 @@ func calculateSHA256Hash(r io.ReadSeeker) (string, error) {
 	// return the hex-encoded hash
 	return fmt.Sprintf("%x", hash.Sum(nil)), nil
 }
+
+// hashingReader calculates the hash of the data while it's read, and reports the progress.
+type hashingReader struct {
+	r        io.Reader
+	hash     hash.Hash
+	sent     int64
+	total    int64
+	progress func(sent, total int64)
+}
+
+func (h *hashingReader) Read(p []byte) (int, error) {
+	n, err := h.r.Read(p)
+
+	if n > 0 {
+		_, _ = h.hash.Write(p[:n])
+		h.sent += int64(n)
+
+		if h.progress != nil {
+			h.progress(h.sent, h.total)
+		}
+	}
+
+	return n, err
+}
+
+// verify checks the number of the sent bytes, and compares the calculated hash with the checksum from the upload
+// response (if the server reports it). The bin expiration time is returned (zero, if not reported).
+func (h *hashingReader) verify(resp io.Reader) (time.Time, error) {
+	if h.sent != h.total {
+		return time.Time{}, fmt.Errorf("the file size changed during the upload: %d bytes sent, %d expected",
+			h.sent, h.total)
+	}
+
+	var uploaded struct {
+		SHA256 string `json:"sha256"`
+		File   struct {
+			SHA256 string `json:"sha256"`
+		} `json:"file"`
+		Bin struct {
+			ExpiredAt time.Time `json:"expired_at"`
+		} `json:"bin"`
+	}
+
+	if err := json.NewDecoder(resp).Decode(&uploaded); err != nil {
+		return time.Time{}, nil // the checksum is not reported (the response is not a JSON)
+	}
+
+	var (
+		got  = strings.ToLower(cmp.Or(uploaded.SHA256, uploaded.File.SHA256))
+		want = fmt.Sprintf("%x", h.hash.Sum(nil))
+	)
+
+	if got != "" && got != want {
+		return time.Time{}, fmt.Errorf("checksum mismatch: the server reports %s, expected %s", got, want)
+	}
+
+	return uploaded.Bin.ExpiredAt, nil
+}
+
+// deleteFile deletes the uploaded file (errors are ignored, since it's a cleanup).
+func deleteFile(client *http.Client, fileURL string, header http.Header) {
+	const timeout = 30 * time.Second
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	_ = deleteRequest(ctx, client, fileURL, header)
+}
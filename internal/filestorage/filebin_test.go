@@ -0,0 +1,232 @@
+package filestorage_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gh.tarampamp.am/video-dl-bot/internal/filestorage"
+)
+
+// fakeFileBin is a minimal filebin server, that reports the checksum of the uploaded files.
+type fakeFileBin struct {
+	corrupt bool // report the wrong checksum
+
+	mu      sync.Mutex
+	files   map[string][]byte // path -> content
+	locked  map[string]bool   // bin -> locked
+	deleted []string
+}
+
+func newFakeFileBin(t testing.TB, corrupt bool) (*fakeFileBin, *httptest.Server) {
+	t.Helper()
+
+	var (
+		fb  = &fakeFileBin{corrupt: corrupt, files: make(map[string][]byte), locked: make(map[string]bool)}
+		srv = httptest.NewServer(fb)
+	)
+
+	t.Cleanup(srv.Close)
+
+	return fb, srv
+}
+
+func (fb *fakeFileBin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+
+	switch r.Method {
+	case http.MethodPost:
+		content, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		var sum = fmt.Sprintf("%x", sha256.Sum256(content))
+
+		if want := r.Header.Get("Content-SHA256"); want != "" && want != sum {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		if fb.corrupt {
+			sum = strings.Repeat("0", len(sum))
+		}
+
+		fb.files[r.URL.Path] = content
+
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"bin":  map[string]any{"expired_at": "2030-01-02T03:04:05Z"},
+			"file": map[string]any{"bytes": len(content), "sha256": sum},
+		})
+	case http.MethodPut:
+		fb.locked[strings.Trim(r.URL.Path, "/")] = true
+	case http.MethodDelete:
+		fb.deleted = append(fb.deleted, r.URL.Path)
+		delete(fb.files, r.URL.Path)
+
+		for path := range fb.files { // the bin is deleted with its files
+			if strings.HasPrefix(path, r.URL.Path+"/") {
+				delete(fb.files, path)
+			}
+		}
+	}
+}
+
+// countingReader counts the bytes read from the underlying reader (it doesn't embed the reader, so io.Copy cannot
+// bypass the counting with io.WriterTo).
+type countingReader struct {
+	r    *bytes.Reader
+	read atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read.Add(int64(n))
+
+	return n, err
+}
+
+func (c *countingReader) Seek(offset int64, whence int) (int64, error) {
+	return c.r.Seek(offset, whence)
+}
+
+func TestUploadToFileBin(t *testing.T) {
+	t.Parallel()
+
+	var (
+		fb, srv  = newFakeFileBin(t, false)
+		content  = bytes.Repeat([]byte("video"), 100_000)
+		r        = &countingReader{r: bytes.NewReader(content)}
+		lastSent atomic.Int64 // the progress callback is called from the transport goroutine
+	)
+
+	link, err := filestorage.UploadToFileBin(context.Background(), r, "video.mp4",
+		filestorage.WithFileBinURL(srv.URL+"/"),
+		filestorage.WithFileBinProgress(func(sent, total int64) {
+			if total != int64(len(content)) {
+				t.Errorf("unexpected total size: %d", total)
+			}
+
+			lastSent.Store(sent)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(link, srv.URL+"/") || !strings.HasSuffix(link, "/video.mp4") {
+		t.Errorf("unexpected link: %s", link)
+	}
+
+	if got := r.read.Load(); got != int64(len(content)) {
+		t.Errorf("the file should be read once, %d bytes read (the size is %d)", got, len(content))
+	}
+
+	if got := lastSent.Load(); got != int64(len(content)) {
+		t.Errorf("unexpected progress: %d bytes sent", got)
+	}
+
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+
+	if len(fb.files) != 1 || len(fb.locked) != 1 {
+		t.Errorf("expected one uploaded file in the locked bin, got %d files and %d locked bins", len(fb.files),
+			len(fb.locked))
+	}
+}
+
+func TestUploadToFileBin_ChecksumMismatch(t *testing.T) {
+	t.Parallel()
+
+	var fb, srv = newFakeFileBin(t, true)
+
+	_, err := filestorage.UploadToFileBin(context.Background(), bytes.NewReader([]byte("video")), "video.mp4",
+		filestorage.WithFileBinURL(srv.URL),
+	)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected the checksum mismatch error, got %v", err)
+	}
+
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+
+	if len(fb.deleted) != 1 || len(fb.files) != 0 || len(fb.locked) != 0 {
+		t.Errorf("the file should be deleted, and the bin should not be locked (deleted: %v, locked: %v)",
+			fb.deleted, fb.locked)
+	}
+}
+
+func TestFileBin_Delete(t *testing.T) {
+	t.Parallel()
+
+	var (
+		fb, srv = newFakeFileBin(t, false)
+		bin     = filestorage.NewFileBin(filestorage.WithFileBinURL(srv.URL))
+	)
+
+	obj, err := bin.Upload(context.Background(), bytes.NewReader([]byte("video")), "video.mp4", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !obj.SelfExpiring || !obj.ExpiresAt.Equal(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)) ||
+		!strings.HasPrefix(obj.URL, srv.URL+"/"+obj.ID+"/") {
+		t.Errorf("unexpected object: %+v", obj)
+	}
+
+	if err = bin.Delete(context.Background(), obj.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+
+	if len(fb.files) != 0 || len(fb.deleted) != 1 || fb.deleted[0] != "/"+obj.ID {
+		t.Errorf("the bin should be deleted (deleted: %v)", fb.deleted)
+	}
+}
+
+// BenchmarkUploadToFileBin compares the bytes read from the file per upload: the file is read once, when the hash
+// is calculated during the upload, and twice with the pre-calculated hash.
+func BenchmarkUploadToFileBin(b *testing.B) {
+	var content = bytes.Repeat([]byte{0xAB}, 8<<20)
+
+	for name, opts := range map[string][]filestorage.FileBinOption{
+		"streaming": nil,
+		"pre-hash":  {filestorage.WithFileBinPreHash()},
+	} {
+		b.Run(name, func(b *testing.B) {
+			var (
+				_, srv = newFakeFileBin(b, false)
+				r      = &countingReader{r: bytes.NewReader(content)}
+			)
+
+			b.SetBytes(int64(len(content)))
+
+			for b.Loop() {
+				if _, err := filestorage.UploadToFileBin(context.Background(), r, "video.mp4",
+					append(opts, filestorage.WithFileBinURL(srv.URL))...,
+				); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			b.ReportMetric(float64(r.read.Load())/float64(b.N), "read-B/op")
+		})
+	}
+}
@@ -0,0 +1,471 @@
+package filestorage
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	s3MinPartSize  = 5 << 20 // minimal size of the multipart upload part (except the last one)
+	s3MaxParts     = 10_000  // maximal number of the multipart upload parts
+	s3MaxLinkTTL   = 7 * 24 * time.Hour
+	s3EmptyPayload = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855" // SHA256 of nothing
+)
+
+type (
+	// S3Config configures the S3 uploader.
+	S3Config struct {
+		Endpoint   string        // e.g., "https://minio.example.com" (the AWS endpoint of the region, if empty)
+		Region     string        // e.g., "eu-central-1" ("us-east-1", if empty)
+		Bucket     string        // bucket name
+		Prefix     string        // prefix of the object keys (e.g., "videos")
+		AccessKey  string        // access key ID
+		SecretKey  string        // secret access key
+		PathStyle  bool          // use the path-style URLs ("https://host/bucket/key"), e.g., for MinIO
+		LinkExpiry time.Duration // lifetime of the presigned download links (48 hours, if empty; 7 days max)
+	}
+
+	// S3 uploads the files to the S3-compatible storage with the multipart uploads, so only the failed part is sent
+	// again after a network failure. The requests are signed with AWS Signature Version 4.
+	//
+	// The presigned download link of the uploaded object is returned as the public URL of the file.
+	S3 struct {
+		cfg      S3Config
+		endpoint *url.URL
+		client   *http.Client
+		policy   ChunkPolicy
+		now      func() time.Time
+	}
+)
+
+var _ Uploader = (*S3)(nil) // ensure the interface is implemented
+
+// NewS3 creates a new S3 uploader.
+func NewS3(cfg S3Config, opts ...Option) (*S3, error) {
+	const defaultLinkTTL = 48 * time.Hour
+
+	cfg.Region = cmp.Or(cfg.Region, "us-east-1")
+	cfg.Endpoint = cmp.Or(cfg.Endpoint, fmt.Sprintf("https://s3.%s.amazonaws.com", cfg.Region))
+	cfg.Prefix = strings.Trim(cfg.Prefix, "/")
+	cfg.LinkExpiry = cmp.Or(cfg.LinkExpiry, defaultLinkTTL)
+
+	switch {
+	case cfg.Bucket == "":
+		return nil, errors.New("bucket is not specified")
+	case cfg.AccessKey == "" || cfg.SecretKey == "":
+		return nil, errors.New("credentials are not specified")
+	case cfg.LinkExpiry < time.Second || cfg.LinkExpiry > s3MaxLinkTTL:
+		return nil, fmt.Errorf("link expiry must be between 1 second and %s", s3MaxLinkTTL)
+	}
+
+	if err := validateHTTPURL(cfg.Endpoint); err != nil {
+		return nil, fmt.Errorf("invalid endpoint: %w", err)
+	}
+
+	endpoint, _ := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+
+	var o = options{}.Apply(opts...)
+
+	return &S3{cfg: cfg, endpoint: endpoint, client: o.client, policy: o.policy, now: time.Now}, nil
+}
+
+// Name implements the Uploader interface.
+func (s *S3) Name() string {
+	return fmt.Sprintf("s3://%s/%s?endpoint=%s", s.cfg.Bucket, s.cfg.Prefix, s.endpoint)
+}
+
+// Upload implements the Uploader interface. The object key is the ID of the uploaded file, and the link expires
+// with the presigned URL (the object is not deleted by the storage, unless the bucket lifecycle rules are set).
+func (s *S3) Upload( //nolint:funlen
+	ctx context.Context,
+	r io.ReadSeeker,
+	filename string,
+	progress func(sent, total int64),
+) (_ *Object, outErr error) {
+	// wrap returned error with module-specific prefix
+	defer func() {
+		if outErr != nil {
+			outErr = fmt.Errorf("s3: %w", outErr)
+		}
+	}()
+
+	fileSize, fileSizeErr := getFileSize(r)
+	if fileSizeErr != nil {
+		return nil, fmt.Errorf("failed to determine file size: %w", fileSizeErr)
+	}
+
+	var key = strings.TrimPrefix(s.cfg.Prefix+"/"+randomString(16)+"/"+filename, "/") //nolint:mnd
+
+	var uploadID string
+
+	if err := s.policy.retry(ctx, func() (err error) {
+		uploadID, err = s.createUpload(ctx, key)
+
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create the multipart upload: %w", err)
+	}
+
+	var (
+		partSize = max(s.policy.Size, s3MinPartSize, (fileSize+s3MaxParts-1)/s3MaxParts)
+		buf      = make([]byte, max(1, min(partSize, fileSize)))
+		parts    []s3Part
+	)
+
+	// the empty file is uploaded as a single empty part
+	for offset := int64(0); offset < fileSize || len(parts) == 0; {
+		n, readErr := readChunk(r, buf, offset)
+		if readErr != nil {
+			s.abortUpload(key, uploadID) //nolint:contextcheck // the context may be canceled already
+
+			return nil, fmt.Errorf("failed to read the file: %w", readErr)
+		}
+
+		var part = s3Part{Number: len(parts) + 1}
+
+		if err := s.policy.retry(ctx, func() (err error) {
+			part.ETag, err = s.uploadPart(ctx, key, uploadID, part.Number, buf[:n], offset, fileSize, progress)
+
+			return err
+		}); err != nil {
+			s.abortUpload(key, uploadID) //nolint:contextcheck // the context may be canceled already
+
+			return nil, fmt.Errorf("failed to upload the part %d: %w", part.Number, err)
+		}
+
+		parts, offset = append(parts, part), offset+int64(n)
+	}
+
+	if err := s.policy.retry(ctx, func() error { return s.completeUpload(ctx, key, uploadID, parts) }); err != nil {
+		s.abortUpload(key, uploadID) //nolint:contextcheck // the context may be canceled already
+
+		return nil, fmt.Errorf("failed to complete the multipart upload: %w", err)
+	}
+
+	var now = s.now()
+
+	return &Object{URL: s.presign(key, now), ID: key, ExpiresAt: now.Add(s.cfg.LinkExpiry)}, nil
+}
+
+// Delete implements the Uploader interface.
+func (s *S3) Delete(ctx context.Context, key string) error {
+	resp, err := s.do(ctx, http.MethodDelete, key, nil, nil)
+	if se := new(statusError); errors.As(err, &se) && se.Code == http.StatusNotFound {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("s3: failed to delete the object: %w", err)
+	}
+
+	return resp.Body.Close()
+}
+
+// s3Part is the uploaded part of the multipart upload.
+type s3Part struct {
+	Number int    `xml:"PartNumber"`
+	ETag   string `xml:"ETag"`
+}
+
+// createUpload initiates the multipart upload, and returns its ID.
+func (s *S3) createUpload(ctx context.Context, key string) (string, error) {
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+
+	if err = xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode the response: %w", err)
+	}
+
+	if result.UploadID == "" {
+		return "", errors.New("the upload ID is not reported")
+	}
+
+	return result.UploadID, nil
+}
+
+// uploadPart uploads the part of the multipart upload, and returns its ETag.
+func (s *S3) uploadPart(
+	ctx context.Context,
+	key, uploadID string,
+	number int,
+	chunk []byte,
+	offset, total int64,
+	progress func(sent, total int64),
+) (string, error) {
+	var query = url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {uploadID}}
+
+	resp, err := s.do(ctx, http.MethodPut, key, query, chunk, func(body io.Reader) io.Reader {
+		return &progressReader{r: body, sent: offset, total: total, progress: progress}
+	})
+	if err != nil {
+		return "", err
+	}
+
+	_ = resp.Body.Close()
+
+	var etag = resp.Header.Get("ETag")
+	if etag == "" {
+		return "", errors.New("the part ETag is not reported")
+	}
+
+	return etag, nil
+}
+
+// completeUpload completes the multipart upload (the object is assembled from the parts).
+func (s *S3) completeUpload(ctx context.Context, key, uploadID string, parts []s3Part) error {
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name `xml:"CompleteMultipartUpload"`
+		Parts   []s3Part `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, body)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	// the error may be reported with the 200 status code, after the response headers are sent
+	var result struct {
+		XMLName xml.Name
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+
+	if err = xml.NewDecoder(resp.Body).Decode(&result); err == nil && result.XMLName.Local == "Error" {
+		return &statusError{Code: http.StatusInternalServerError, Body: result.Code + ": " + result.Message}
+	}
+
+	return nil
+}
+
+// abortUpload aborts the multipart upload, so the uploaded parts are removed (errors are ignored, since it's
+// a cleanup).
+func (s *S3) abortUpload(key, uploadID string) {
+	const timeout = 30 * time.Second
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if resp, err := s.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil); err == nil {
+		_ = resp.Body.Close()
+	}
+}
+
+// do sends the signed request, and checks the response status code (the response body must be closed by the
+// caller, if no error is returned). The payload reader can be wrapped (e.g., to report the progress).
+func (s *S3) do(
+	ctx context.Context,
+	method, key string,
+	query url.Values,
+	payload []byte,
+	wrap ...func(io.Reader) io.Reader,
+) (*http.Response, error) {
+	var (
+		u              = s.objectURL(key)
+		body io.Reader = bytes.NewReader(payload)
+	)
+
+	u.RawQuery = canonicalQuery(query)
+
+	for _, fn := range wrap {
+		body = fn(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.ContentLength = int64(len(payload))
+
+	var payloadHash = s3EmptyPayload
+
+	if len(payload) > 0 {
+		payloadHash = hashHex(payload)
+	}
+
+	s.sign(req, payloadHash, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		defer func() { _ = resp.Body.Close() }()
+
+		return nil, newStatusError(resp)
+	}
+
+	return resp, nil
+}
+
+// objectURL returns the URL of the object (path-style or virtual-hosted-style one).
+func (s *S3) objectURL(key string) *url.URL {
+	var u = *s.endpoint
+
+	if s.cfg.PathStyle {
+		u.Path += "/" + s.cfg.Bucket + "/" + key
+	} else {
+		u.Host = s.cfg.Bucket + "." + u.Host
+		u.Path += "/" + key
+	}
+
+	var segments = strings.Split(u.Path, "/")
+
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+
+	u.RawPath = strings.Join(segments, "/")
+
+	return &u
+}
+
+// sign signs the request with AWS Signature Version 4 (the headers are set).
+func (s *S3) sign(req *http.Request, payloadHash string, now time.Time) {
+	var amzDate = now.UTC().Format("20060102T150405Z")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	const signedHeaders = "host;x-amz-content-sha256;x-amz-date"
+
+	var (
+		canonicalHeaders = "host:" + req.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n"
+		scope     = s.scope(amzDate)
+		signature = s.signature(amzDate, strings.Join([]string{
+			req.Method,
+			req.URL.EscapedPath(),
+			req.URL.RawQuery,
+			canonicalHeaders,
+			signedHeaders,
+			payloadHash,
+		}, "\n"))
+	)
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.cfg.AccessKey, scope, signedHeaders, signature,
+	))
+}
+
+// presign returns the presigned GET URL of the object, that expires after the configured link lifetime.
+func (s *S3) presign(key string, now time.Time) string {
+	var (
+		u       = s.objectURL(key)
+		amzDate = now.UTC().Format("20060102T150405Z")
+		query   = url.Values{
+			"X-Amz-Algorithm":     {"AWS4-HMAC-SHA256"},
+			"X-Amz-Credential":    {s.cfg.AccessKey + "/" + s.scope(amzDate)},
+			"X-Amz-Date":          {amzDate},
+			"X-Amz-Expires":       {strconv.Itoa(int(s.cfg.LinkExpiry.Seconds()))},
+			"X-Amz-SignedHeaders": {"host"},
+		}
+	)
+
+	query.Set("X-Amz-Signature", s.signature(amzDate, strings.Join([]string{
+		http.MethodGet,
+		u.EscapedPath(),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")))
+
+	u.RawQuery = canonicalQuery(query)
+
+	return u.String()
+}
+
+// scope returns the credential scope for the date (in the "20060102T150405Z" format).
+func (s *S3) scope(amzDate string) string {
+	return amzDate[:8] + "/" + s.cfg.Region + "/s3/aws4_request"
+}
+
+// signature calculates the signature of the canonical request.
+func (s *S3) signature(amzDate, canonicalRequest string) string {
+	var key = []byte("AWS4" + s.cfg.SecretKey)
+
+	for _, part := range []string{amzDate[:8], s.cfg.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+
+	return hex.EncodeToString(hmacSHA256(key,
+		"AWS4-HMAC-SHA256\n"+amzDate+"\n"+s.scope(amzDate)+"\n"+hashHex([]byte(canonicalRequest)),
+	))
+}
+
+// canonicalQuery returns the query string, sorted and encoded as required by AWS Signature Version 4.
+func canonicalQuery(query url.Values) string {
+	var pairs = make([]string, 0, len(query))
+
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, uriEncode(key)+"="+uriEncode(value))
+		}
+	}
+
+	slices.Sort(pairs)
+
+	return strings.Join(pairs, "&")
+}
+
+// uriEncode encodes everything except the unreserved characters (RFC 3986), as required by AWS Signature Version 4.
+func uriEncode(s string) string {
+	const hexDigits = "0123456789ABCDEF"
+
+	var sb strings.Builder
+
+	for i := range len(s) {
+		switch c := s[i]; {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte('%')
+			sb.WriteByte(hexDigits[c>>4])
+			sb.WriteByte(hexDigits[c&0x0F])
+		}
+	}
+
+	return sb.String()
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	var h = hmac.New(sha256.New, key)
+
+	_, _ = h.Write([]byte(data))
+
+	return h.Sum(nil)
+}
+
+func hashHex(data []byte) string {
+	var sum = sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,240 @@
+package filestorage
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// tusVersion is the supported version of the tus protocol.
+const tusVersion = "1.0.0"
+
+// Tus uploads the files to the tus server in chunks (https://tus.io/protocols/resumable-upload). When a chunk
+// upload fails, the offset received by the server is requested, and the upload is resumed from it (so even the
+// partially sent chunk is not sent again).
+//
+// The upload URL (e.g., served by tusd) is returned as the public URL of the file.
+type Tus struct {
+	endpoint string // creation endpoint (e.g., "https://tus.example.com/files/")
+	client   *http.Client
+	policy   ChunkPolicy
+}
+
+var _ Uploader = (*Tus)(nil) // ensure the interface is implemented
+
+// NewTus creates a new tus uploader for the given creation endpoint.
+func NewTus(endpoint string, opts ...Option) *Tus {
+	var o = options{}.Apply(opts...)
+
+	return &Tus{endpoint: endpoint, client: o.client, policy: o.policy}
+}
+
+// Name implements the Uploader interface.
+func (t *Tus) Name() string { return "tus+" + t.endpoint }
+
+// Upload implements the Uploader interface. The upload URL is the ID of the uploaded file. If the server reports
+// the upload expiration (the "expiration" extension), it's considered to delete the expired uploads itself.
+func (t *Tus) Upload( //nolint:funlen
+	ctx context.Context,
+	r io.ReadSeeker,
+	filename string,
+	progress func(sent, total int64),
+) (_ *Object, outErr error) {
+	// wrap returned error with module-specific prefix
+	defer func() {
+		if outErr != nil {
+			outErr = fmt.Errorf("tus: %w", outErr)
+		}
+	}()
+
+	fileSize, fileSizeErr := getFileSize(r)
+	if fileSizeErr != nil {
+		return nil, fmt.Errorf("failed to determine file size: %w", fileSizeErr)
+	}
+
+	var (
+		location  string
+		expiresAt time.Time // reported by the server (optional)
+	)
+
+	if err := t.policy.retry(ctx, func() (err error) {
+		location, expiresAt, err = t.create(ctx, fileSize, filename)
+
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("failed to create the upload: %w", err)
+	}
+
+	var (
+		buf    = make([]byte, max(1, min(t.policy.Size, fileSize)))
+		offset int64
+	)
+
+	for offset < fileSize {
+		n, readErr := readChunk(r, buf, offset)
+		if readErr != nil {
+			return nil, fmt.Errorf("failed to read the file: %w", readErr)
+		}
+
+		var start, end = offset, offset + int64(n)
+
+		if err := t.policy.retry(ctx, func() error {
+			next, expires, err := t.patch(ctx, location, buf[offset-start:n], offset, fileSize, progress)
+			if err == nil && next <= offset {
+				err = fmt.Errorf("the server has not accepted the chunk (offset %d)", next)
+			}
+
+			if err == nil {
+				offset, expiresAt = next, cmp.Or(expires, expiresAt)
+
+				return nil
+			}
+
+			// the server may have received a part of the chunk, so the upload is resumed from its offset
+			if received, headErr := t.offset(ctx, location); headErr == nil && received >= start && received <= end {
+				if offset = received; offset == end {
+					return nil // the response is lost, but the chunk is received
+				}
+			}
+
+			return err
+		}); err != nil {
+			deleteFile(t.client, location, tusHeader()) //nolint:contextcheck // the context may be canceled already
+
+			return nil, fmt.Errorf("failed to upload the chunk at offset %d: %w", offset, err)
+		}
+	}
+
+	return &Object{URL: location, ID: location, ExpiresAt: expiresAt, SelfExpiring: !expiresAt.IsZero()}, nil
+}
+
+// Delete implements the Uploader interface (the server must support the "termination" extension).
+func (t *Tus) Delete(ctx context.Context, location string) error {
+	if err := deleteRequest(ctx, t.client, location, tusHeader()); err != nil {
+		return fmt.Errorf("tus: failed to delete the upload: %w", err)
+	}
+
+	return nil
+}
+
+// create creates a new upload on the server, and returns its URL (and the expiration time, if reported).
+func (t *Tus) create(ctx context.Context, size int64, filename string) (string, time.Time, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, http.NoBody)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	req.Header.Set("Tus-Resumable", tusVersion)
+	req.Header.Set("Upload-Length", strconv.FormatInt(size, 10))
+	req.Header.Set("Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte(filename)))
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusCreated {
+		return "", time.Time{}, newStatusError(resp)
+	}
+
+	location, err := resp.Location() // resolves the relative location against the request URL
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid upload location: %w", err)
+	}
+
+	return location.String(), parseExpires(resp), nil
+}
+
+// patch sends the chunk, that starts from the offset, and returns the new offset (and the expiration time, if
+// reported) by the server.
+func (t *Tus) patch(
+	ctx context.Context,
+	location string,
+	chunk []byte,
+	offset, total int64,
+	progress func(sent, total int64),
+) (int64, time.Time, error) {
+	var body = &progressReader{r: bytes.NewReader(chunk), sent: offset, total: total, progress: progress}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, location, body)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	req.ContentLength = int64(len(chunk))
+	req.Header.Set("Tus-Resumable", tusVersion)
+	req.Header.Set("Content-Type", "application/offset+octet-stream")
+	req.Header.Set("Upload-Offset", strconv.FormatInt(offset, 10))
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return 0, time.Time{}, err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusNoContent {
+		return 0, time.Time{}, newStatusError(resp)
+	}
+
+	offset, err = parseOffset(resp)
+
+	return offset, parseExpires(resp), err
+}
+
+// offset requests the offset of the upload, received by the server.
+func (t *Tus) offset(ctx context.Context, location string) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, location, http.NoBody)
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Tus-Resumable", tusVersion)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
+		return 0, &statusError{Code: resp.StatusCode}
+	}
+
+	return parseOffset(resp)
+}
+
+// tusHeader returns the header with the protocol version, required for every request (except OPTIONS).
+func tusHeader() http.Header { return http.Header{"Tus-Resumable": {tusVersion}} }
+
+// parseExpires parses the Upload-Expires header of the response (zero, if not reported).
+func parseExpires(resp *http.Response) time.Time {
+	t, _ := http.ParseTime(resp.Header.Get("Upload-Expires"))
+
+	return t
+}
+
+// parseOffset parses the Upload-Offset header of the response.
+func parseOffset(resp *http.Response) (int64, error) {
+	var v = resp.Header.Get("Upload-Offset")
+	if v == "" {
+		return 0, errors.New("the upload offset is not reported")
+	}
+
+	offset, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid upload offset: %q", v)
+	}
+
+	return offset, nil
+}
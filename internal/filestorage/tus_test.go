@@ -0,0 +1,196 @@
+package filestorage_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gh.tarampamp.am/video-dl-bot/internal/filestorage"
+)
+
+// fakeTus is a minimal tus server, that drops the connection of every N-th PATCH request after receiving a half of
+// the chunk (as the flaky network does).
+type fakeTus struct {
+	t         testing.TB
+	dropEvery int
+
+	mu      sync.Mutex
+	uploads map[string][]byte // path -> received content
+	lengths map[string]int64  // path -> upload length
+	patches int
+	dropped int
+}
+
+func newFakeTus(t testing.TB, dropEvery int) (*fakeTus, *httptest.Server) {
+	t.Helper()
+
+	var (
+		ft  = &fakeTus{t: t, dropEvery: dropEvery, uploads: make(map[string][]byte), lengths: make(map[string]int64)}
+		srv = httptest.NewServer(ft)
+	)
+
+	t.Cleanup(srv.Close)
+
+	return ft, srv
+}
+
+func (ft *fakeTus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	if r.Header.Get("Tus-Resumable") != "1.0.0" {
+		w.WriteHeader(http.StatusPreconditionFailed)
+
+		return
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		var path = fmt.Sprintf("%s%d", r.URL.Path, len(ft.uploads)+1)
+
+		ft.uploads[path], ft.lengths[path] = []byte{}, length
+
+		w.Header().Set("Location", strings.TrimPrefix(path, "/files/")) // relative to the endpoint
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodHead:
+		w.Header().Set("Upload-Offset", strconv.Itoa(len(ft.uploads[r.URL.Path])))
+		w.Header().Set("Upload-Length", strconv.FormatInt(ft.lengths[r.URL.Path], 10))
+	case http.MethodPatch:
+		var data = ft.uploads[r.URL.Path]
+
+		if r.Header.Get("Upload-Offset") != strconv.Itoa(len(data)) {
+			w.WriteHeader(http.StatusConflict)
+
+			return
+		}
+
+		if ft.patches++; ft.dropEvery > 0 && ft.patches%ft.dropEvery == 0 {
+			var half = make([]byte, r.ContentLength/2) //nolint:mnd
+
+			n, _ := io.ReadFull(r.Body, half)
+			ft.uploads[r.URL.Path] = append(data, half[:n]...)
+			ft.dropped++
+
+			dropConnection(ft.t, w)
+
+			return
+		}
+
+		chunk, err := io.ReadAll(r.Body)
+		if err != nil {
+			return
+		}
+
+		ft.uploads[r.URL.Path] = append(data, chunk...)
+
+		w.Header().Set("Upload-Offset", strconv.Itoa(len(ft.uploads[r.URL.Path])))
+		w.Header().Set("Upload-Expires", time.Now().Add(24*time.Hour).UTC().Format(http.TimeFormat))
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodDelete:
+		delete(ft.uploads, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func TestTus_Upload(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ft, srv  = newFakeTus(t, 2)
+		content  = bytes.Repeat([]byte("video"), 100_000) // ~8 chunks
+		lastSent int64
+	)
+
+	var tus = filestorage.NewTus(srv.URL+"/files/",
+		filestorage.WithHTTPClient(srv.Client()),
+		filestorage.WithChunkPolicy(testChunkPolicy()),
+	)
+
+	obj, err := tus.Upload(context.Background(), bytes.NewReader(content), "video.mp4", func(sent, total int64) {
+		if total != int64(len(content)) {
+			t.Errorf("unexpected total size: %d", total)
+		}
+
+		lastSent = sent
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if obj.URL != srv.URL+"/files/1" || obj.ID != obj.URL || !obj.SelfExpiring || obj.ExpiresAt.IsZero() {
+		t.Errorf("unexpected object: %+v", obj)
+	}
+
+	if lastSent != int64(len(content)) {
+		t.Errorf("unexpected progress: %d bytes sent", lastSent)
+	}
+
+	ft.mu.Lock()
+
+	if ft.dropped == 0 {
+		t.Error("expected some connections to be dropped")
+	}
+
+	if !bytes.Equal(ft.uploads["/files/1"], content) {
+		t.Errorf("the uploaded content differs: %d bytes received, %d sent", len(ft.uploads["/files/1"]), len(content))
+	}
+
+	ft.mu.Unlock()
+
+	if err = tus.Delete(context.Background(), obj.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	ft.mu.Lock()
+	defer ft.mu.Unlock()
+
+	if _, ok := ft.uploads["/files/1"]; ok {
+		t.Error("the upload should be deleted")
+	}
+}
+
+func TestTus_Upload_NotFound(t *testing.T) {
+	t.Parallel()
+
+	var srv = httptest.NewServer(http.NotFoundHandler())
+
+	t.Cleanup(srv.Close)
+
+	var calls int
+
+	_, err := filestorage.NewTus(srv.URL+"/files/",
+		filestorage.WithHTTPClient(&http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			calls++
+
+			return http.DefaultTransport.RoundTrip(r)
+		})}),
+		filestorage.WithChunkPolicy(testChunkPolicy()),
+	).Upload(context.Background(), bytes.NewReader([]byte("video")), "video.mp4", nil)
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected the not found error, got %v", err)
+	}
+
+	if calls != 1 {
+		t.Errorf("the client errors should not be retried, got %d requests", calls)
+	}
+}
+
+// roundTripFunc is an adapter to use a function as the HTTP transport.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
@@ -0,0 +1,232 @@
+package filestorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Uploader uploads the files to the file hosting (or storage). The progress callback (optional) is called with
+	// the number of the bytes sent so far and the file size. The uploaded files can be deleted by their IDs.
+	Uploader interface {
+		// Name identifies the storage (e.g., "s3://bucket/prefix", without the credentials), so the stored IDs
+		// can be checked to belong to it.
+		Name() string
+		Upload(ctx context.Context, r io.ReadSeeker, filename string, progress func(sent, total int64)) (*Object, error)
+		// Delete deletes the uploaded file by its ID (deleting the missing file is not an error).
+		Delete(ctx context.Context, id string) error
+	}
+
+	// Object describes the uploaded file.
+	Object struct {
+		URL          string    // public URL of the file
+		ID           string    // storage-specific ID of the file (bin ID, object key or upload URL), see Delete
+		ExpiresAt    time.Time // when the link expires (zero, if unknown)
+		SelfExpiring bool      // the storage deletes the file itself, when it expires
+	}
+
+	// options holds configuration for the uploaders, created with Open (or NewTus, NewS3).
+	options struct {
+		client *http.Client
+		policy ChunkPolicy
+	}
+
+	// Option defines a functional option type for customizing the uploaders.
+	Option func(*options)
+)
+
+// WithHTTPClient sets the HTTP client for the upload requests (e.g., to use a proxy). For the chunked uploads, the
+// client timeout limits a single chunk upload.
+func WithHTTPClient(c *http.Client) Option { return func(o *options) { o.client = c } }
+
+// WithChunkPolicy sets the chunk size and the retries of the chunked uploads.
+func WithChunkPolicy(p ChunkPolicy) Option { return func(o *options) { o.policy = p } }
+
+// Apply sets default values and applies user-provided functional options.
+func (o options) Apply(opts ...Option) options {
+	o.policy = DefaultChunkPolicy()
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.client == nil {
+		o.client = &http.Client{
+			Timeout:   10 * time.Minute,                         //nolint:mnd // per chunk, should be enough
+			Transport: &http.Transport{ForceAttemptHTTP2: true}, // use HTTP/2 (why not?)
+		}
+	}
+
+	return o
+}
+
+// FileBin uploads the files to filebin.net (or the self-hosted filebin server) with a single request, a bin per file
+// (see UploadToFileBin). The bins are deleted by the server, when they expire.
+type FileBin struct{ opts []FileBinOption }
+
+var _ Uploader = (*FileBin)(nil) // ensure the interface is implemented
+
+// NewFileBin creates a new filebin uploader.
+func NewFileBin(opts ...FileBinOption) *FileBin { return &FileBin{opts: opts} }
+
+// Name implements the Uploader interface.
+func (f *FileBin) Name() string {
+	if o := (fileBinOptions{}).Apply(f.opts...); o.baseURL != fileBinURL {
+		return "filebin+" + o.baseURL
+	}
+
+	return "filebin"
+}
+
+// Upload implements the Uploader interface. The bin ID is the ID of the uploaded file.
+func (f *FileBin) Upload(
+	ctx context.Context,
+	r io.ReadSeeker,
+	filename string,
+	progress func(sent, total int64),
+) (*Object, error) {
+	return uploadToFileBin(ctx, r, filename, fileBinOptions{}.Apply(append(slices.Clip(f.opts),
+		WithFileBinProgress(progress),
+	)...))
+}
+
+// Delete implements the Uploader interface. The whole bin is deleted.
+func (f *FileBin) Delete(ctx context.Context, binID string) error {
+	var o = fileBinOptions{}.Apply(f.opts...)
+
+	if err := deleteRequest(ctx, o.client, fmt.Sprintf("%s/%s", o.baseURL, url.PathEscape(binID)), nil); err != nil {
+		return fmt.Errorf("filebin.net: failed to delete the bin: %w", err)
+	}
+
+	return nil
+}
+
+// Open creates the uploader by the target specification:
+//
+//   - "filebin" - filebin.net; "filebin+https://host" - self-hosted filebin server (single request uploads);
+//   - "tus+https://host/files/" - tus server (resumable chunked uploads, see Tus);
+//   - "s3://key:secret@bucket/prefix?region=...&endpoint=...&path-style=true&link-expiry=48h" - S3-compatible
+//     storage (multipart uploads with the presigned download links, see S3).
+func Open(target string, opts ...Option) (Uploader, error) {
+	switch {
+	case target == "", target == "filebin":
+		return NewFileBin(WithFileBinHTTPClient(options{}.Apply(opts...).client)), nil
+	case strings.HasPrefix(target, "filebin+"):
+		var baseURL = strings.TrimPrefix(target, "filebin+")
+
+		if err := validateHTTPURL(baseURL); err != nil {
+			return nil, fmt.Errorf("invalid filebin URL: %w", err)
+		}
+
+		return NewFileBin(WithFileBinHTTPClient(options{}.Apply(opts...).client), WithFileBinURL(baseURL)), nil
+	case strings.HasPrefix(target, "tus+"):
+		var endpoint = strings.TrimPrefix(target, "tus+")
+
+		if err := validateHTTPURL(endpoint); err != nil {
+			return nil, fmt.Errorf("invalid tus endpoint: %w", err)
+		}
+
+		return NewTus(endpoint, opts...), nil
+	case strings.HasPrefix(target, "s3://"):
+		cfg, err := parseS3Target(target)
+		if err != nil {
+			return nil, fmt.Errorf("invalid S3 target: %w", err)
+		}
+
+		return NewS3(cfg, opts...)
+	}
+
+	return nil, fmt.Errorf("unsupported upload target: %q", target)
+}
+
+// deleteRequest sends the DELETE request, the missing file (404 or 410) is not an error.
+func deleteRequest(ctx context.Context, client *http.Client, fileURL string, header http.Header) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, fileURL, http.NoBody)
+	if err != nil {
+		return err
+	}
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+	case resp.StatusCode == http.StatusNotFound, resp.StatusCode == http.StatusGone:
+	default:
+		return newStatusError(resp)
+	}
+
+	return nil
+}
+
+// validateHTTPURL checks that the URL is an absolute HTTP(S) one.
+func validateHTTPURL(s string) error {
+	u, err := url.ParseRequestURI(s)
+	if err != nil {
+		return err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("scheme must be http or https")
+	}
+
+	if u.Host == "" {
+		return errors.New("host is missing")
+	}
+
+	return nil
+}
+
+// parseS3Target parses the "s3://key:secret@bucket/prefix?region=...&endpoint=...&path-style=...&link-expiry=..."
+// target.
+func parseS3Target(target string) (S3Config, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return S3Config{}, err
+	}
+
+	var (
+		q   = u.Query()
+		cfg = S3Config{
+			Bucket:   u.Host,
+			Prefix:   strings.Trim(u.Path, "/"),
+			Region:   q.Get("region"),
+			Endpoint: q.Get("endpoint"),
+		}
+	)
+
+	if u.User != nil {
+		cfg.AccessKey = u.User.Username()
+		cfg.SecretKey, _ = u.User.Password()
+	}
+
+	if v := q.Get("path-style"); v != "" {
+		if cfg.PathStyle, err = strconv.ParseBool(v); err != nil {
+			return S3Config{}, fmt.Errorf("invalid path-style value: %w", err)
+		}
+	}
+
+	if v := q.Get("link-expiry"); v != "" {
+		if cfg.LinkExpiry, err = time.ParseDuration(v); err != nil {
+			return S3Config{}, fmt.Errorf("invalid link-expiry value: %w", err)
+		}
+	}
+
+	return cfg, nil
+}
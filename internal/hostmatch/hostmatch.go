@@ -0,0 +1,43 @@
+// Package hostmatch provides matching of host names against domain patterns.
+package hostmatch
+
+import (
+	"path"
+	"strings"
+)
+
+// Match reports whether the host matches the pattern. The pattern can be:
+//
+//   - a domain name (e.g., "youtube.com") - matches the domain itself and all its subdomains
+//   - a glob pattern (e.g., "*.youtube.com", "youtu*.be") - matches using the shell-like rules (see path.Match)
+//   - "*" - matches any host
+//
+// The comparison is case-insensitive, and a trailing dot in the host is ignored.
+func Match(pattern, host string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+
+	if pattern == "" || host == "" {
+		return false
+	}
+
+	if strings.ContainsAny(pattern, "*?[") {
+		ok, err := path.Match(pattern, host)
+
+		return err == nil && ok
+	}
+
+	return host == pattern || strings.HasSuffix(host, "."+pattern)
+}
+
+// Specificity returns a score, that can be used to order the patterns from the most specific to the least
+// specific one (the higher the score, the more specific the pattern).
+func Specificity(pattern string) int {
+	var score = len(pattern)
+
+	if strings.ContainsAny(pattern, "*?[") {
+		score -= 1000 //nolint:mnd // patterns with wildcards are less specific than the plain domain names
+	}
+
+	return score
+}
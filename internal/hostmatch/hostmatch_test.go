@@ -0,0 +1,56 @@
+package hostmatch_test
+
+import (
+	"sort"
+	"testing"
+
+	"gh.tarampamp.am/video-dl-bot/internal/hostmatch"
+)
+
+func TestMatch(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		givePattern, giveHost string
+		want                  bool
+	}{
+		"exact domain":               {givePattern: "youtube.com", giveHost: "youtube.com", want: true},
+		"subdomain":                  {givePattern: "youtube.com", giveHost: "www.youtube.com", want: true},
+		"deep subdomain":             {givePattern: "youtube.com", giveHost: "m.www.youtube.com", want: true},
+		"case insensitive":           {givePattern: "YouTube.com", giveHost: "WWW.youtube.COM", want: true},
+		"trailing dot":               {givePattern: "youtube.com", giveHost: "youtube.com.", want: true},
+		"other domain":               {givePattern: "youtube.com", giveHost: "notyoutube.com", want: false},
+		"parent domain":              {givePattern: "www.youtube.com", giveHost: "youtube.com", want: false},
+		"glob subdomains only":       {givePattern: "*.youtube.com", giveHost: "www.youtube.com", want: true},
+		"glob does not match parent": {givePattern: "*.youtube.com", giveHost: "youtube.com", want: false},
+		"glob in the middle":         {givePattern: "youtu*.be", giveHost: "youtu.be", want: true},
+		"any":                        {givePattern: "*", giveHost: "example.com", want: true},
+		"broken glob":                {givePattern: "[example.com", giveHost: "example.com", want: false},
+		"empty pattern":              {givePattern: "", giveHost: "example.com", want: false},
+		"empty host":                 {givePattern: "example.com", giveHost: "", want: false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := hostmatch.Match(tc.givePattern, tc.giveHost); got != tc.want {
+				t.Errorf("Match(%q, %q) = %v, want %v", tc.givePattern, tc.giveHost, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSpecificity(t *testing.T) {
+	t.Parallel()
+
+	var patterns = []string{"*", "youtube.com", "*.youtube.com", "music.youtube.com", "*.com"}
+
+	sort.SliceStable(patterns, func(i, j int) bool {
+		return hostmatch.Specificity(patterns[i]) > hostmatch.Specificity(patterns[j])
+	})
+
+	for i, want := range []string{"music.youtube.com", "youtube.com", "*.youtube.com", "*.com", "*"} {
+		if patterns[i] != want {
+			t.Errorf("position %d: got %q, want %q", i, patterns[i], want)
+		}
+	}
+}
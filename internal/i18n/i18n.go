@@ -0,0 +1,211 @@
+// Package i18n provides the translations of the bot replies. The message catalogs (one YAML file per language,
+// with the message keys mapped to the texts) are embedded into the binary.
+//
+// Messages may contain placeholders like "{name}", that are replaced with the given values. The messages with the
+// "-md" key suffix are MarkdownV2 formatted, and the values are escaped for them, so the user input cannot break
+// the formatting.
+package i18n
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// markdownSuffix is the key suffix of the MarkdownV2 formatted messages.
+const markdownSuffix = "-md"
+
+// DefaultLanguage is the language used when the user's language is unknown or not supported. Its catalog is the
+// reference one - other catalogs must have the same keys.
+const DefaultLanguage = "en"
+
+//go:embed locales/*.yaml
+var locales embed.FS
+
+// placeholderRe matches the placeholders in the messages (e.g., "{name}").
+var placeholderRe = regexp.MustCompile(`\{([a-z0-9_]+)\}`)
+
+type (
+	// Bundle contains the message catalogs of all the supported languages.
+	Bundle struct {
+		catalogs  map[string]map[string]string // language code -> message key -> text
+		templates templates                    // operator overrides (optional, see LoadTemplates)
+	}
+
+	// Localizer renders the messages in a specific language (falling back to the default one).
+	Localizer struct {
+		lang              string
+		catalog, fallback map[string]string
+		templates         templates
+	}
+
+	// Vars contains the placeholder values (the key is a placeholder name without braces).
+	Vars map[string]any
+)
+
+// New loads the embedded message catalogs.
+func New() (*Bundle, error) { return Load(locales, "locales") }
+
+// Load loads the message catalogs from the directory (the file name without the extension is a language code,
+// e.g., "en.yaml").
+func Load(fsys fs.FS, dir string) (*Bundle, error) {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+
+	var b = Bundle{catalogs: make(map[string]map[string]string, len(files))}
+
+	for _, file := range files {
+		data, rErr := fs.ReadFile(fsys, file)
+		if rErr != nil {
+			return nil, rErr
+		}
+
+		var catalog map[string]string
+
+		if uErr := yaml.Unmarshal(data, &catalog); uErr != nil {
+			return nil, fmt.Errorf("failed to parse the message catalog %s: %w", file, uErr)
+		}
+
+		b.catalogs[strings.TrimSuffix(path.Base(file), ".yaml")] = catalog
+	}
+
+	if _, ok := b.catalogs[DefaultLanguage]; !ok {
+		return nil, fmt.Errorf("the default language (%s) catalog is missing", DefaultLanguage)
+	}
+
+	return &b, nil
+}
+
+// Languages returns the codes of the supported languages (sorted).
+func (b *Bundle) Languages() []string {
+	var list = make([]string, 0, len(b.catalogs))
+
+	for lang := range b.catalogs {
+		list = append(list, lang)
+	}
+
+	slices.Sort(list)
+
+	return list
+}
+
+// Keys returns the keys of the messages in the language catalog (sorted).
+func (b *Bundle) Keys(lang string) []string {
+	var list = make([]string, 0, len(b.catalogs[lang]))
+
+	for key := range b.catalogs[lang] {
+		list = append(list, key)
+	}
+
+	slices.Sort(list)
+
+	return list
+}
+
+// Match returns the supported language for the IETF language tag (e.g., "ru-RU" -> "ru"), or the default
+// language, if the tag is empty or the language is not supported.
+func (b *Bundle) Match(tag string) string {
+	var lang, _, _ = strings.Cut(strings.ToLower(strings.ReplaceAll(tag, "_", "-")), "-")
+
+	if _, ok := b.catalogs[lang]; ok {
+		return lang
+	}
+
+	return DefaultLanguage
+}
+
+// Localizer returns the localizer for the language (see Match).
+func (b *Bundle) Localizer(tag string) Localizer {
+	var lang = b.Match(tag)
+
+	return Localizer{
+		lang:      lang,
+		catalog:   b.catalogs[lang],
+		fallback:  b.catalogs[DefaultLanguage],
+		templates: b.templates,
+	}
+}
+
+// Language returns the language code of the localizer.
+func (l Localizer) Language() string { return l.lang }
+
+// T returns the message with the placeholders replaced by the values. For the MarkdownV2 messages (see IsMarkdown)
+// the values are escaped. If the message is missing in the catalog, the default language one is used (and the key
+// itself, as the last resort). The message template, if it's loaded for the key, takes precedence over the catalog.
+func (l Localizer) T(key string, vars Vars) string {
+	if text, ok := l.templates.execute(l.lang, key, vars); ok {
+		return text
+	}
+
+	if IsMarkdown(key) {
+		return render(l.lookup(key), vars, func(v ...any) string { return EscapeMarkdown(fmt.Sprint(v...)) })
+	}
+
+	return render(l.lookup(key), vars, fmt.Sprint)
+}
+
+// lookup returns the message text by the key.
+func (l Localizer) lookup(key string) string {
+	if text, ok := l.catalog[key]; ok {
+		return text
+	}
+
+	if text, ok := l.fallback[key]; ok {
+		return text
+	}
+
+	return key
+}
+
+// render replaces the placeholders in the text with the formatted values (unknown placeholders are kept).
+func render(text string, vars Vars, format func(...any) string) string {
+	if len(vars) == 0 {
+		return text
+	}
+
+	return placeholderRe.ReplaceAllStringFunc(text, func(match string) string {
+		if v, ok := vars[match[1:len(match)-1]]; ok {
+			return format(v)
+		}
+
+		return match
+	})
+}
+
+// IsMarkdown reports whether the message with the key is MarkdownV2 formatted.
+func IsMarkdown(key string) bool { return strings.HasSuffix(key, markdownSuffix) }
+
+// Placeholders returns the names of the placeholders used in the text (sorted, without duplicates).
+func Placeholders(text string) []string {
+	var list []string
+
+	for _, m := range placeholderRe.FindAllStringSubmatch(text, -1) {
+		list = append(list, m[1])
+	}
+
+	slices.Sort(list)
+
+	return slices.Compact(list)
+}
+
+// markdownEscaper escapes the MarkdownV2 special characters.
+var markdownEscaper = func() *strings.Replacer { //nolint:gochecknoglobals
+	var pairs []string
+
+	for _, c := range `\_*[]()~` + "`" + `>#+-=|{}.!` {
+		pairs = append(pairs, string(c), `\`+string(c))
+	}
+
+	return strings.NewReplacer(pairs...)
+}()
+
+// EscapeMarkdown escapes the MarkdownV2 special characters in the string, so it's rendered as is.
+func EscapeMarkdown(s string) string { return markdownEscaper.Replace(s) }
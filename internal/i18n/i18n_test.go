@@ -0,0 +1,176 @@
+package i18n_test
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"testing"
+
+	"gh.tarampamp.am/video-dl-bot/internal/i18n"
+)
+
+func TestCatalogsCompleteness(t *testing.T) {
+	t.Parallel()
+
+	bundle, err := i18n.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		langs = bundle.Languages()
+		ref   = bundle.Localizer(i18n.DefaultLanguage)
+		keys  = bundle.Keys(i18n.DefaultLanguage)
+	)
+
+	for _, want := range []string{"en", "ru"} {
+		if !slices.Contains(langs, want) {
+			t.Errorf("the %q catalog is missing", want)
+		}
+	}
+
+	for _, lang := range langs {
+		t.Run(lang, func(t *testing.T) {
+			t.Parallel()
+
+			if got := bundle.Keys(lang); !slices.Equal(got, keys) {
+				t.Fatalf("the keys differ from the default catalog:\nwant: %v\ngot:  %v", keys, got)
+			}
+
+			var l = bundle.Localizer(lang)
+
+			for _, key := range keys {
+				if l.T(key, nil) == "" {
+					t.Errorf("the %q message is empty", key)
+				}
+
+				if want, got := i18n.Placeholders(ref.T(key, nil)), i18n.Placeholders(l.T(key, nil)); !slices.Equal(want, got) {
+					t.Errorf("the %q message placeholders differ: want %v, got %v", key, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCatalogsHaveUsedKeys(t *testing.T) {
+	t.Parallel()
+
+	bundle, err := i18n.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the keys are passed to the localizer as the literals, or as the usage keys of the commands with the link
+	var keyRe = regexp.MustCompile(`\.T\("([^"]+)"|probeCommandLink\([^)]*"([^"]+)"\)`)
+
+	files, err := filepath.Glob(filepath.Join("..", "bot", "*.go"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no bot sources found: %v", err)
+	}
+
+	var used = make(map[string]string) // key -> the file, where it's used
+
+	for _, file := range files {
+		src, rErr := os.ReadFile(file)
+		if rErr != nil {
+			t.Fatal(rErr)
+		}
+
+		for _, m := range keyRe.FindAllStringSubmatch(string(src), -1) {
+			used[m[1]+m[2]] = filepath.Base(file)
+		}
+	}
+
+	for _, lang := range []string{"en", "ru"} {
+		var keys = bundle.Keys(lang)
+
+		for key, file := range used {
+			if !slices.Contains(keys, key) {
+				t.Errorf("the %q key (used in %s) is missing in the %q catalog", key, file, lang)
+			}
+		}
+	}
+}
+
+func TestBundle_Match(t *testing.T) {
+	t.Parallel()
+
+	bundle, err := i18n.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for tag, want := range map[string]string{
+		"":      "en",
+		"en":    "en",
+		"ru":    "ru",
+		"ru-RU": "ru",
+		"RU_ru": "ru",
+		"xx":    "en",
+	} {
+		t.Run(tag, func(t *testing.T) {
+			t.Parallel()
+
+			if got := bundle.Match(tag); got != want {
+				t.Errorf("want %q, got %q", want, got)
+			}
+		})
+	}
+}
+
+func TestLocalizer_T(t *testing.T) {
+	t.Parallel()
+
+	bundle, err := i18n.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var l = bundle.Localizer("en")
+
+	for name, tc := range map[string]struct {
+		giveKey  string
+		giveVars i18n.Vars
+		want     string
+	}{
+		"plain text": {
+			giveKey:  "send-failed",
+			giveVars: i18n.Vars{"size": "1.00 MB", "error": "foo_bar (baz)"},
+			want:     "❌ Failed to send video (1.00 MB): foo_bar (baz)",
+		},
+		"markdown": {
+			giveKey:  "hosting-link-md",
+			giveVars: i18n.Vars{"url": "https://example.com/v?a=(1)", "expires": "2026-01-02 03:04 UTC"},
+			want: `[Your video](https://example\.com/v?a\=\(1\)) is ready for download _\(the link expires ` +
+				`2026\-01\-02 03:04 UTC, see /links\)_:`,
+		},
+		"unknown placeholder": {
+			giveKey:  "history-page",
+			giveVars: i18n.Vars{"page": 1},
+			want:     "📜 Your downloads (page 1 of {pages}):",
+		},
+		"unknown key": {
+			giveKey: "foo-bar",
+			want:    "foo-bar",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := l.T(tc.giveKey, tc.giveVars); got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestEscapeMarkdown(t *testing.T) {
+	t.Parallel()
+
+	const give, want = "a_b*c[d](e)~f`g>h#i+j-k=l|m{n}o.p!q\\r", "a\\_b\\*c\\[d\\]\\(e\\)\\~f\\`g\\>h\\#i\\+j\\-k\\=l\\|m\\{n\\}o\\.p\\!q\\\\r" //nolint:lll
+
+	if got := i18n.EscapeMarkdown(give); got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
@@ -0,0 +1,159 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateExt is the extension of the message template files.
+const templateExt = ".tmpl"
+
+// templates contains the message templates (language code, or empty string for all languages -> message key ->
+// template).
+type templates map[string]map[string]*template.Template
+
+// TemplateFuncs returns the helper functions available in the message templates.
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"md":       func(v any) string { return EscapeMarkdown(fmt.Sprint(v)) },
+		"html":     func(v any) string { return html.EscapeString(fmt.Sprint(v)) },
+		"size":     FormatSize,
+		"duration": FormatDuration,
+	}
+}
+
+// LoadTemplates loads the message templates (Go text/template files), that override the catalog messages. The
+// template file is named after the message key ("start.tmpl") and overrides it for all the languages, or it's put
+// to the language subdirectory ("ru/start.tmpl") to override it for that language only.
+//
+// The placeholder values are available as the template fields ("{{ .name }}"), and they are NOT escaped - use the
+// helper functions (see TemplateFuncs) for that. All the templates are validated, and the errors are reported by
+// the template name.
+func (b *Bundle) LoadTemplates(fsys fs.FS) error {
+	var (
+		keys = b.Keys(DefaultLanguage)
+		set  = make(templates)
+		errs []error
+	)
+
+	walkErr := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		switch {
+		case err != nil:
+			return err
+		case d.IsDir() || path.Ext(name) != templateExt:
+			return nil
+		}
+
+		var (
+			lang, file = path.Split(name)
+			key        = strings.TrimSuffix(file, templateExt)
+		)
+
+		if lang = strings.TrimSuffix(lang, "/"); lang != "" && !slices.Contains(b.Languages(), lang) {
+			errs = append(errs, fmt.Errorf("template %s: unsupported language %q", name, lang))
+
+			return nil
+		}
+
+		if !slices.Contains(keys, key) {
+			errs = append(errs, fmt.Errorf("template %s: unknown message %q", name, key))
+
+			return nil
+		}
+
+		data, rErr := fs.ReadFile(fsys, name)
+		if rErr != nil {
+			errs = append(errs, fmt.Errorf("template %s: %w", name, rErr))
+
+			return nil
+		}
+
+		tpl, pErr := template.New(name).Funcs(TemplateFuncs()).Option("missingkey=error").Parse(string(data))
+		if pErr != nil {
+			errs = append(errs, fmt.Errorf("template %s: %w", name, pErr))
+
+			return nil
+		}
+
+		if set[lang] == nil {
+			set[lang] = make(map[string]*template.Template)
+		}
+
+		set[lang][key] = tpl
+
+		return nil
+	})
+	if walkErr != nil {
+		return walkErr
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	b.templates = set
+
+	return nil
+}
+
+// execute renders the template for the message key (the language specific one is preferred), and reports whether
+// it was rendered.
+func (t templates) execute(lang, key string, vars Vars) (string, bool) {
+	var tpl = t[lang][key]
+	if tpl == nil {
+		if tpl = t[""][key]; tpl == nil {
+			return "", false
+		}
+	}
+
+	var out strings.Builder
+
+	if err := tpl.Execute(&out, vars); err != nil {
+		return "", false // fall back to the catalog message
+	}
+
+	return strings.TrimSpace(out.String()), true
+}
+
+// FormatSize returns the human-readable size (e.g., "1.50 MB") of the given number of bytes.
+func FormatSize(bytes int64) string {
+	const unit = 1024
+
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	var (
+		value = float64(bytes) / unit
+		units = []string{"KB", "MB", "GB", "TB"}
+		i     int
+	)
+
+	for ; value >= unit && i < len(units)-1; i++ {
+		value /= unit
+	}
+
+	return fmt.Sprintf("%.2f %s", value, units[i])
+}
+
+// FormatDuration returns the duration in the "h:mm:ss" (or "m:ss" if it's less than an hour) format.
+func FormatDuration(d time.Duration) string {
+	var s = int64(d.Round(time.Second) / time.Second)
+
+	if s < 0 {
+		return "-" + FormatDuration(-d)
+	}
+
+	if s >= 3600 { //nolint:mnd
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s%3600/60, s%60) //nolint:mnd
+	}
+
+	return fmt.Sprintf("%d:%02d", s/60, s%60) //nolint:mnd
+}
@@ -0,0 +1,123 @@
+package i18n_test
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"gh.tarampamp.am/video-dl-bot/internal/i18n"
+)
+
+func TestBundle_LoadTemplates(t *testing.T) {
+	t.Parallel()
+
+	bundle, err := i18n.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = bundle.LoadTemplates(fstest.MapFS{
+		"start.tmpl":           {Data: []byte("Hi, {{ .name }}! Support: @example\n")},
+		"ru/start.tmpl":        {Data: []byte("Привет, {{ .name }}!")},
+		"hosting-link-md.tmpl": {Data: []byte(`[Video]({{ md .url }}) \({{ size .bytes }}\)`)},
+		"send-failed.tmpl":     {Data: []byte("{{ .foo }}")}, // missing key - the catalog message is used
+		"README.md":            {Data: []byte("ignored")},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var en, ru = bundle.Localizer("en"), bundle.Localizer("ru")
+
+	for name, tc := range map[string]struct {
+		got, want string
+	}{
+		"all languages":     {en.T("start", i18n.Vars{"name": "Bob"}), "Hi, Bob! Support: @example"},
+		"language specific": {ru.T("start", i18n.Vars{"name": "Bob"}), "Привет, Bob!"},
+		"helpers": {
+			en.T("hosting-link-md", i18n.Vars{"url": "https://example.com/a_b", "bytes": int64(1536)}),
+			`[Video](https://example\.com/a\_b) \(1.50 KB\)`,
+		},
+		"execution error": {
+			en.T("send-failed", i18n.Vars{"size": "1 MB", "error": "oops"}),
+			"❌ Failed to send video (1 MB): oops",
+		},
+		"not overridden": {en.T("history-empty", nil), "📭 Your download history is empty"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			if tc.got != tc.want {
+				t.Errorf("want %q, got %q", tc.want, tc.got)
+			}
+		})
+	}
+}
+
+func TestBundle_LoadTemplates_Errors(t *testing.T) {
+	t.Parallel()
+
+	bundle, err := i18n.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = bundle.LoadTemplates(fstest.MapFS{
+		"start.tmpl":   {Data: []byte("{{ .name ")},
+		"foo.tmpl":     {Data: []byte("foo")},
+		"xx/test.tmpl": {Data: []byte("bar")},
+		"test.tmpl":    {Data: []byte("{{ unknown .name }}")},
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	for _, want := range []string{
+		"template start.tmpl:",
+		`template foo.tmpl: unknown message "foo"`,
+		`template xx/test.tmpl: unsupported language "xx"`,
+		"template test.tmpl:",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected the error to contain %q, got: %v", want, err)
+		}
+	}
+
+	if got := bundle.Localizer("en").T("foo-bar", nil); got != "foo-bar" {
+		t.Errorf("expected the templates not to be applied, got %q", got)
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	t.Parallel()
+
+	for give, want := range map[int64]string{
+		0:       "0 B",
+		1023:    "1023 B",
+		1024:    "1.00 KB",
+		1572864: "1.50 MB",
+		1 << 40: "1.00 TB",
+		1 << 50: "1024.00 TB",
+	} {
+		if got := i18n.FormatSize(give); got != want {
+			t.Errorf("FormatSize(%d): want %q, got %q", give, want, got)
+		}
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	t.Parallel()
+
+	for give, want := range map[time.Duration]string{
+		0:                                   "0:00",
+		59 * time.Second:                    "0:59",
+		61*time.Minute + 5*time.Second:      "1:01:05",
+		-90 * time.Second:                   "-1:30",
+		1500 * time.Millisecond:             "0:02",
+		25*time.Hour + 100*time.Millisecond: "25:00:00",
+	} {
+		if got := i18n.FormatDuration(give); got != want {
+			t.Errorf("FormatDuration(%s): want %q, got %q", give, want, got)
+		}
+	}
+}
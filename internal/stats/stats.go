@@ -0,0 +1,244 @@
+package stats
+
+import (
+	"cmp"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// Window is the period the jobs are kept for (older jobs are counted in the totals only).
+	Window = 7 * 24 * time.Hour
+
+	// maxJobs limits the number of the kept jobs (the oldest ones are dropped first).
+	maxJobs = 100_000
+)
+
+// Job outcomes (the same as in the audit log).
+const (
+	OutcomeSuccess  = "success"
+	OutcomeRejected = "rejected"
+	OutcomeFailed   = "failed"
+	OutcomeCanceled = "canceled"
+)
+
+type (
+	// Collector collects the usage statistics in memory (they are reset on restart). It's safe for concurrent use.
+	Collector struct {
+		startedAt time.Time
+		now       func() time.Time
+
+		queued   atomic.Int64 // jobs waiting for a free download slot
+		inFlight atomic.Int64 // jobs being processed (downloading or uploading)
+
+		mu          sync.Mutex
+		jobs        []Job // finished jobs within the window (ordered by time)
+		bytesServed int64 // total size of the sent files (since start)
+		links       int64 // total number of the links to the external storage (since start)
+		total       int64 // total number of the finished jobs (since start)
+	}
+
+	// Tracker tracks the state of a single job (see Collector.Track).
+	Tracker struct {
+		c                 *Collector
+		started, finished atomic.Bool
+	}
+
+	// Job describes a finished job.
+	Job struct {
+		Time       time.Time // when the job was finished (set by the Collector)
+		UserID     int64
+		Extractor  string // yt-dlp extractor (e.g., "youtube")
+		Outcome    string // see the Outcome* constants
+		ErrorClass string // for the failed jobs
+		Bytes      int64  // size of the sent file
+		Link       bool   // the file was uploaded to the external storage
+	}
+
+	// Count is a named counter.
+	Count struct {
+		Name  string
+		Count int
+	}
+
+	// UserCount is a per-user counter.
+	UserCount struct {
+		UserID int64
+		Count  int
+	}
+
+	// Snapshot is the statistics at the moment.
+	Snapshot struct {
+		Uptime          time.Duration
+		Queued          int         // jobs waiting for a free download slot
+		InFlight        int         // jobs being processed
+		TotalJobs       int64       // finished jobs since start
+		BytesServed     int64       // total size of the sent files since start
+		Links           int64       // links to the external storage since start
+		Links7d         int         // links to the external storage within the window
+		Downloads24h    []Count     // successful downloads by extractor, within the last 24 hours
+		Downloads7d     []Count     // successful downloads by extractor, within the window
+		TopUsers        []UserCount // users with the most successful downloads, within the window
+		Failures7d      int         // failed jobs within the window
+		FailureRate     float64     // failed / (successful + failed) within the window (0..1)
+		FailuresByClass []Count     // failed jobs by error class, within the window
+	}
+
+	// Option is a function that configures the Collector.
+	Option func(*Collector)
+)
+
+// WithClock sets the clock (useful for testing).
+func WithClock(now func() time.Time) Option { return func(c *Collector) { c.now = now } }
+
+// New creates a new statistics collector.
+func New(opts ...Option) *Collector {
+	var c = Collector{now: time.Now}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	c.startedAt = c.now()
+
+	return &c
+}
+
+// Track registers a new job as queued (waiting for a free download slot). Call Tracker.Start when the job gets
+// the slot, and Tracker.Finish when it's done.
+func (c *Collector) Track() *Tracker {
+	c.queued.Add(1)
+
+	return &Tracker{c: c}
+}
+
+// Start marks the job as being processed.
+func (t *Tracker) Start() {
+	if t.started.CompareAndSwap(false, true) {
+		t.c.queued.Add(-1)
+		t.c.inFlight.Add(1)
+	}
+}
+
+// Finish records the finished job (the subsequent calls are ignored).
+func (t *Tracker) Finish(j Job) {
+	if !t.finished.CompareAndSwap(false, true) {
+		return
+	}
+
+	if t.started.Load() {
+		t.c.inFlight.Add(-1)
+	} else {
+		t.c.queued.Add(-1)
+	}
+
+	t.c.record(j)
+}
+
+// record records the finished job.
+func (c *Collector) record(j Job) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	j.Time = c.now() // set under the lock, so the jobs are ordered by time
+
+	c.total++
+	c.bytesServed += j.Bytes
+
+	if j.Link {
+		c.links++
+	}
+
+	c.jobs = append(c.jobs, j)
+	c.prune()
+}
+
+// Snapshot returns the current statistics.
+func (c *Collector) Snapshot() Snapshot {
+	var now = c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.prune()
+
+	var (
+		s = Snapshot{
+			Uptime:      now.Sub(c.startedAt),
+			Queued:      int(c.queued.Load()),
+			InFlight:    int(c.inFlight.Load()),
+			TotalJobs:   c.total,
+			BytesServed: c.bytesServed,
+			Links:       c.links,
+		}
+		d24h, d7d, classes = make(map[string]int), make(map[string]int), make(map[string]int)
+		users              = make(map[int64]int)
+		successes          int
+	)
+
+	for _, j := range c.jobs {
+		if j.Link {
+			s.Links7d++
+		}
+
+		switch j.Outcome {
+		case OutcomeSuccess:
+			successes++
+			d7d[j.Extractor]++
+			users[j.UserID]++
+
+			if now.Sub(j.Time) < 24*time.Hour { //nolint:mnd
+				d24h[j.Extractor]++
+			}
+		case OutcomeFailed:
+			s.Failures7d++
+			classes[j.ErrorClass]++
+		}
+	}
+
+	if total := successes + s.Failures7d; total > 0 {
+		s.FailureRate = float64(s.Failures7d) / float64(total)
+	}
+
+	s.Downloads24h, s.Downloads7d, s.FailuresByClass = counts(d24h), counts(d7d), counts(classes)
+
+	for id, n := range users {
+		s.TopUsers = append(s.TopUsers, UserCount{UserID: id, Count: n})
+	}
+
+	slices.SortFunc(s.TopUsers, func(a, b UserCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.UserID, b.UserID))
+	})
+
+	return s
+}
+
+// prune removes the jobs outside the window (and the oldest ones exceeding the limit).
+func (c *Collector) prune() {
+	var since = c.now().Add(-Window)
+
+	var i, _ = slices.BinarySearchFunc(c.jobs, since, func(j Job, t time.Time) int { return j.Time.Compare(t) })
+
+	i = max(i, len(c.jobs)-maxJobs)
+
+	if i > 0 {
+		c.jobs = slices.Delete(c.jobs, 0, i)
+	}
+}
+
+// counts converts the map into the list of counters, sorted by count (descending) and name.
+func counts(m map[string]int) []Count {
+	var list = make([]Count, 0, len(m))
+
+	for name, n := range m {
+		list = append(list, Count{Name: name, Count: n})
+	}
+
+	slices.SortFunc(list, func(a, b Count) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Name, b.Name))
+	})
+
+	return list
+}
@@ -0,0 +1,109 @@
+package stats_test
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"gh.tarampamp.am/video-dl-bot/internal/stats"
+)
+
+// fakeClock is a manually advanced clock.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func TestCollector(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	var (
+		clock = &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+		c     = stats.New(stats.WithClock(clock.Now))
+	)
+
+	finish := func(j stats.Job) {
+		var tr = c.Track()
+
+		tr.Start()
+		tr.Finish(j)
+		tr.Finish(j) // ignored
+	}
+
+	// an old job, that goes out of the window
+	finish(stats.Job{UserID: 1, Extractor: "vimeo", Outcome: stats.OutcomeSuccess, Bytes: 100})
+
+	clock.Add(6 * 24 * time.Hour)
+
+	finish(stats.Job{UserID: 1, Extractor: "youtube", Outcome: stats.OutcomeSuccess, Bytes: 10, Link: true})
+	finish(stats.Job{UserID: 2, Extractor: "youtube", Outcome: stats.OutcomeFailed, ErrorClass: "transient"})
+
+	clock.Add(2 * 24 * time.Hour)
+
+	finish(stats.Job{UserID: 2, Extractor: "youtube", Outcome: stats.OutcomeSuccess, Bytes: 1})
+	finish(stats.Job{UserID: 2, Extractor: "tiktok", Outcome: stats.OutcomeSuccess, Bytes: 1})
+	finish(stats.Job{UserID: 3, Outcome: stats.OutcomeRejected})
+	finish(stats.Job{UserID: 3, Outcome: stats.OutcomeFailed, ErrorClass: "unsupported"})
+
+	var (
+		queued   = c.Track()
+		inFlight = c.Track()
+	)
+
+	inFlight.Start()
+
+	clock.Add(time.Hour)
+
+	var s = c.Snapshot()
+
+	if s.Uptime != 8*24*time.Hour+time.Hour || s.Queued != 1 || s.InFlight != 1 {
+		t.Errorf("unexpected uptime/queued/in flight: %s/%d/%d", s.Uptime, s.Queued, s.InFlight)
+	}
+
+	if s.TotalJobs != 7 || s.BytesServed != 112 || s.Links != 1 || s.Links7d != 1 {
+		t.Errorf("unexpected totals: %+v", s)
+	}
+
+	if want := []stats.Count{{"tiktok", 1}, {"youtube", 1}}; !reflect.DeepEqual(s.Downloads24h, want) {
+		t.Errorf("unexpected downloads (24h): %v", s.Downloads24h)
+	}
+
+	if want := []stats.Count{{"youtube", 2}, {"tiktok", 1}}; !reflect.DeepEqual(s.Downloads7d, want) {
+		t.Errorf("unexpected downloads (7d): %v", s.Downloads7d)
+	}
+
+	if want := []stats.UserCount{{2, 2}, {1, 1}}; !reflect.DeepEqual(s.TopUsers, want) {
+		t.Errorf("unexpected top users: %v", s.TopUsers)
+	}
+
+	if want := []stats.Count{{"transient", 1}, {"unsupported", 1}}; !reflect.DeepEqual(s.FailuresByClass, want) {
+		t.Errorf("unexpected failures: %v", s.FailuresByClass)
+	}
+
+	if s.Failures7d != 2 || s.FailureRate != 0.4 {
+		t.Errorf("unexpected failure rate: %d/%f", s.Failures7d, s.FailureRate)
+	}
+
+	// finishing the tracked jobs
+	queued.Finish(stats.Job{Outcome: stats.OutcomeCanceled})
+	inFlight.Finish(stats.Job{Outcome: stats.OutcomeCanceled})
+
+	if s = c.Snapshot(); s.Queued != 0 || s.InFlight != 0 || s.TotalJobs != 9 {
+		t.Errorf("unexpected queued/in flight/total: %d/%d/%d", s.Queued, s.InFlight, s.TotalJobs)
+	}
+}
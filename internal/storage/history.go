@@ -0,0 +1,200 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// historyBucket is the name of the bucket with the download history (it contains a nested bucket per user,
+// with the records keyed by their IDs).
+var historyBucket = []byte("history")
+
+// HistoryRecord describes a single successful download.
+type HistoryRecord struct {
+	ID        uint64    `json:"-"`                   // unique (per user) record ID, set by AddHistory
+	UserID    int64     `json:"-"`                   // Telegram user ID
+	URL       string    `json:"url"`                 // original video URL
+	Title     string    `json:"title,omitempty"`     // video title
+	Extractor string    `json:"extractor,omitempty"` // yt-dlp extractor (e.g., "youtube")
+	Size      int64     `json:"size"`                // file size in bytes
+	FileID    string    `json:"file_id,omitempty"`   // Telegram file ID (if the video was sent to Telegram)
+	Link      string    `json:"link,omitempty"`      // link to the file hosting (if the video was too large)
+	CreatedAt time.Time `json:"created_at"`          // when the video was downloaded
+}
+
+// AddHistory adds the record to the user history, setting its ID (and the creation time, if it's not set). If
+// the limit is positive, the oldest records exceeding it are removed.
+func (db *DB) AddHistory(rec *HistoryRecord, limit int) error {
+	if rec.CreatedAt.IsZero() {
+		rec.CreatedAt = time.Now()
+	}
+
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists(historyBucket)
+		if err != nil {
+			return err
+		}
+
+		bucket, err := root.CreateBucketIfNotExists(userKey(rec.UserID))
+		if err != nil {
+			return err
+		}
+
+		if rec.ID, err = bucket.NextSequence(); err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+
+		if err = bucket.Put(itob(rec.ID), data); err != nil {
+			return err
+		}
+
+		if limit <= 0 {
+			return nil
+		}
+
+		// the keys are sorted by ID, so the oldest records go first
+		for n, c := countKeys(bucket), bucket.Cursor(); n > limit; n-- {
+			if k, _ := c.First(); k == nil {
+				break
+			}
+
+			if err = c.Delete(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// History returns the page of the user history (the newest records go first) and the total number of records.
+func (db *DB) History(userID int64, offset, limit int) (_ []HistoryRecord, total int, _ error) {
+	var list []HistoryRecord
+
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		var bucket = userHistory(tx, userID)
+		if bucket == nil {
+			return nil
+		}
+
+		var c = bucket.Cursor()
+
+		for k, v := c.Last(); k != nil; k, v = c.Prev() {
+			if total++; total <= offset || len(list) >= limit {
+				continue
+			}
+
+			rec, err := decodeHistory(userID, k, v)
+			if err != nil {
+				return err
+			}
+
+			list = append(list, *rec)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	return list, total, nil
+}
+
+// HistoryRecord returns the user history record by its ID (ErrNotFound if there is no such record).
+func (db *DB) HistoryRecord(userID int64, id uint64) (*HistoryRecord, error) {
+	var rec *HistoryRecord
+
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		var bucket = userHistory(tx, userID)
+		if bucket == nil {
+			return ErrNotFound
+		}
+
+		var v = bucket.Get(itob(id))
+		if v == nil {
+			return ErrNotFound
+		}
+
+		var err error
+
+		rec, err = decodeHistory(userID, itob(id), v)
+
+		return err
+	})
+
+	return rec, err
+}
+
+// DeleteHistory removes the record from the user history (ErrNotFound if there is no such record).
+func (db *DB) DeleteHistory(userID int64, id uint64) error {
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		var bucket = userHistory(tx, userID)
+		if bucket == nil || bucket.Get(itob(id)) == nil {
+			return ErrNotFound
+		}
+
+		return bucket.Delete(itob(id))
+	})
+}
+
+// DeleteUserHistory removes all the records from the user history and returns the number of removed records.
+func (db *DB) DeleteUserHistory(userID int64) (removed int, _ error) {
+	err := db.bolt.Update(func(tx *bolt.Tx) error {
+		var bucket = userHistory(tx, userID)
+		if bucket == nil {
+			return nil
+		}
+
+		removed = countKeys(bucket)
+
+		return tx.Bucket(historyBucket).DeleteBucket(userKey(userID))
+	})
+
+	return removed, err
+}
+
+// userHistory returns the bucket with the user history (nil if there is no history).
+func userHistory(tx *bolt.Tx, userID int64) *bolt.Bucket {
+	if root := tx.Bucket(historyBucket); root != nil {
+		return root.Bucket(userKey(userID))
+	}
+
+	return nil
+}
+
+// decodeHistory decodes the history record.
+func decodeHistory(userID int64, k, v []byte) (*HistoryRecord, error) {
+	var rec HistoryRecord
+
+	if err := json.Unmarshal(v, &rec); err != nil {
+		return nil, errors.Join(fmt.Errorf("corrupted history record %d", btoi(k)), err)
+	}
+
+	rec.ID, rec.UserID = btoi(k), userID
+
+	return &rec, nil
+}
+
+// countKeys returns the number of keys in the bucket (the stats don't reflect the uncommitted changes).
+func countKeys(bucket *bolt.Bucket) (n int) {
+	var c = bucket.Cursor()
+
+	for k, _ := c.First(); k != nil; k, _ = c.Next() {
+		n++
+	}
+
+	return n
+}
+
+// userKey returns the key of the user's nested bucket.
+func userKey(userID int64) []byte { return itob(uint64(userID)) } //nolint:gosec // IDs are positive
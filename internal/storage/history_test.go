@@ -0,0 +1,101 @@
+package storage_test
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"gh.tarampamp.am/video-dl-bot/internal/storage"
+)
+
+func openDB(t *testing.T) *storage.DB {
+	t.Helper()
+
+	db, err := storage.Open(filepath.Join(t.TempDir(), "data", "bot.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = db.Close() })
+
+	return db
+}
+
+func TestDB_History(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	var db = openDB(t)
+
+	for i := range 5 {
+		var rec = storage.HistoryRecord{UserID: 1, URL: fmt.Sprintf("https://example.com/%d", i), Size: int64(i)}
+
+		if err := db.AddHistory(&rec, 3); err != nil {
+			t.Fatal(err)
+		}
+
+		if rec.ID != uint64(i+1) || rec.CreatedAt.IsZero() { //nolint:gosec
+			t.Fatalf("unexpected record: %+v", rec)
+		}
+	}
+
+	if err := db.AddHistory(&storage.HistoryRecord{UserID: 2, URL: "https://example.com/foo"}, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	// the oldest records are removed, the newest go first
+	list, total, err := db.History(1, 0, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if total != 3 || len(list) != 2 || list[0].ID != 5 || list[1].ID != 4 || list[0].UserID != 1 {
+		t.Fatalf("unexpected history (total %d): %+v", total, list)
+	}
+
+	if list[0].URL != "https://example.com/4" || list[0].Size != 4 {
+		t.Errorf("unexpected record: %+v", list[0])
+	}
+
+	if list, total, _ = db.History(1, 2, 2); total != 3 || len(list) != 1 || list[0].ID != 3 {
+		t.Fatalf("unexpected second page (total %d): %+v", total, list)
+	}
+
+	if list, total, _ = db.History(42, 0, 10); total != 0 || len(list) != 0 {
+		t.Fatalf("unexpected history of the unknown user (total %d): %+v", total, list)
+	}
+
+	// get and delete
+	if rec, gErr := db.HistoryRecord(1, 4); gErr != nil || rec.URL != "https://example.com/3" {
+		t.Fatalf("unexpected record: %+v (error: %v)", rec, gErr)
+	}
+
+	if _, err = db.HistoryRecord(1, 1); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for the removed record, got %v", err)
+	}
+
+	if _, err = db.HistoryRecord(2, 4); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for the record of another user, got %v", err)
+	}
+
+	if err = db.DeleteHistory(1, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = db.DeleteHistory(1, 4); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// forget the user
+	if removed, dErr := db.DeleteUserHistory(1); dErr != nil || removed != 2 {
+		t.Fatalf("expected 2 removed records, got %d (error: %v)", removed, dErr)
+	}
+
+	if _, total, _ = db.History(1, 0, 10); total != 0 {
+		t.Fatalf("expected empty history, got %d records", total)
+	}
+
+	if _, total, _ = db.History(2, 0, 10); total != 1 {
+		t.Fatalf("expected the history of another user to be kept, got %d records", total)
+	}
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// linksBucket is the name of the bucket with the external links (the files uploaded to the file hosting), keyed by
+// their IDs.
+var linksBucket = []byte("links")
+
+// Link describes the file uploaded to the external storage, so it can be listed and deleted later.
+type Link struct {
+	ID           uint64    `json:"-"`                       // unique record ID, set by AddLink
+	UserID       int64     `json:"user_id"`                 // Telegram user ID (the owner)
+	Backend      string    `json:"backend"`                 // storage name (e.g., "filebin")
+	ObjectID     string    `json:"object_id"`               // storage-specific file ID (bin ID, object key, etc.)
+	URL          string    `json:"url"`                     // public link to the file
+	VideoURL     string    `json:"video_url"`               // original video URL
+	Title        string    `json:"title,omitempty"`         // video title
+	Size         int64     `json:"size"`                    // file size in bytes
+	SelfExpiring bool      `json:"self_expiring,omitempty"` // the storage deletes the file itself, when it expires
+	CreatedAt    time.Time `json:"created_at"`              // when the file was uploaded
+	ExpiresAt    time.Time `json:"expires_at"`              // when the link expires
+}
+
+// AddLink adds the link record, setting its ID (and the creation time, if it's not set).
+func (db *DB) AddLink(link *Link) error {
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = time.Now()
+	}
+
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(linksBucket)
+		if err != nil {
+			return err
+		}
+
+		if link.ID, err = bucket.NextSequence(); err != nil {
+			return err
+		}
+
+		data, err := json.Marshal(link)
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(itob(link.ID), data)
+	})
+}
+
+// Links returns the user links, that are not expired at the given time (the newest go first).
+func (db *DB) Links(userID int64, now time.Time) ([]Link, error) {
+	return db.links(func(link *Link) bool { return link.UserID == userID && link.ExpiresAt.After(now) }, true)
+}
+
+// ExpiredLinks returns the links of all the users, that are expired at the given time (the oldest go first).
+func (db *DB) ExpiredLinks(now time.Time) ([]Link, error) {
+	return db.links(func(link *Link) bool { return !link.ExpiresAt.After(now) }, false)
+}
+
+// Link returns the link record by its ID (ErrNotFound if there is no such record).
+func (db *DB) Link(id uint64) (*Link, error) {
+	var link *Link
+
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		var bucket = tx.Bucket(linksBucket)
+		if bucket == nil {
+			return ErrNotFound
+		}
+
+		var v = bucket.Get(itob(id))
+		if v == nil {
+			return ErrNotFound
+		}
+
+		var err error
+
+		link, err = decodeLink(itob(id), v)
+
+		return err
+	})
+
+	return link, err
+}
+
+// DeleteLink removes the link record (ErrNotFound if there is no such record).
+func (db *DB) DeleteLink(id uint64) error {
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		var bucket = tx.Bucket(linksBucket)
+		if bucket == nil || bucket.Get(itob(id)) == nil {
+			return ErrNotFound
+		}
+
+		return bucket.Delete(itob(id))
+	})
+}
+
+// links returns the link records, matching the filter (the records are ordered by ID).
+func (db *DB) links(match func(*Link) bool, newestFirst bool) ([]Link, error) {
+	var list []Link
+
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		var bucket = tx.Bucket(linksBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		var c = bucket.Cursor()
+
+		first, next := c.First, c.Next
+		if newestFirst {
+			first, next = c.Last, c.Prev
+		}
+
+		for k, v := first(); k != nil; k, v = next() {
+			link, err := decodeLink(k, v)
+			if err != nil {
+				return err
+			}
+
+			if match(link) {
+				list = append(list, *link)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read links: %w", err)
+	}
+
+	return list, nil
+}
+
+// decodeLink decodes the link record.
+func decodeLink(k, v []byte) (*Link, error) {
+	var link Link
+
+	if err := json.Unmarshal(v, &link); err != nil {
+		return nil, errors.Join(fmt.Errorf("corrupted link record %d", btoi(k)), err)
+	}
+
+	link.ID = btoi(k)
+
+	return &link, nil
+}
@@ -0,0 +1,67 @@
+package storage_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gh.tarampamp.am/video-dl-bot/internal/storage"
+)
+
+func TestDB_Links(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db  = openDB(t)
+		now = time.Now()
+	)
+
+	for _, link := range []storage.Link{
+		{UserID: 1, ObjectID: "expired", ExpiresAt: now.Add(-time.Hour)},
+		{UserID: 1, ObjectID: "first", ExpiresAt: now.Add(time.Hour)},
+		{UserID: 2, ObjectID: "another", ExpiresAt: now.Add(time.Hour)},
+		{UserID: 1, ObjectID: "second", ExpiresAt: now.Add(2 * time.Hour), SelfExpiring: true},
+	} {
+		if err := db.AddLink(&link); err != nil {
+			t.Fatal(err)
+		}
+
+		if link.ID == 0 || link.CreatedAt.IsZero() {
+			t.Fatalf("unexpected link: %+v", link)
+		}
+	}
+
+	// active links of the user, the newest go first
+	list, err := db.Links(1, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list) != 2 || list[0].ObjectID != "second" || list[1].ObjectID != "first" || !list[0].SelfExpiring {
+		t.Fatalf("unexpected links: %+v", list)
+	}
+
+	// expired links of all the users, the oldest go first
+	if list, err = db.ExpiredLinks(now.Add(90 * time.Minute)); err != nil {
+		t.Fatal(err)
+	} else if len(list) != 3 || list[0].ObjectID != "expired" || list[2].ObjectID != "another" {
+		t.Fatalf("unexpected expired links: %+v", list)
+	}
+
+	// get and delete
+	if link, gErr := db.Link(2); gErr != nil || link.ObjectID != "first" || link.UserID != 1 {
+		t.Fatalf("unexpected link: %+v (error: %v)", link, gErr)
+	}
+
+	if err = db.DeleteLink(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = db.Link(2); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for the removed link, got %v", err)
+	}
+
+	if err = db.DeleteLink(2); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
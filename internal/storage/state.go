@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"encoding/json"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// stateBucket is the name of the bucket with the bot state values (keyed by name).
+var stateBucket = []byte("state")
+
+// LoadState decodes the state value with the given key into v (ErrNotFound if there is no such value).
+func (db *DB) LoadState(key string, v any) error {
+	return db.bolt.View(func(tx *bolt.Tx) error {
+		var bucket = tx.Bucket(stateBucket)
+		if bucket == nil {
+			return ErrNotFound
+		}
+
+		var data = bucket.Get([]byte(key))
+		if data == nil {
+			return ErrNotFound
+		}
+
+		return json.Unmarshal(data, v)
+	})
+}
+
+// SaveState saves the state value (JSON-encoded) with the given key.
+func (db *DB) SaveState(key string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		bucket, bErr := tx.CreateBucketIfNotExists(stateBucket)
+		if bErr != nil {
+			return bErr
+		}
+
+		return bucket.Put([]byte(key), data)
+	})
+}
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// ErrNotFound is returned when the requested record does not exist.
+var ErrNotFound = errors.New("not found")
+
+// openTimeout is the time to wait for the database file lock (another process may hold it).
+const openTimeout = 5 * time.Second
+
+// DB is a local embedded database (a single file), that persists the bot data between restarts. It's safe for
+// concurrent use.
+type DB struct{ bolt *bolt.DB }
+
+// Open opens the database file (it's created, together with the parent directories, if it doesn't exist).
+func Open(path string) (*DB, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil { //nolint:mnd
+		return nil, fmt.Errorf("failed to create database directory: %w", err)
+	}
+
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: openTimeout}) //nolint:mnd
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	return &DB{bolt: db}, nil
+}
+
+// Close closes the database.
+func (db *DB) Close() error { return db.bolt.Close() }
+
+// itob encodes the number as a big-endian byte slice (keys are sorted in the numeric order this way).
+func itob(v uint64) []byte { return binary.BigEndian.AppendUint64(nil, v) }
+
+// btoi decodes the big-endian byte slice (see itob).
+func btoi(b []byte) uint64 { return binary.BigEndian.Uint64(b) }
@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// usersBucket is the name of the bucket with the known users (keyed by the user ID).
+var usersBucket = []byte("users")
+
+// User describes the user known to the bot.
+type User struct {
+	ID        int64     `json:"-"`                  // Telegram user ID
+	FirstSeen time.Time `json:"first_seen"`         // when the user was seen for the first time
+	Language  string    `json:"language,omitempty"` // preferred language (empty = from the Telegram client)
+}
+
+// TouchUser adds the user to the known users (if it's not there yet), and reports whether it was added.
+func (db *DB) TouchUser(id int64) (added bool, _ error) {
+	err := db.bolt.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucketIfNotExists(usersBucket)
+		if err != nil {
+			return err
+		}
+
+		if bucket.Get(userKey(id)) != nil {
+			return nil
+		}
+
+		data, err := json.Marshal(User{FirstSeen: time.Now()})
+		if err != nil {
+			return err
+		}
+
+		added = true
+
+		return bucket.Put(userKey(id), data)
+	})
+
+	return added, err
+}
+
+// User returns the known user (ErrNotFound if the user is unknown).
+func (db *DB) User(id int64) (*User, error) {
+	var u User
+
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		var bucket = tx.Bucket(usersBucket)
+		if bucket == nil {
+			return ErrNotFound
+		}
+
+		var v = bucket.Get(userKey(id))
+		if v == nil {
+			return ErrNotFound
+		}
+
+		if err := json.Unmarshal(v, &u); err != nil {
+			return errors.Join(fmt.Errorf("corrupted user record %d", id), err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	u.ID = id
+
+	return &u, nil
+}
+
+// SaveUser saves the user (adding it to the known users, if needed).
+func (db *DB) SaveUser(u *User) error {
+	if u.FirstSeen.IsZero() {
+		u.FirstSeen = time.Now()
+	}
+
+	data, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		bucket, bErr := tx.CreateBucketIfNotExists(usersBucket)
+		if bErr != nil {
+			return bErr
+		}
+
+		return bucket.Put(userKey(u.ID), data)
+	})
+}
+
+// UserIDs returns the IDs of all the known users.
+func (db *DB) UserIDs() ([]int64, error) {
+	var ids []int64
+
+	err := db.bolt.View(func(tx *bolt.Tx) error {
+		var bucket = tx.Bucket(usersBucket)
+		if bucket == nil {
+			return nil
+		}
+
+		return bucket.ForEach(func(k, _ []byte) error {
+			ids = append(ids, int64(btoi(k))) //nolint:gosec
+
+			return nil
+		})
+	})
+
+	return ids, err
+}
+
+// DeleteUser removes the user from the known users (it's not an error if the user is unknown).
+func (db *DB) DeleteUser(id int64) error {
+	return db.bolt.Update(func(tx *bolt.Tx) error {
+		if bucket := tx.Bucket(usersBucket); bucket != nil {
+			return bucket.Delete(userKey(id))
+		}
+
+		return nil
+	})
+}
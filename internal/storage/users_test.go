@@ -0,0 +1,78 @@
+package storage_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"gh.tarampamp.am/video-dl-bot/internal/storage"
+)
+
+func TestDB_Users(t *testing.T) {
+	t.Parallel()
+
+	var db = openDB(t)
+
+	if _, err := db.User(1); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	for _, id := range []int64{3, 1, 2, 1} {
+		if _, err := db.TouchUser(id); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if added, _ := db.TouchUser(2); added {
+		t.Error("expected the known user not to be added again")
+	}
+
+	if ids, err := db.UserIDs(); err != nil || !slices.Equal(ids, []int64{1, 2, 3}) {
+		t.Fatalf("unexpected user IDs: %v (error: %v)", ids, err)
+	}
+
+	u, err := db.User(2)
+	if err != nil || u.ID != 2 || u.FirstSeen.IsZero() || u.Language != "" {
+		t.Fatalf("unexpected user: %+v (error: %v)", u, err)
+	}
+
+	u.Language = "ru"
+
+	if err = db.SaveUser(u); err != nil {
+		t.Fatal(err)
+	}
+
+	if u, _ = db.User(2); u.Language != "ru" {
+		t.Errorf("expected the language to be saved, got %q", u.Language)
+	}
+
+	if err = db.DeleteUser(2); err != nil {
+		t.Fatal(err)
+	}
+
+	if ids, _ := db.UserIDs(); !slices.Equal(ids, []int64{1, 3}) {
+		t.Fatalf("unexpected user IDs after the removal: %v", ids)
+	}
+}
+
+func TestDB_State(t *testing.T) {
+	t.Parallel()
+
+	var db = openDB(t)
+
+	type value struct{ Enabled bool }
+
+	var v value
+
+	if err := db.LoadState("foo", &v); !errors.Is(err, storage.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	if err := db.SaveState("foo", value{Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.LoadState("foo", &v); err != nil || !v.Enabled {
+		t.Fatalf("unexpected value: %+v (error: %v)", v, err)
+	}
+}
@@ -0,0 +1,181 @@
+package workspace
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ErrNoSpace is returned when the reservation doesn't fit into the budget (or the free disk space).
+var ErrNoSpace = errors.New("not enough disk space")
+
+type (
+	// Manager manages the working directory for the downloads. The downloads reserve their estimated size before
+	// they start, so the concurrent downloads cannot exceed the byte budget (and the free disk space). The files
+	// left after a crash are removed with Sweep.
+	Manager struct {
+		dir      string   // working directory
+		budget   int64    // maximal total size of the reservations (0 = limited by the free disk space only)
+		patterns []string // glob patterns of the temporary files and directories in the working directory
+
+		mu       sync.Mutex
+		reserved int64 // total size of the active reservations
+	}
+
+	// Reservation is the disk space reserved for a download. It must be released when the downloaded files are
+	// removed.
+	Reservation struct {
+		m    *Manager
+		size int64
+		once sync.Once
+	}
+
+	// Option configures the Manager.
+	Option func(*Manager)
+)
+
+// WithBudget sets the maximal total size of the reservations, in bytes (0 = limited by the free disk space only).
+func WithBudget(size int64) Option { return func(m *Manager) { m.budget = size } }
+
+// WithPatterns sets the glob patterns of the temporary files and directories, that are removed by Sweep (e.g.,
+// "yt-dlp-*").
+func WithPatterns(patterns ...string) Option { return func(m *Manager) { m.patterns = patterns } }
+
+// New creates a new workspace manager for the given directory (it's created if it doesn't exist).
+func New(dir string, opts ...Option) (*Manager, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil { //nolint:mnd
+		return nil, fmt.Errorf("failed to create working directory: %w", err)
+	}
+
+	var m = Manager{dir: dir}
+
+	for _, opt := range opts {
+		opt(&m)
+	}
+
+	for _, pattern := range m.patterns {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	return &m, nil
+}
+
+// Dir returns the working directory.
+func (m *Manager) Dir() string { return m.dir }
+
+// Budget returns the maximal total size of the reservations (0 = limited by the free disk space only).
+func (m *Manager) Budget() int64 { return m.budget }
+
+// Reserved returns the total size of the active reservations.
+func (m *Manager) Reserved() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.reserved
+}
+
+// Reserve reserves the disk space for a download. ErrNoSpace is returned if the size, together with the active
+// reservations, exceeds the budget or the free disk space.
+func (m *Manager) Reserve(size int64) (*Reservation, error) {
+	size = max(0, size)
+
+	free, freeErr := FreeSpace(m.dir)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.budget > 0 && m.reserved+size > m.budget {
+		return nil, fmt.Errorf("%w: %d bytes requested, %d of %d bytes reserved", ErrNoSpace, size, m.reserved, m.budget)
+	}
+
+	// the reserved space is not used yet (in the worst case), so it's subtracted from the free one
+	if freeErr == nil && uint64(m.reserved+size) > free { //nolint:gosec // both values are not negative
+		return nil, fmt.Errorf("%w: %d bytes requested, %d bytes reserved, %d bytes free", ErrNoSpace, size, m.reserved, free)
+	}
+
+	m.reserved += size
+
+	return &Reservation{m: m, size: size}, nil
+}
+
+// Size returns the reserved size, in bytes.
+func (r *Reservation) Size() int64 { return r.size }
+
+// Release releases the reserved space. It's safe to call it multiple times.
+func (r *Reservation) Release() {
+	r.once.Do(func() {
+		r.m.mu.Lock()
+		r.m.reserved -= r.size
+		r.m.mu.Unlock()
+	})
+}
+
+// Sweep removes the temporary files and directories (matching the patterns) from the working directory, that are
+// not modified for longer than the given age (0 = all of them, e.g., at startup). The removed paths are returned.
+func (m *Manager) Sweep(olderThan time.Duration) (removed []string, _ error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		now  = time.Now()
+		errs []error
+	)
+
+	for _, entry := range entries {
+		if !m.matches(entry.Name()) {
+			continue
+		}
+
+		info, infoErr := entry.Info()
+		if infoErr != nil {
+			continue // removed in the meantime
+		}
+
+		if olderThan > 0 && now.Sub(info.ModTime()) < olderThan {
+			continue
+		}
+
+		var path = filepath.Join(m.dir, entry.Name())
+
+		if err = os.RemoveAll(path); err != nil {
+			errs = append(errs, err)
+
+			continue
+		}
+
+		removed = append(removed, path)
+	}
+
+	return removed, errors.Join(errs...)
+}
+
+// matches reports whether the file name matches any of the patterns.
+func (m *Manager) matches(name string) bool {
+	for _, pattern := range m.patterns {
+		if ok, _ := filepath.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FreeSpace returns the free disk space (available for unprivileged users) of the file system with the given
+// path, in bytes.
+func FreeSpace(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, fmt.Errorf("failed to get the file system stats: %w", err)
+	}
+
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil //nolint:gosec,unconvert // the types differ per platform
+}
@@ -0,0 +1,136 @@
+package workspace_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"gh.tarampamp.am/video-dl-bot/internal/workspace"
+)
+
+func TestManager_Reserve(t *testing.T) {
+	t.Parallel()
+
+	m, err := workspace.New(filepath.Join(t.TempDir(), "work"), workspace.WithBudget(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := m.Reserve(60)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = m.Reserve(50); !errors.Is(err, workspace.ErrNoSpace) {
+		t.Fatalf("expected ErrNoSpace, got %v", err)
+	}
+
+	second, err := m.Reserve(40)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := m.Reserved(); got != 100 {
+		t.Errorf("want 100 bytes reserved, got %d", got)
+	}
+
+	first.Release()
+	first.Release() // no-op
+
+	if got := m.Reserved(); got != 40 {
+		t.Errorf("want 40 bytes reserved, got %d", got)
+	}
+
+	second.Release()
+
+	if got := m.Reserved(); got != 0 {
+		t.Errorf("want nothing reserved, got %d", got)
+	}
+}
+
+func TestManager_Reserve_FreeSpace(t *testing.T) {
+	t.Parallel()
+
+	m, err := workspace.New(t.TempDir()) // no budget
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	free, err := workspace.FreeSpace(m.Dir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if free == 0 {
+		t.Fatal("expected some free space")
+	}
+
+	if _, err = m.Reserve(int64(free) + 1); !errors.Is(err, workspace.ErrNoSpace) { //nolint:gosec
+		t.Errorf("expected ErrNoSpace, got %v", err)
+	}
+}
+
+func TestManager_Sweep(t *testing.T) {
+	t.Parallel()
+
+	var dir = t.TempDir()
+
+	m, err := workspace.New(dir, workspace.WithPatterns("yt-dlp-*", "yt-dlp-result-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		stale = filepath.Join(dir, "yt-dlp-111")
+		fresh = filepath.Join(dir, "yt-dlp-result-222.mp4")
+		other = filepath.Join(dir, "bot.db")
+	)
+
+	if err = os.MkdirAll(filepath.Join(stale, "nested"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{fresh, other} {
+		if err = os.WriteFile(path, []byte("foo"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var old = time.Now().Add(-2 * time.Hour)
+
+	for _, path := range []string{stale, other} {
+		if err = os.Chtimes(path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	removed, err := m.Sweep(time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(removed, []string{stale}) {
+		t.Errorf("unexpected removed paths: %v", removed)
+	}
+
+	if removed, err = m.Sweep(0); err != nil {
+		t.Fatal(err)
+	} else if !slices.Equal(removed, []string{fresh}) {
+		t.Errorf("unexpected removed paths: %v", removed)
+	}
+
+	if _, err = os.Stat(other); err != nil {
+		t.Errorf("the other file should be kept: %v", err)
+	}
+}
+
+func TestNew_InvalidPattern(t *testing.T) {
+	t.Parallel()
+
+	if _, err := workspace.New(t.TempDir(), workspace.WithPatterns("[")); err == nil {
+		t.Error("expected an error")
+	}
+}
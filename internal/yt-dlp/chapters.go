@@ -0,0 +1,81 @@
+package ytdlp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// chapterFilePrefix is the prefix of the files, the video is split into (see WithSplitChapters).
+const chapterFilePrefix = "chapter."
+
+// sectionArg returns the "--download-sections" value for the time range.
+func sectionArg(c *Chapter) string {
+	var seconds = func(d time.Duration) string { return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) }
+
+	return fmt.Sprintf("*%s-%s", seconds(c.Start), seconds(c.End))
+}
+
+// readChapterFiles returns the files, the video is split into (named like "chapter.001.mp4", in the chapters
+// order), with the chapters metadata. Nil is returned, if there are no such files.
+func readChapterFiles(dir string, chapters []Chapter) ([]Item, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	type file struct {
+		number int
+		name   string
+	}
+
+	var files []file
+
+	for _, e := range entries {
+		rest, ok := strings.CutPrefix(e.Name(), chapterFilePrefix)
+		if !ok {
+			continue
+		}
+
+		num, ext, _ := strings.Cut(rest, ".")
+
+		// the intermediate files have more than one extension (see resultFile)
+		if ext == "" || strings.Contains(ext, ".") || slices.Contains(tempExtensions, ext) {
+			continue
+		}
+
+		n, nErr := strconv.Atoi(num)
+		if nErr != nil || n < 1 || n > len(chapters) {
+			continue
+		}
+
+		files = append(files, file{number: n, name: e.Name()})
+	}
+
+	slices.SortFunc(files, func(a, b file) int { return a.number - b.number })
+
+	var items = make([]Item, 0, len(files))
+
+	for _, f := range files {
+		var chapter = chapters[f.number-1]
+
+		items = append(items, Item{
+			Filepath: filepath.Join(dir, f.name),
+			Kind:     MediaVideo,
+			ID:       strconv.Itoa(f.number),
+			Title:    chapter.Title,
+			Duration: chapter.End - chapter.Start,
+			Chapter:  &chapter,
+		})
+	}
+
+	if len(items) == 0 {
+		return nil, nil
+	}
+
+	return items, nil
+}
@@ -0,0 +1,134 @@
+package ytdlp_test
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	ytdlp "gh.tarampamp.am/video-dl-bot/internal/yt-dlp"
+)
+
+const chaptersInfo = `{"id":"foo","title":"Foo","duration":300,"chapters":[` +
+	`{"title":"Intro","start_time":0,"end_time":30},` +
+	`{"title":"Main","start_time":30,"end_time":250.5},` +
+	`{"title":"Outro","start_time":250.5,"end_time":300}]}`
+
+func TestDownload_Section(t *testing.T) {
+	t.Parallel()
+
+	var runner = &mediaRunner{files: map[string]string{"result.info.json": chaptersInfo, "result.mp4": "part"}}
+
+	dl, err := ytdlp.Download(context.Background(), "https://example.com",
+		ytdlp.WithWorkDir(t.TempDir()),
+		ytdlp.WithSection(30*time.Second, 250500*time.Millisecond),
+		ytdlp.WithRunner(runner),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = dl.Cleanup() })
+
+	if !dl.IsVideo() || len(dl.Chapters) != 3 {
+		t.Errorf("unexpected result: %+v", dl)
+	}
+
+	if got := argValue(runner.args, "--download-sections"); got != "*30-250.5" {
+		t.Errorf("unexpected sections argument: %q", got)
+	}
+
+	if slices.Contains(runner.args, "--split-chapters") {
+		t.Error("the chapters should not be split")
+	}
+}
+
+func TestDownload_SplitChapters(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveFiles map[string]string
+		wantFiles []string
+		wantTitle []string
+	}{
+		"split": {
+			giveFiles: map[string]string{
+				"result.info.json":     chaptersInfo,
+				"result.mp4":           "full",
+				"chapter.003.mp4":      "outro",
+				"chapter.001.mp4":      "intro",
+				"chapter.002.mp4":      "main",
+				"chapter.002.temp.mp4": "leftover",
+				"chapter.004.mp4":      "unknown chapter",
+			},
+			wantFiles: []string{"chapter.001.mp4", "chapter.002.mp4", "chapter.003.mp4"},
+			wantTitle: []string{"Intro", "Main", "Outro"},
+		},
+		"no chapters": {
+			giveFiles: map[string]string{"result.info.json": `{"id":"foo"}`, "result.webm": "full"},
+			wantFiles: []string{"result.webm"},
+			wantTitle: []string{""},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var runner = &mediaRunner{files: tc.giveFiles}
+
+			dl, err := ytdlp.Download(context.Background(), "https://example.com",
+				ytdlp.WithWorkDir(t.TempDir()),
+				ytdlp.WithSplitChapters(),
+				ytdlp.WithRunner(runner),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			t.Cleanup(func() { _ = dl.Cleanup() })
+
+			if !slices.Contains(runner.args, "--split-chapters") ||
+				!slices.ContainsFunc(runner.args, func(s string) bool { return strings.HasPrefix(s, "chapter:") }) {
+				t.Errorf("expected the chapters to be split, got %v", runner.args)
+			}
+
+			var files, titles []string
+
+			for _, item := range dl.Items {
+				files, titles = append(files, filepath.Base(item.Filepath)), append(titles, item.Title)
+
+				if (item.Chapter != nil) != (item.Title != "") || item.Kind != ytdlp.MediaVideo {
+					t.Errorf("unexpected item: %+v", item)
+				}
+			}
+
+			if !slices.Equal(files, tc.wantFiles) || !slices.Equal(titles, tc.wantTitle) {
+				t.Errorf("unexpected items: %v %v", files, titles)
+			}
+		})
+	}
+
+	// the chapter durations and time ranges
+	var runner = &mediaRunner{files: map[string]string{
+		"result.info.json": chaptersInfo,
+		"result.mp4":       "full",
+		"chapter.002.mkv":  "main",
+	}}
+
+	dl, err := ytdlp.Download(context.Background(), "https://example.com",
+		ytdlp.WithWorkDir(t.TempDir()),
+		ytdlp.WithSplitChapters(),
+		ytdlp.WithRunner(runner),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = dl.Cleanup() })
+
+	if item := dl.Items[0]; len(dl.Items) != 1 || item.Duration != 220500*time.Millisecond ||
+		item.Chapter.Start != 30*time.Second || item.Chapter.End != 250500*time.Millisecond {
+		t.Errorf("unexpected items: %+v", dl.Items)
+	}
+}
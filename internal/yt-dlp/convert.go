@@ -0,0 +1,168 @@
+package ytdlp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Convert is the mode of the downloaded videos conversion, that makes them playable in Telegram (the Telegram
+// clients play the MP4 files with H.264 video and AAC or MP3 audio reliably).
+type Convert string
+
+const (
+	ConvertNone      Convert = "none"      // keep the videos as is
+	ConvertRemux     Convert = "remux"     // repack the videos into the MP4 container, without re-encoding
+	ConvertTranscode Convert = "transcode" // re-encode the video and audio streams with other codecs, too
+)
+
+// Names of the FFmpeg binaries (they are looked up in the PATH).
+const (
+	ffmpegExe  = "ffmpeg"
+	ffprobeExe = "ffprobe"
+)
+
+var (
+	// mp4Codecs are the codecs, that can be stored in the MP4 container as is.
+	mp4Codecs = []string{ //nolint:gochecknoglobals
+		"h264", "hevc", "av1", "vp9", "mpeg4", // video
+		"aac", "mp3", "opus", "flac", "ac3", "eac3", // audio
+	}
+
+	// telegramAudioCodecs are the audio codecs, that are played by all the Telegram clients.
+	telegramAudioCodecs = []string{"aac", "mp3"} //nolint:gochecknoglobals
+)
+
+// ParseConvert parses the conversion mode ("none", "remux" or "transcode"; the empty string means "none").
+func ParseConvert(s string) (Convert, error) {
+	switch c := Convert(strings.ToLower(strings.TrimSpace(s))); c {
+	case "", ConvertNone:
+		return ConvertNone, nil
+	case ConvertRemux, ConvertTranscode:
+		return c, nil
+	default:
+		return "", fmt.Errorf("unknown conversion mode %q (expected none, remux or transcode)", s)
+	}
+}
+
+// mediaCodecs holds the codecs of the first video and audio streams of the media file (empty, if there is no such
+// stream).
+type mediaCodecs struct{ Video, Audio string }
+
+// probeCodecs returns the codecs of the media file, using ffprobe.
+func probeCodecs(ctx context.Context, o options, path string) (*mediaCodecs, error) {
+	res, err := o.runner.Run(ctx, ffprobeExe,
+		"-v", "error",
+		"-show_entries", "stream=codec_type,codec_name",
+		"-of", "json",
+		path,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe: %w", err)
+	}
+
+	var out struct {
+		Streams []struct {
+			Type  string `json:"codec_type"`
+			Codec string `json:"codec_name"`
+		} `json:"streams"`
+	}
+
+	if err = json.NewDecoder(res.Stdout).Decode(&out); err != nil {
+		return nil, fmt.Errorf("ffprobe: failed to decode the output: %w", err)
+	}
+
+	var codecs mediaCodecs
+
+	for _, s := range out.Streams {
+		switch {
+		case s.Type == "video" && codecs.Video == "":
+			codecs.Video = s.Codec
+		case s.Type == "audio" && codecs.Audio == "":
+			codecs.Audio = s.Codec
+		}
+	}
+
+	return &codecs, nil
+}
+
+// convertArgs returns the ffmpeg arguments to convert the video file (with the extension and codecs) into the MP4
+// one, or nil if the conversion is not needed (or not possible without re-encoding, in the remux mode).
+func convertArgs(mode Convert, ext string, c *mediaCodecs, in, out string) []string {
+	if c.Video == "" || mode == ConvertNone || mode == "" { // audio-only files are kept as is
+		return nil
+	}
+
+	var videoArgs, audioArgs = []string{"-c:v", "copy"}, []string{"-c:a", "copy"}
+
+	switch mode {
+	case ConvertRemux:
+		var compatible = slices.Contains(mp4Codecs, c.Video) && (c.Audio == "" || slices.Contains(mp4Codecs, c.Audio))
+
+		if ext == "mp4" || !compatible {
+			return nil
+		}
+	case ConvertTranscode:
+		if c.Video != "h264" {
+			videoArgs = []string{"-c:v", "libx264", "-preset", "veryfast", "-crf", "23", "-pix_fmt", "yuv420p"}
+		}
+
+		if c.Audio != "" && !slices.Contains(telegramAudioCodecs, c.Audio) {
+			audioArgs = []string{"-c:a", "aac", "-b:a", "160k"}
+		}
+
+		if ext == "mp4" && videoArgs[1] == "copy" && audioArgs[1] == "copy" {
+			return nil
+		}
+	}
+
+	var args = []string{
+		"-hide_banner", "-loglevel", "error", "-nostdin", "-y",
+		"-i", in,
+		"-map", "0:v:0", "-map", "0:a:0?", // the first video and audio (if any) streams
+	}
+
+	args = append(args, videoArgs...)
+	args = append(args, audioArgs...)
+
+	return append(args, "-movflags", "+faststart", "-f", "mp4", out)
+}
+
+// convertVideo converts the video file into the MP4 one, if it's needed (see Convert), and returns the path to the
+// resulting file. The original file is replaced.
+func convertVideo(ctx context.Context, o options, path string) (string, error) {
+	codecs, err := probeCodecs(ctx, o, path)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		base = strings.TrimSuffix(path, filepath.Ext(path))
+		tmp  = base + ".convert.mp4"
+		args = convertArgs(o.convert, strings.ToLower(strings.TrimPrefix(filepath.Ext(path), ".")), codecs, path, tmp)
+	)
+
+	if args == nil {
+		return path, nil
+	}
+
+	if _, err = o.runner.Run(ctx, ffmpegExe, args...); err != nil {
+		_ = os.Remove(tmp)
+
+		return "", fmt.Errorf("ffmpeg: %w", err)
+	}
+
+	if err = os.Remove(path); err != nil {
+		return "", err
+	}
+
+	if err = os.Rename(tmp, base+".mp4"); err != nil {
+		return "", err
+	}
+
+	return base + ".mp4", nil
+}
@@ -0,0 +1,332 @@
+package ytdlp_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	ytdlp "gh.tarampamp.am/video-dl-bot/internal/yt-dlp"
+)
+
+// mediaRunner is a fake runner for yt-dlp, ffprobe and ffmpeg: yt-dlp creates the files in the output directory
+// (and optionally lists them in the results file), ffprobe returns the codecs, and ffmpeg creates the output file.
+type mediaRunner struct {
+	mu      sync.Mutex
+	files   map[string]string // files, created by yt-dlp (name: content)
+	printed []string          // file names, printed by yt-dlp into the results file (optional)
+	probe   string            // ffprobe output
+	calls   []string          // names of the executed binaries
+	args    []string          // arguments of the yt-dlp call
+	ffmpeg  []string          // arguments of the ffmpeg call
+}
+
+func (r *mediaRunner) Run(_ context.Context, exe string, args ...string) (*ytdlp.RunResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, filepath.Base(exe))
+
+	var ok = &ytdlp.RunResult{Stdout: strings.NewReader(""), Stderr: strings.NewReader("")}
+
+	switch filepath.Base(exe) {
+	case "ffprobe":
+		return &ytdlp.RunResult{Stdout: strings.NewReader(r.probe), Stderr: strings.NewReader("")}, nil
+
+	case "ffmpeg":
+		r.ffmpeg = args
+
+		return ok, os.WriteFile(args[len(args)-1], []byte("converted"), 0o600)
+	}
+
+	r.args = args
+
+	var dir = argValue(args, "--paths")
+
+	for name, content := range r.files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(r.printed) > 0 {
+		var list strings.Builder
+
+		for _, name := range r.printed {
+			list.WriteString(filepath.Join(dir, name) + "\n")
+		}
+
+		// the path is a template, so "%%" is unescaped by yt-dlp
+		var path = strings.ReplaceAll(args[slices.Index(args, "after_move:filepath")+1], "%%", "%")
+
+		if err := os.WriteFile(path, []byte(list.String()), 0o600); err != nil {
+			return nil, err
+		}
+	}
+
+	return ok, nil
+}
+
+func TestDownload_AnyExtension(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveFiles   map[string]string
+		givePrinted []string
+		wantFile    string
+		wantKind    ytdlp.MediaKind
+	}{
+		"webm": {
+			giveFiles: map[string]string{"result.webm": "video"},
+			wantFile:  "result.webm",
+			wantKind:  ytdlp.MediaVideo,
+		},
+		"mkv with the leftovers": {
+			giveFiles: map[string]string{
+				"result.f137.mp4":  "video stream",
+				"result.f251.webm": "audio stream",
+				"result.mkv.part":  "partial",
+				"result.mkv":       "video",
+			},
+			wantFile: "result.mkv",
+			wantKind: ytdlp.MediaVideo,
+		},
+		"mov in upper case": {
+			giveFiles: map[string]string{"result.MOV": "video"},
+			wantFile:  "result.MOV",
+			wantKind:  ytdlp.MediaVideo,
+		},
+		"printed file is preferred": {
+			giveFiles:   map[string]string{"result.webm": "original", "result.mp4": "post-processed"},
+			givePrinted: []string{"result.mp4"},
+			wantFile:    "result.mp4",
+			wantKind:    ytdlp.MediaVideo,
+		},
+		"image": {
+			giveFiles: map[string]string{"result.webp": "image"},
+			wantFile:  "result.webp",
+			wantKind:  ytdlp.MediaImage,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var files = map[string]string{"result.info.json": `{"id":"foo","ext":"mp4"}`}
+
+			for n, c := range tc.giveFiles {
+				files[n] = c
+			}
+
+			dl, err := ytdlp.Download(context.Background(), "https://example.com",
+				ytdlp.WithWorkDir(t.TempDir()),
+				ytdlp.WithRunner(&mediaRunner{files: files, printed: tc.givePrinted}),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			t.Cleanup(func() { _ = dl.Cleanup() })
+
+			if len(dl.Items) != 1 {
+				t.Fatalf("expected a single item, got %+v", dl.Items)
+			}
+
+			if got := filepath.Base(dl.Items[0].Filepath); got != tc.wantFile || dl.Items[0].Kind != tc.wantKind {
+				t.Errorf("unexpected item: %s (%s)", got, dl.Items[0].Kind)
+			}
+
+			if content, _ := os.ReadFile(dl.Items[0].Filepath); string(content) != tc.giveFiles[tc.wantFile] {
+				t.Errorf("unexpected content: %q", content)
+			}
+		})
+	}
+}
+
+func TestDownload_NoResultFile(t *testing.T) {
+	t.Parallel()
+
+	_, err := ytdlp.Download(context.Background(), "https://example.com",
+		ytdlp.WithWorkDir(t.TempDir()),
+		ytdlp.WithRunner(&mediaRunner{files: map[string]string{
+			"result.info.json": `{"id":"foo"}`,
+			"result.webm.part": "partial",
+			"result.f137.mp4":  "video stream",
+		}}),
+	)
+	if err == nil || !strings.Contains(err.Error(), "result file does not exist") {
+		t.Errorf("expected the missing result file error, got %v", err)
+	}
+}
+
+func TestDownload_Convert(t *testing.T) {
+	t.Parallel()
+
+	const (
+		h264AAC = `{"streams":[{"codec_type":"video","codec_name":"h264"},{"codec_type":"audio","codec_name":"aac"}]}`
+		vp9Opus = `{"streams":[{"codec_type":"video","codec_name":"vp9"},{"codec_type":"audio","codec_name":"opus"}]}`
+		mpeg2   = `{"streams":[{"codec_type":"video","codec_name":"mpeg2video"}]}`
+		audio   = `{"streams":[{"codec_type":"audio","codec_name":"opus"}]}`
+	)
+
+	for name, tc := range map[string]struct {
+		giveMode    ytdlp.Convert
+		giveFile    string
+		giveProbe   string
+		wantFile    string
+		wantContent string
+		wantCalls   []string
+		wantArgs    []string // expected ffmpeg arguments (the subset)
+	}{
+		"none": {
+			giveMode:    ytdlp.ConvertNone,
+			giveFile:    "result.webm",
+			wantFile:    "result.webm",
+			wantContent: "original",
+			wantCalls:   []string{"yt-dlp"},
+		},
+		"remux webm": {
+			giveMode:    ytdlp.ConvertRemux,
+			giveFile:    "result.webm",
+			giveProbe:   vp9Opus,
+			wantFile:    "result.mp4",
+			wantContent: "converted",
+			wantCalls:   []string{"yt-dlp", "ffprobe", "ffmpeg"},
+			wantArgs:    []string{"-c:v", "copy", "-c:a", "copy", "+faststart"},
+		},
+		"remux mp4 is skipped": {
+			giveMode:    ytdlp.ConvertRemux,
+			giveFile:    "result.mp4",
+			giveProbe:   h264AAC,
+			wantFile:    "result.mp4",
+			wantContent: "original",
+			wantCalls:   []string{"yt-dlp", "ffprobe"},
+		},
+		"remux incompatible codec is skipped": {
+			giveMode:    ytdlp.ConvertRemux,
+			giveFile:    "result.mkv",
+			giveProbe:   mpeg2,
+			wantFile:    "result.mkv",
+			wantContent: "original",
+			wantCalls:   []string{"yt-dlp", "ffprobe"},
+		},
+		"transcode webm": {
+			giveMode:    ytdlp.ConvertTranscode,
+			giveFile:    "result.webm",
+			giveProbe:   vp9Opus,
+			wantFile:    "result.mp4",
+			wantContent: "converted",
+			wantCalls:   []string{"yt-dlp", "ffprobe", "ffmpeg"},
+			wantArgs:    []string{"libx264", "yuv420p", "-c:a", "aac"},
+		},
+		"transcode mkv with h264 is remuxed": {
+			giveMode:    ytdlp.ConvertTranscode,
+			giveFile:    "result.mkv",
+			giveProbe:   h264AAC,
+			wantFile:    "result.mp4",
+			wantContent: "converted",
+			wantCalls:   []string{"yt-dlp", "ffprobe", "ffmpeg"},
+			wantArgs:    []string{"-c:v", "copy", "-c:a", "copy"},
+		},
+		"transcode compatible mp4 is skipped": {
+			giveMode:    ytdlp.ConvertTranscode,
+			giveFile:    "result.mp4",
+			giveProbe:   h264AAC,
+			wantFile:    "result.mp4",
+			wantContent: "original",
+			wantCalls:   []string{"yt-dlp", "ffprobe"},
+		},
+		"audio only is skipped": {
+			giveMode:    ytdlp.ConvertTranscode,
+			giveFile:    "result.webm",
+			giveProbe:   audio,
+			wantFile:    "result.webm",
+			wantContent: "original",
+			wantCalls:   []string{"yt-dlp", "ffprobe"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var runner = &mediaRunner{
+				files: map[string]string{"result.info.json": `{"id":"foo"}`, tc.giveFile: "original"},
+				probe: tc.giveProbe,
+			}
+
+			dl, err := ytdlp.Download(context.Background(), "https://example.com",
+				ytdlp.WithWorkDir(t.TempDir()),
+				ytdlp.WithExePath("/usr/bin/yt-dlp"),
+				ytdlp.WithConvert(tc.giveMode),
+				ytdlp.WithRunner(runner),
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			t.Cleanup(func() { _ = dl.Cleanup() })
+
+			if !slices.Equal(runner.calls, tc.wantCalls) {
+				t.Errorf("unexpected calls: %v", runner.calls)
+			}
+
+			var path = dl.Items[0].Filepath
+
+			if filepath.Base(path) != tc.wantFile {
+				t.Errorf("unexpected file: %s", path)
+			}
+
+			if content, _ := os.ReadFile(path); string(content) != tc.wantContent {
+				t.Errorf("unexpected content: %q", content)
+			}
+
+			for _, arg := range tc.wantArgs {
+				if !slices.Contains(runner.ffmpeg, arg) {
+					t.Errorf("expected the ffmpeg argument %q, got %v", arg, runner.ffmpeg)
+				}
+			}
+
+			// no intermediate files are left
+			if matches, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "result.*")); len(matches) != 2 {
+				t.Errorf("expected the result and metadata files only, got %v", matches)
+			}
+		})
+	}
+}
+
+func TestDownload_ConvertError(t *testing.T) {
+	t.Parallel()
+
+	_, err := ytdlp.Download(context.Background(), "https://example.com",
+		ytdlp.WithWorkDir(t.TempDir()),
+		ytdlp.WithConvert(ytdlp.ConvertRemux),
+		ytdlp.WithRunner(&mediaRunner{
+			files: map[string]string{"result.info.json": `{"id":"foo"}`, "result.webm": "video"},
+			probe: "not a json",
+		}),
+	)
+	if err == nil || !strings.Contains(err.Error(), "failed to convert the video") {
+		t.Errorf("expected the conversion error, got %v", err)
+	}
+}
+
+func TestParseConvert(t *testing.T) {
+	t.Parallel()
+
+	for give, want := range map[string]ytdlp.Convert{
+		"":           ytdlp.ConvertNone,
+		"none":       ytdlp.ConvertNone,
+		" Remux ":    ytdlp.ConvertRemux,
+		"TRANSCODE":  ytdlp.ConvertTranscode,
+		"transcode ": ytdlp.ConvertTranscode,
+	} {
+		if got, err := ytdlp.ParseConvert(give); err != nil || got != want {
+			t.Errorf("ParseConvert(%q) = %q, %v; want %q", give, got, err, want)
+		}
+	}
+
+	if _, err := ytdlp.ParseConvert("h265"); err == nil {
+		t.Error("expected an error for the unknown mode")
+	}
+}
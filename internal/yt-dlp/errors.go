@@ -0,0 +1,103 @@
+package ytdlp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Errors that can be used to find out the reason of the failure (use errors.Is).
+var (
+	ErrRateLimited = errors.New("rate limited")    // the site limits the requests rate (e.g., HTTP 429)
+	ErrForbidden   = errors.New("forbidden")       // the site denies the access (e.g., HTTP 403, geo-blocking)
+	ErrUnavailable = errors.New("unavailable")     // the video is removed, private, or requires authentication
+	ErrUnsupported = errors.New("unsupported url") // the URL is not supported by any extractor
+	ErrExtractor   = errors.New("extractor error") // the extractor is broken (usually, fixed by updating yt-dlp)
+	ErrTransient   = errors.New("temporary error") // network issues, server errors, etc. (may succeed on retry)
+	ErrFileSize    = errors.New("file size limit") // the file is too large or too small (see WithMaxFileSize)
+)
+
+// errorMarkers maps the (lowercased) yt-dlp output fragments to the errors they indicate. The first match wins.
+var errorMarkers = [...]struct { //nolint:gochecknoglobals
+	text string
+	err  error
+}{
+	{"http error 429", ErrRateLimited},
+	{"too many requests", ErrRateLimited},
+	{"rate-limit", ErrRateLimited},
+	{"rate limit", ErrRateLimited},
+	{"sign in to confirm you", ErrRateLimited}, // youtube asks to sign in when the IP is flagged as a bot
+	{"http error 403", ErrForbidden},
+	{"403: forbidden", ErrForbidden},
+	{"not available in your country", ErrForbidden},
+	{"geo restriction", ErrForbidden},
+	{"geo-restricted", ErrForbidden},
+	{"unsupported url", ErrUnsupported},
+	{"is not a valid url", ErrUnsupported},
+	{"video unavailable", ErrUnavailable},
+	{"private video", ErrUnavailable},
+	{"has been removed", ErrUnavailable},
+	{"no video formats found", ErrUnavailable},
+	{"login required", ErrUnavailable},
+	{"unable to extract", ErrExtractor},
+	{"nsig extraction failed", ErrExtractor},
+	{"signature extraction failed", ErrExtractor},
+	{"please report this issue", ErrExtractor},
+	{"http error 5", ErrTransient}, // 5xx server errors
+	{"timed out", ErrTransient},
+	{"connection reset", ErrTransient},
+	{"connection refused", ErrTransient},
+	{"temporary failure", ErrTransient},
+	{"remote end closed connection", ErrTransient},
+	{"incompleteread", ErrTransient},
+	{"unable to download webpage", ErrTransient},
+	{"unable to download json metadata", ErrTransient},
+	{"not found, unable to continue", ErrTransient}, // "fragment 3 not found, unable to continue"
+	{"unable to download fragment", ErrTransient},
+	{"fragment not found", ErrTransient},
+}
+
+// classifyError wraps the error with the matching sentinel error (if any), based on its text.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var text = strings.ToLower(err.Error())
+
+	for _, m := range errorMarkers {
+		if strings.Contains(text, m.text) {
+			return fmt.Errorf("%w: %w", m.err, err)
+		}
+	}
+
+	return err
+}
+
+// ErrorClass returns a short name of the error class (e.g., "rate-limited", "transient"), useful for logging and
+// metrics. Returns an empty string for nil errors and "other" for unknown ones.
+func ErrorClass(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "canceled"
+	case errors.Is(err, ErrRateLimited):
+		return "rate-limited"
+	case errors.Is(err, ErrForbidden):
+		return "forbidden"
+	case errors.Is(err, ErrUnavailable):
+		return "unavailable"
+	case errors.Is(err, ErrUnsupported):
+		return "unsupported"
+	case errors.Is(err, ErrExtractor):
+		return "extractor"
+	case errors.Is(err, ErrTransient):
+		return "transient"
+	case errors.Is(err, ErrFileSize):
+		return "file-size"
+	}
+
+	return "other"
+}
@@ -0,0 +1,22 @@
+package ytdlp
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// SetExePathForTest sets the default binary path for the duration of the test.
+func SetExePathForTest(t *testing.T, path string) {
+	t.Helper()
+
+	var prev = exePath.Load()
+
+	exePath.Store(&path)
+	t.Cleanup(func() { exePath.Store(prev) })
+}
+
+// RunForTest runs the command with the system runner, that uses the given kill grace period.
+func RunForTest(ctx context.Context, killGrace time.Duration, exe string, args ...string) (*RunResult, error) {
+	return systemRunner{killGrace: killGrace}.Run(ctx, exe, args...)
+}
@@ -0,0 +1,131 @@
+package ytdlp
+
+import (
+	"cmp"
+	"time"
+)
+
+type (
+	// Metadata is the extended metadata of the video, shared by Downloaded and Info. Unknown values are left empty.
+	Metadata struct {
+		Uploader   string      // Name of the uploader (e.g., the channel name)
+		ChannelURL string      // URL of the channel (or the uploader profile)
+		UploadDate time.Time   // Date of the upload (UTC)
+		ViewCount  int64       // Number of the views
+		LikeCount  int64       // Number of the likes
+		Tags       []string    // Tags (keywords) of the video
+		Chapters   []Chapter   // Chapters of the video (in order)
+		Width      int         // Width of the video (in pixels)
+		Height     int         // Height of the video (in pixels)
+		FPS        float64     // Frame rate of the video
+		VideoCodec string      // Video codec (e.g., "avc1.640028"; empty for the audio-only formats)
+		AudioCodec string      // Audio codec (e.g., "mp4a.40.2"; empty for the video-only formats)
+		FileSize   int64       // Size of the file in bytes (exact or estimated)
+		FormatID   string      // Format ID(s) selected by yt-dlp (e.g., "137+140")
+		Thumbnails []Thumbnail // Thumbnails of the video (from the worst to the best quality, as yt-dlp orders them)
+	}
+
+	// Chapter is a chapter of the video.
+	Chapter struct {
+		Title string        // Title of the chapter
+		Start time.Duration // Start time of the chapter
+		End   time.Duration // End time of the chapter
+	}
+
+	// Thumbnail is a thumbnail image of the video.
+	Thumbnail struct {
+		URL    string // URL of the image
+		Width  int    // Width of the image (zero, if unknown)
+		Height int    // Height of the image (zero, if unknown)
+	}
+)
+
+// BestThumbnail returns the URL of the best thumbnail (the last one, as yt-dlp sorts them by the preference), or an
+// empty string if there are no thumbnails.
+func (m *Metadata) BestThumbnail() string {
+	for i := len(m.Thumbnails) - 1; i >= 0; i-- {
+		if m.Thumbnails[i].URL != "" {
+			return m.Thumbnails[i].URL
+		}
+	}
+
+	return ""
+}
+
+// metadataJSON is the part of the yt-dlp metadata JSON, that is converted into Metadata.
+type metadataJSON struct {
+	Uploader    string   `json:"uploader"`
+	UploaderURL string   `json:"uploader_url"`
+	Channel     string   `json:"channel"`
+	ChannelURL  string   `json:"channel_url"`
+	UploadDate  string   `json:"upload_date"` // YYYYMMDD
+	Timestamp   float64  `json:"timestamp"`   // UNIX timestamp of the upload
+	ViewCount   int64    `json:"view_count"`
+	LikeCount   int64    `json:"like_count"`
+	Tags        []string `json:"tags"`
+	Chapters    []struct {
+		Title     string  `json:"title"`
+		StartTime float64 `json:"start_time"`
+		EndTime   float64 `json:"end_time"`
+	} `json:"chapters"`
+	Width      int     `json:"width"`
+	Height     int     `json:"height"`
+	FPS        float64 `json:"fps"`
+	VCodec     string  `json:"vcodec"`
+	ACodec     string  `json:"acodec"`
+	FileSize   int64   `json:"filesize"`
+	SizeApprox float64 `json:"filesize_approx"`
+	FormatID   string  `json:"format_id"`
+	Thumbnails []struct {
+		URL    string `json:"url"`
+		Width  int    `json:"width"`
+		Height int    `json:"height"`
+	} `json:"thumbnails"`
+}
+
+// metadata converts the JSON metadata into Metadata.
+func (j *metadataJSON) metadata() Metadata {
+	var m = Metadata{
+		Uploader:   cmp.Or(j.Uploader, j.Channel),
+		ChannelURL: cmp.Or(j.ChannelURL, j.UploaderURL),
+		ViewCount:  j.ViewCount,
+		LikeCount:  j.LikeCount,
+		Tags:       j.Tags,
+		Width:      j.Width,
+		Height:     j.Height,
+		FPS:        j.FPS,
+		VideoCodec: codecName(j.VCodec),
+		AudioCodec: codecName(j.ACodec),
+		FileSize:   cmp.Or(j.FileSize, int64(j.SizeApprox)),
+		FormatID:   j.FormatID,
+	}
+
+	if date, err := time.Parse("20060102", j.UploadDate); err == nil {
+		m.UploadDate = date
+	} else if j.Timestamp > 0 {
+		m.UploadDate = time.Unix(int64(j.Timestamp), 0).UTC()
+	}
+
+	for _, c := range j.Chapters {
+		m.Chapters = append(m.Chapters, Chapter{
+			Title: c.Title,
+			Start: time.Duration(c.StartTime * float64(time.Second)),
+			End:   time.Duration(c.EndTime * float64(time.Second)),
+		})
+	}
+
+	for _, t := range j.Thumbnails {
+		m.Thumbnails = append(m.Thumbnails, Thumbnail{URL: t.URL, Width: t.Width, Height: t.Height})
+	}
+
+	return m
+}
+
+// codecName returns the codec name, reported by yt-dlp ("none" means there is no such stream).
+func codecName(s string) string {
+	if s == "none" {
+		return ""
+	}
+
+	return s
+}
@@ -0,0 +1,99 @@
+package ytdlp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Info holds the video metadata, extracted without downloading (see Probe).
+type Info struct {
+	ID         string        // Video ID (e.g., "daOyEt3nTnY")
+	Title      string        // Short title of the video
+	WebpageURL string        // Original video URL
+	Extractor  string        // Source site or extractor (e.g., "youtube")
+	Duration   time.Duration // Duration of the video (zero, if unknown)
+	IsLive     bool          // The video is a live stream, that is in progress
+	WasLive    bool          // The video is a recording of a finished live stream
+	InfoJSON   []byte        // Raw metadata JSON, printed by yt-dlp
+
+	Metadata // Extended metadata (the FileSize is estimated for the best format)
+}
+
+// Probe extracts the video metadata from the given URL without downloading it. It's much cheaper than Download,
+// and can be used to check the video (e.g., its extractor or duration) before downloading. The format selector
+// and the file size limits are ignored.
+func Probe(ctx context.Context, in string, opts ...Option) (_ *Info, outErr error) {
+	defer func() {
+		if outErr != nil {
+			outErr = fmt.Errorf("%s: %w", errPrefix, outErr)
+		}
+	}()
+
+	var (
+		o    = options{}.Apply(opts...)
+		args = []string{
+			"--ignore-config",  // don't load any more configuration files except those given to --config-locations
+			"--color", "never", // disable colored output
+			"--no-playlist",             // extract only the video, if the URL refers to a video and a playlist
+			"--skip-download",           // do not download the video
+			"--dump-json",               // print the video metadata as JSON
+			"--ignore-no-formats-error", // the formats are not needed, only the metadata
+			"--no-warnings",             // do not print the warnings
+			"--cache-dir", os.TempDir(), // where yt-dlp can store some downloaded information permanently
+		}
+	)
+
+	if o.cookiesFile != "" {
+		args = append(args, "--cookies", o.cookiesFile)
+	}
+
+	if o.jsRuntimes != "" {
+		args = append(args, "--js-runtimes", o.jsRuntimes)
+	}
+
+	if o.proxy != "" {
+		args = append(args, "--proxy", o.proxy)
+	}
+
+	res, err := o.runner.Run(ctx, o.exePath, append(args, in)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to probe: %w", classifyError(err))
+	}
+
+	var raw json.RawMessage
+
+	if err = json.NewDecoder(res.Stdout).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("failed to decode the metadata: %w", err)
+	}
+
+	var info struct {
+		ID         string  `json:"id"`
+		Title      string  `json:"title"`
+		WebpageURL string  `json:"webpage_url"`
+		Extractor  string  `json:"extractor"`
+		Duration   float64 `json:"duration"`
+		IsLive     bool    `json:"is_live"`
+		WasLive    bool    `json:"was_live"`
+
+		metadataJSON
+	}
+
+	if err = json.Unmarshal(raw, &info); err != nil {
+		return nil, fmt.Errorf("failed to decode the metadata: %w", err)
+	}
+
+	return &Info{
+		ID:         info.ID,
+		Title:      info.Title,
+		WebpageURL: info.WebpageURL,
+		Extractor:  info.Extractor,
+		Duration:   time.Duration(info.Duration * float64(time.Second)),
+		IsLive:     info.IsLive,
+		WasLive:    info.WasLive,
+		InfoJSON:   raw,
+		Metadata:   info.metadata(),
+	}, nil
+}
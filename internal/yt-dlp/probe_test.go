@@ -0,0 +1,104 @@
+package ytdlp_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	ytdlp "gh.tarampamp.am/video-dl-bot/internal/yt-dlp"
+)
+
+// runnerFunc is a fake runner, that calls the function.
+type runnerFunc func(args []string) (*ytdlp.RunResult, error)
+
+func (f runnerFunc) Run(_ context.Context, _ string, args ...string) (*ytdlp.RunResult, error) {
+	return f(args)
+}
+
+const probeOutput = `{"id":"foo","title":"Foo","extractor":"youtube","webpage_url":"https://example.com/live",` +
+	`"duration":90.5,"filesize_approx":1048576.5,"is_live":true,"channel":"Channel",` +
+	`"channel_url":"https://example.com/@channel","upload_date":"20250115","view_count":1000,"like_count":10,` +
+	`"tags":["foo","bar"],"chapters":[{"title":"Intro","start_time":0,"end_time":30},` +
+	`{"title":"Main","start_time":30,"end_time":90.5}],"width":1920,"height":1080,"fps":29.97,` +
+	`"vcodec":"avc1.640028","acodec":"none","format_id":"137+140","thumbnails":[` +
+	`{"url":"https://example.com/small.jpg"},{"url":"https://example.com/large.jpg","width":1280,"height":720}]}`
+
+func TestProbe(t *testing.T) {
+	t.Parallel()
+
+	var gotArgs []string
+
+	info, err := ytdlp.Probe(context.Background(), "https://example.com/live",
+		ytdlp.WithCookiesFile("/tmp/cookies.txt"),
+		ytdlp.WithProxy("socks5://127.0.0.1:1080"),
+		ytdlp.WithRunner(runnerFunc(func(args []string) (*ytdlp.RunResult, error) {
+			gotArgs = args
+
+			return &ytdlp.RunResult{Stdout: strings.NewReader(probeOutput)}, nil
+		})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := (ytdlp.Info{
+		ID:         "foo",
+		Title:      "Foo",
+		WebpageURL: "https://example.com/live",
+		Extractor:  "youtube",
+		Duration:   90500 * time.Millisecond,
+		IsLive:     true,
+		InfoJSON:   []byte(probeOutput),
+		Metadata: ytdlp.Metadata{
+			Uploader:   "Channel",
+			ChannelURL: "https://example.com/@channel",
+			UploadDate: time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC),
+			ViewCount:  1000,
+			LikeCount:  10,
+			Tags:       []string{"foo", "bar"},
+			Chapters: []ytdlp.Chapter{
+				{Title: "Intro", End: 30 * time.Second},
+				{Title: "Main", Start: 30 * time.Second, End: 90500 * time.Millisecond},
+			},
+			Width:      1920,
+			Height:     1080,
+			FPS:        29.97,
+			VideoCodec: "avc1.640028",
+			FileSize:   1 << 20,
+			FormatID:   "137+140",
+			Thumbnails: []ytdlp.Thumbnail{
+				{URL: "https://example.com/small.jpg"},
+				{URL: "https://example.com/large.jpg", Width: 1280, Height: 720},
+			},
+		},
+	}); !reflect.DeepEqual(*info, want) {
+		t.Errorf("unexpected info: %+v", *info)
+	}
+
+	if got := info.BestThumbnail(); got != "https://example.com/large.jpg" {
+		t.Errorf("unexpected best thumbnail: %s", got)
+	}
+
+	for _, want := range []string{"--skip-download", "--dump-json", "--cookies", "--proxy", "https://example.com/live"} {
+		if !slices.Contains(gotArgs, want) {
+			t.Errorf("expected the %q argument, got %v", want, gotArgs)
+		}
+	}
+
+	if slices.Contains(gotArgs, "--format") {
+		t.Errorf("the format should not be selected, got %v", gotArgs)
+	}
+
+	_, err = ytdlp.Probe(context.Background(), "https://example.com",
+		ytdlp.WithRunner(runnerFunc(func([]string) (*ytdlp.RunResult, error) {
+			return nil, errors.New("exit status 1: ERROR: Unsupported URL: https://example.com")
+		})),
+	)
+	if !errors.Is(err, ytdlp.ErrUnsupported) {
+		t.Errorf("expected ErrUnsupported, got %v", err)
+	}
+}
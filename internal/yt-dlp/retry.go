@@ -0,0 +1,238 @@
+package ytdlp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+type (
+	// RetryPolicy describes how the failed downloads are retried (see DownloadWithRetry). The download is tried
+	// with the preferred settings first, then with the fallback formats, with/without cookies and with the
+	// fallback JavaScript runtimes (each step changes a single setting). Every step is retried with the
+	// exponential backoff while the errors are transient.
+	RetryPolicy struct {
+		Attempts           int           // attempts per step for the transient errors (1 or less = no retries)
+		Backoff            time.Duration // delay before the second attempt (doubled for each next one)
+		MaxBackoff         time.Duration // maximum delay between the attempts (0 = no limit)
+		FallbackFormats    []string      // format selectors to try when the preferred one fails (e.g., "best")
+		WithoutCookies     bool          // retry without cookies, when the download with them fails
+		CookiesFile        string        // cookies file to retry with, when the download without cookies fails
+		FallbackJSRuntimes string        // JavaScript runtimes to retry with (e.g., "deno")
+		OnAttempt          func(Attempt) // called after each failed attempt (optional)
+	}
+
+	// Attempt describes a single failed download attempt.
+	Attempt struct {
+		Number   int           // attempt number (starting from 1)
+		Step     string        // human-readable step description (e.g., `format "best"`)
+		Err      error         // the error returned by Download
+		Duration time.Duration // how long the attempt took
+	}
+
+	// RetryError is returned by DownloadWithRetry when all the attempts fail. It unwraps to the error of the
+	// last attempt.
+	RetryError struct {
+		Attempts []Attempt
+	}
+
+	// retryStep is a set of options that differs from the preferred settings.
+	retryStep struct {
+		name string
+		opts []Option
+	}
+)
+
+// DefaultRetryPolicy returns the retry policy with reasonable defaults.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		Attempts:        3,                //nolint:mnd
+		Backoff:         2 * time.Second,  //nolint:mnd
+		MaxBackoff:      30 * time.Second, //nolint:mnd
+		FallbackFormats: []string{"best"},
+		WithoutCookies:  true,
+	}
+}
+
+// Error implements the error interface.
+func (e *RetryError) Error() string {
+	if len(e.Attempts) == 0 {
+		return "no download attempts were made"
+	}
+
+	var last = e.Attempts[len(e.Attempts)-1]
+
+	return fmt.Sprintf("%d attempt(s) failed, the last one (%s): %s", len(e.Attempts), last.Step, last.Err)
+}
+
+// Unwrap returns the error of the last attempt.
+func (e *RetryError) Unwrap() error {
+	if len(e.Attempts) == 0 {
+		return nil
+	}
+
+	return e.Attempts[len(e.Attempts)-1].Err
+}
+
+// Summary returns a short human-readable list of the attempts with the error classes (one per line; the
+// consecutive attempts of the same step with the same error class are grouped), suitable for the end users.
+func (e *RetryError) Summary() string {
+	var (
+		lines []string
+		count int
+	)
+
+	for i, a := range e.Attempts {
+		count++
+
+		if next := i + 1; next < len(e.Attempts) &&
+			e.Attempts[next].Step == a.Step && ErrorClass(e.Attempts[next].Err) == ErrorClass(a.Err) {
+			continue
+		}
+
+		var line = fmt.Sprintf("%s: %s", a.Step, ErrorClass(a.Err))
+		if count > 1 {
+			line += fmt.Sprintf(" (×%d)", count)
+		}
+
+		lines, count = append(lines, line), 0
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// DownloadWithRetry downloads the video like Download does, but retries the failed downloads according to the
+// policy. The retries are stopped early when the URL is not supported, the site rate-limits the requests (a
+// different IP address is needed, see WithProxy), or the context is canceled. On failure, the returned error is
+// *RetryError (wrapped with the module prefix).
+func DownloadWithRetry(ctx context.Context, in string, policy RetryPolicy, opts ...Option) (*Downloaded, error) {
+	var steps = policy.steps(options{}.Apply(opts...), true)
+
+	return withRetry(ctx, policy, steps, opts, func(opts ...Option) (*Downloaded, error) {
+		return Download(ctx, in, opts...)
+	})
+}
+
+// ProbeWithRetry extracts the video metadata like Probe does, but retries the failed attempts according to the
+// policy, like DownloadWithRetry does (the fallback formats are not tried, as the formats are not selected by the
+// probe).
+func ProbeWithRetry(ctx context.Context, in string, policy RetryPolicy, opts ...Option) (*Info, error) {
+	var steps = policy.steps(options{}.Apply(opts...), false)
+
+	return withRetry(ctx, policy, steps, opts, func(opts ...Option) (*Info, error) {
+		return Probe(ctx, in, opts...)
+	})
+}
+
+// withRetry calls the function with the options of each retry step (added to the given ones), until it succeeds,
+// retrying the transient errors with the exponential backoff (see DownloadWithRetry).
+func withRetry[T any](
+	ctx context.Context,
+	policy RetryPolicy,
+	steps []retryStep,
+	opts []Option,
+	fn func(...Option) (T, error),
+) (_ T, outErr error) {
+	defer func() {
+		if outErr != nil {
+			outErr = fmt.Errorf("%s: %w", errPrefix, outErr)
+		}
+	}()
+
+	var (
+		retryErr RetryError
+		zero     T
+	)
+
+steps:
+	for _, step := range steps {
+		var delay = policy.Backoff
+
+		for try := 1; ; try++ {
+			var startedAt = time.Now()
+
+			res, err := fn(append(slices.Clip(opts), step.opts...)...)
+			if err == nil {
+				return res, nil
+			}
+
+			if ctxErr := ctx.Err(); ctxErr != nil { // the process is killed, the error is not informative
+				err = fmt.Errorf("%w: %w", ctxErr, err)
+			}
+
+			var attempt = Attempt{
+				Number:   len(retryErr.Attempts) + 1,
+				Step:     step.name,
+				Err:      err,
+				Duration: time.Since(startedAt),
+			}
+
+			retryErr.Attempts = append(retryErr.Attempts, attempt)
+
+			if policy.OnAttempt != nil {
+				policy.OnAttempt(attempt)
+			}
+
+			switch {
+			case ctx.Err() != nil, errors.Is(err, ErrUnsupported), errors.Is(err, ErrRateLimited):
+				return zero, &retryErr // retries can't help
+			case !errors.Is(err, ErrTransient) || try >= policy.Attempts:
+				continue steps
+			}
+
+			if sleepErr := sleep(ctx, delay); sleepErr != nil {
+				return zero, &retryErr
+			}
+
+			if delay *= 2; policy.MaxBackoff > 0 && delay > policy.MaxBackoff {
+				delay = policy.MaxBackoff
+			}
+		}
+	}
+
+	return zero, &retryErr
+}
+
+// steps returns the retry steps for the given (preferred) options. The first step is always the preferred
+// settings. The fallback formats are included, if the formats are selected.
+func (p RetryPolicy) steps(o options, formats bool) []retryStep {
+	var steps = []retryStep{{name: "preferred settings"}}
+
+	for _, format := range p.FallbackFormats {
+		if formats && format != "" && format != o.format {
+			steps = append(steps, retryStep{name: fmt.Sprintf("format %q", format), opts: []Option{WithFormat(format)}})
+		}
+	}
+
+	switch {
+	case o.cookiesFile != "" && p.WithoutCookies:
+		steps = append(steps, retryStep{name: "without cookies", opts: []Option{WithCookiesFile("")}})
+	case o.cookiesFile == "" && p.CookiesFile != "":
+		steps = append(steps, retryStep{name: "with cookies", opts: []Option{WithCookiesFile(p.CookiesFile)}})
+	}
+
+	if p.FallbackJSRuntimes != "" && p.FallbackJSRuntimes != o.jsRuntimes {
+		steps = append(steps, retryStep{
+			name: fmt.Sprintf("JS runtimes %q", p.FallbackJSRuntimes),
+			opts: []Option{WithJSRuntimes(p.FallbackJSRuntimes)},
+		})
+	}
+
+	return steps
+}
+
+// sleep waits for the given duration or until the context is canceled.
+func sleep(ctx context.Context, d time.Duration) error {
+	var timer = time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,368 @@
+package ytdlp_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	ytdlp "gh.tarampamp.am/video-dl-bot/internal/yt-dlp"
+)
+
+// scriptedRunner is a fake runner, that returns the scripted errors one by one (nil means success - in this case,
+// the result files are created in the output directory, like yt-dlp does).
+type scriptedRunner struct {
+	mu     sync.Mutex
+	script []error
+	calls  [][]string // arguments of each call
+}
+
+func (r *scriptedRunner) Run(_ context.Context, _ string, args ...string) (*ytdlp.RunResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.calls = append(r.calls, args)
+
+	if len(r.script) == 0 {
+		return nil, errors.New("unexpected call")
+	}
+
+	var err = r.script[0]
+
+	r.script = r.script[1:]
+
+	if err != nil {
+		return nil, err
+	}
+
+	var dir = argValue(args, "--paths")
+
+	if wErr := os.WriteFile(filepath.Join(dir, "result.mp4"), []byte("video"), 0o600); wErr != nil {
+		return nil, wErr
+	}
+
+	var info = []byte(`{"id":"foo","ext":"mp4"}`)
+
+	if wErr := os.WriteFile(filepath.Join(dir, "result.info.json"), info, 0o600); wErr != nil {
+		return nil, wErr
+	}
+
+	return &ytdlp.RunResult{Stdout: strings.NewReader(""), Stderr: strings.NewReader("")}, nil
+}
+
+// argValue returns the value of the argument (e.g., "--format") or an empty string if it's not set.
+func argValue(args []string, name string) string {
+	if i := slices.Index(args, name); i >= 0 && i+1 < len(args) {
+		return args[i+1]
+	}
+
+	return ""
+}
+
+func TestDownloadWithRetry(t *testing.T) { //nolint:funlen
+	t.Parallel()
+
+	var (
+		errTransient   = errors.New("exit status 1: ERROR: Unable to download webpage: HTTP Error 503")
+		errOther       = errors.New("exit status 1: ERROR: something went wrong")
+		errRateLimited = errors.New("exit status 1: ERROR: HTTP Error 429: Too Many Requests")
+		errUnsupported = errors.New("exit status 1: ERROR: Unsupported URL: https://example.com")
+		policy         = ytdlp.RetryPolicy{
+			Attempts:           3,
+			Backoff:            time.Millisecond,
+			FallbackFormats:    []string{"best"},
+			WithoutCookies:     true,
+			FallbackJSRuntimes: "deno",
+		}
+	)
+
+	type call struct{ format, cookies, jsRuntimes string }
+
+	for name, tc := range map[string]struct {
+		giveScript []error
+		giveOpts   []ytdlp.Option
+		wantCalls  []call
+		wantErr    error  // nil = success expected
+		wantSumm   string // expected summary of the attempts (for failures)
+	}{
+		"success on the first attempt": {
+			giveScript: []error{nil},
+			wantCalls:  []call{{format: "", cookies: ""}},
+		},
+		"transient errors are retried": {
+			giveScript: []error{errTransient, errTransient, nil},
+			wantCalls:  []call{{}, {}, {}},
+		},
+		"fallback format": {
+			giveScript: []error{errOther, nil},
+			wantCalls:  []call{{}, {format: "best"}},
+		},
+		"all the steps fail": {
+			giveScript: []error{errTransient, errTransient, errTransient, errOther, errOther, errOther},
+			giveOpts:   []ytdlp.Option{ytdlp.WithCookiesFile("/cookies.txt"), ytdlp.WithJSRuntimes("node")},
+			wantCalls: []call{
+				{cookies: "/cookies.txt", jsRuntimes: "node"},
+				{cookies: "/cookies.txt", jsRuntimes: "node"},
+				{cookies: "/cookies.txt", jsRuntimes: "node"},
+				{format: "best", cookies: "/cookies.txt", jsRuntimes: "node"},
+				{cookies: "", jsRuntimes: "node"},
+				{cookies: "/cookies.txt", jsRuntimes: "deno"},
+			},
+			wantErr: errOther,
+			wantSumm: "preferred settings: transient (×3)\n" +
+				"format \"best\": other\n" +
+				"without cookies: other\n" +
+				"JS runtimes \"deno\": other",
+		},
+		"rate limiting stops the retries": {
+			giveScript: []error{errTransient, errRateLimited},
+			wantCalls:  []call{{}, {}},
+			wantErr:    ytdlp.ErrRateLimited,
+			wantSumm:   "preferred settings: transient\npreferred settings: rate-limited",
+		},
+		"unsupported url stops the retries": {
+			giveScript: []error{errUnsupported},
+			wantCalls:  []call{{}},
+			wantErr:    ytdlp.ErrUnsupported,
+			wantSumm:   "preferred settings: unsupported",
+		},
+		"the preferred format is not repeated": {
+			giveScript: []error{errOther, errOther},
+			giveOpts:   []ytdlp.Option{ytdlp.WithFormat("best")},
+			wantCalls:  []call{{format: "best"}, {format: "best", jsRuntimes: "deno"}},
+			wantErr:    errOther,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				runner   = &scriptedRunner{script: tc.giveScript}
+				attempts []ytdlp.Attempt
+				p        = policy
+			)
+
+			p.OnAttempt = func(a ytdlp.Attempt) { attempts = append(attempts, a) }
+
+			dl, err := ytdlp.DownloadWithRetry(
+				t.Context(),
+				"https://example.com/video",
+				p,
+				append([]ytdlp.Option{ytdlp.WithRunner(runner)}, tc.giveOpts...)...,
+			)
+
+			if len(runner.calls) != len(tc.wantCalls) {
+				t.Fatalf("expected %d calls, got %d", len(tc.wantCalls), len(runner.calls))
+			}
+
+			for i, want := range tc.wantCalls {
+				var args = runner.calls[i]
+
+				if want.format != "" && argValue(args, "--format") != want.format {
+					t.Errorf("call %d: expected format %q, got %q", i, want.format, argValue(args, "--format"))
+				}
+
+				if got := argValue(args, "--cookies"); got != want.cookies {
+					t.Errorf("call %d: expected cookies %q, got %q", i, want.cookies, got)
+				}
+
+				if got := argValue(args, "--js-runtimes"); got != want.jsRuntimes {
+					t.Errorf("call %d: expected JS runtimes %q, got %q", i, want.jsRuntimes, got)
+				}
+			}
+
+			if tc.wantErr == nil {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+
+				_ = dl.Cleanup()
+
+				if len(attempts) != len(tc.wantCalls)-1 {
+					t.Errorf("expected %d failed attempts, got %d", len(tc.wantCalls)-1, len(attempts))
+				}
+
+				return
+			}
+
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("expected error %v, got %v", tc.wantErr, err)
+			}
+
+			var retryErr *ytdlp.RetryError
+			if !errors.As(err, &retryErr) {
+				t.Fatalf("expected *RetryError, got %T", err)
+			}
+
+			if len(retryErr.Attempts) != len(tc.wantCalls) || len(attempts) != len(tc.wantCalls) {
+				t.Errorf("expected %d attempts, got %d (%d reported)", len(tc.wantCalls), len(retryErr.Attempts), len(attempts))
+			}
+
+			if tc.wantSumm != "" && retryErr.Summary() != tc.wantSumm {
+				t.Errorf("unexpected summary:\n%s\nwant:\n%s", retryErr.Summary(), tc.wantSumm)
+			}
+		})
+	}
+}
+
+func TestDownloadWithRetry_Canceled(t *testing.T) {
+	t.Parallel()
+
+	ctx, cancel := context.WithCancel(t.Context())
+
+	var runner = &scriptedRunner{script: []error{errors.New("exit status 1: ERROR: timed out"), nil}}
+
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	_, err := ytdlp.DownloadWithRetry(ctx, "https://example.com/video", ytdlp.RetryPolicy{
+		Attempts: 2,
+		Backoff:  time.Hour,
+	}, ytdlp.WithRunner(runner))
+
+	if !errors.Is(err, ytdlp.ErrTransient) {
+		t.Errorf("expected the error of the last attempt, got %v", err)
+	}
+
+	if len(runner.calls) != 1 {
+		t.Errorf("expected 1 call, got %d", len(runner.calls))
+	}
+}
+
+func TestProbeWithRetry(t *testing.T) {
+	t.Parallel()
+
+	var (
+		errTransient = errors.New("exit status 1: ERROR: Unable to download webpage: HTTP Error 503")
+		errOther     = errors.New("exit status 1: ERROR: something went wrong")
+		policy       = ytdlp.RetryPolicy{
+			Attempts:        2,
+			Backoff:         time.Millisecond,
+			FallbackFormats: []string{"best"},
+			WithoutCookies:  true,
+		}
+	)
+
+	for name, tc := range map[string]struct {
+		giveScript  []error
+		wantErr     bool
+		wantCalls   int
+		wantCookies []bool // whether the cookies are passed to each call
+	}{
+		"transient error is retried": {
+			giveScript:  []error{errTransient, nil},
+			wantCalls:   2,
+			wantCookies: []bool{true, true},
+		},
+		"no fallback formats": {
+			giveScript:  []error{errOther, nil},
+			wantCalls:   2,
+			wantCookies: []bool{true, false},
+		},
+		"all attempts failed": {
+			giveScript:  []error{errTransient, errTransient, errOther},
+			wantErr:     true,
+			wantCalls:   3,
+			wantCookies: []bool{true, true, false},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				script = slices.Clone(tc.giveScript)
+				calls  [][]string
+			)
+
+			info, err := ytdlp.ProbeWithRetry(t.Context(), "https://example.com/video", policy,
+				ytdlp.WithCookiesFile("/tmp/cookies.txt"),
+				ytdlp.WithRunner(runnerFunc(func(args []string) (*ytdlp.RunResult, error) {
+					calls = append(calls, args)
+
+					if len(script) == 0 {
+						return nil, errors.New("unexpected call")
+					}
+
+					var sErr = script[0]
+
+					if script = script[1:]; sErr != nil {
+						return nil, sErr
+					}
+
+					return &ytdlp.RunResult{Stdout: strings.NewReader(probeOutput)}, nil
+				})),
+			)
+
+			if tc.wantErr {
+				var retryErr *ytdlp.RetryError
+
+				if !errors.As(err, &retryErr) || len(retryErr.Attempts) != tc.wantCalls {
+					t.Errorf("expected the retry error with %d attempts, got %v", tc.wantCalls, err)
+				}
+			} else if err != nil || info.ID != "foo" {
+				t.Fatalf("unexpected result: %+v, %v", info, err)
+			}
+
+			if len(calls) != tc.wantCalls {
+				t.Fatalf("expected %d calls, got %d", tc.wantCalls, len(calls))
+			}
+
+			for i, args := range calls {
+				if got := slices.Contains(args, "--cookies"); got != tc.wantCookies[i] {
+					t.Errorf("call %d: cookies passed = %t, want %t", i+1, got, tc.wantCookies[i])
+				}
+			}
+		})
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	t.Parallel()
+
+	for want, err := range map[string]error{
+		"":             nil,
+		"canceled":     context.Canceled,
+		"rate-limited": ytdlp.ErrRateLimited,
+		"forbidden":    errors.Join(errors.New("foo"), ytdlp.ErrForbidden),
+		"unavailable":  ytdlp.ErrUnavailable,
+		"unsupported":  ytdlp.ErrUnsupported,
+		"extractor":    ytdlp.ErrExtractor,
+		"transient":    ytdlp.ErrTransient,
+		"other":        errors.New("foo"),
+	} {
+		if got := ytdlp.ErrorClass(err); got != want {
+			t.Errorf("ErrorClass(%v) = %q, want %q", err, got, want)
+		}
+	}
+}
+
+func TestErrorClass_Output(t *testing.T) {
+	t.Parallel()
+
+	for output, want := range map[string]string{
+		"ERROR: fragment 3 not found, unable to continue":           "transient",
+		"ERROR: Unable to download fragment: HTTP Error 404":        "transient",
+		"ERROR: Unable to download webpage: HTTP Error 503":         "transient",
+		"ERROR: [youtube] foo: Video unavailable":                   "unavailable",
+		"ERROR: Postprocessing: fragment files could not be merged": "other",
+		"ERROR: fragments of the live stream are not supported":     "other",
+	} {
+		t.Run(output, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := ytdlp.Probe(t.Context(), "https://example.com",
+				ytdlp.WithRunner(runnerFunc(func([]string) (*ytdlp.RunResult, error) {
+					return nil, errors.New("exit status 1: " + output)
+				})),
+			)
+
+			if got := ytdlp.ErrorClass(err); got != want {
+				t.Errorf("want %q, got %q", want, got)
+			}
+		})
+	}
+}
@@ -2,7 +2,9 @@ package ytdlp
 
 import (
 	"bytes"
+	"cmp"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os/exec"
@@ -26,8 +28,15 @@ type (
 	}
 )
 
-// systemRunner is the default (system) runner for executing the external command.
-type systemRunner struct{}
+// defaultKillGrace is how long the canceled command has to exit after SIGTERM, before it's killed with SIGKILL.
+const defaultKillGrace = 5 * time.Second
+
+// systemRunner is the default (system) runner for executing the external command. The command is started in its
+// own process group, so when the context is canceled, its children (e.g., ffmpeg, spawned by yt-dlp for merging
+// the formats) are stopped too.
+type systemRunner struct {
+	killGrace time.Duration // see defaultKillGrace (used, if zero)
+}
 
 var _ runner = (*systemRunner)(nil) // compile-time assertion to ensure systemRunner implements runner interface
 
@@ -44,8 +53,43 @@ func (r systemRunner) Run(ctx context.Context, exe string, args ...string) (*Run
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
 
+	// on the context cancellation, stop the whole process group gracefully (SIGTERM), and kill it (SIGKILL) after
+	// the grace period (the command output is not waited for longer)
+	var grace = cmp.Or(r.killGrace, defaultKillGrace)
+
+	cmd.WaitDelay = grace
+	useProcessGroup(cmd)
+
+	var canceledAt time.Time // when the command is asked to stop (set by the cmd.Wait goroutine, before Run returns)
+
+	if cancel := cmd.Cancel; cancel != nil {
+		cmd.Cancel = func() error {
+			canceledAt = time.Now()
+
+			return cancel()
+		}
+	}
+
 	// run the command and handle any errors
-	if err := cmd.Run(); err != nil {
+	var err = cmd.Run()
+
+	if err != nil && ctx.Err() != nil {
+		if canceledAt.IsZero() { // the command exited on its own, before the cancellation
+			canceledAt = time.Now()
+		}
+
+		// the children, that are still stopping after SIGTERM (e.g., ffmpeg finalizing the file), get the rest of
+		// the grace period, and the survivors are killed
+		killProcessGroup(cmd, canceledAt.Add(grace))
+
+		if !errors.Is(err, ctx.Err()) {
+			err = fmt.Errorf("%w: %w", ctx.Err(), err) // so the cancellation can be recognized
+		}
+
+		return nil, err
+	}
+
+	if err != nil {
 		// if the stderr buffer has contents, enhance the error with that output
 		if stderr.Len() > 0 {
 			return nil, fmt.Errorf(
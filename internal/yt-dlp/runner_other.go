@@ -0,0 +1,14 @@
+//go:build !unix
+
+package ytdlp
+
+import (
+	"os/exec"
+	"time"
+)
+
+// useProcessGroup does nothing on this platform: the context cancellation kills the command process only.
+func useProcessGroup(*exec.Cmd) {}
+
+// killProcessGroup does nothing on this platform (see useProcessGroup).
+func killProcessGroup(*exec.Cmd, time.Time) {}
@@ -0,0 +1,42 @@
+//go:build unix
+
+package ytdlp
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// killPollInterval is how often the process group is checked for the exit, before it's killed.
+const killPollInterval = 50 * time.Millisecond
+
+// useProcessGroup starts the command in its own process group, and makes the context cancellation send SIGTERM to
+// the whole group (instead of killing the command process only).
+func useProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGTERM) // the negative PID means the process group
+	}
+}
+
+// killProcessGroup waits for the process group of the (started) command to exit until the deadline, and kills the
+// remaining processes with SIGKILL.
+func killProcessGroup(cmd *exec.Cmd, deadline time.Time) {
+	if cmd.Process == nil {
+		return
+	}
+
+	var pgid = -cmd.Process.Pid // the negative PID means the process group
+
+	for time.Now().Before(deadline) {
+		if syscall.Kill(pgid, 0) != nil { // no processes left in the group
+			return
+		}
+
+		time.Sleep(killPollInterval)
+	}
+
+	_ = syscall.Kill(pgid, syscall.SIGKILL)
+}
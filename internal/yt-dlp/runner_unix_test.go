@@ -0,0 +1,155 @@
+//go:build unix
+
+package ytdlp_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	ytdlp "gh.tarampamp.am/video-dl-bot/internal/yt-dlp"
+)
+
+// fakeDownloader is a script, that acts like yt-dlp spawning ffmpeg: it starts a long-running child process (its
+// PID is written to the file, passed as the first argument), and waits for it. With "ignore-term" as the second
+// argument, both processes ignore SIGTERM; with "slow-term", the child takes a while to stop on SIGTERM (like
+// ffmpeg finalizing the file; its output is not attached to the script's one, so it's not waited for), and creates
+// the "<first argument>.done" file when it's done.
+const fakeDownloader = `#!/bin/sh
+[ "$2" = "ignore-term" ] && trap '' TERM
+if [ "$2" = "slow-term" ]; then
+  sh -c 'trap "sleep 0.3; touch $0.done; exit 0" TERM; sleep 60 & wait' "$1" >/dev/null 2>&1 &
+else
+  sleep 60 &
+fi
+echo $! > "$1.tmp" && mv "$1.tmp" "$1"
+wait
+`
+
+func TestSystemRunner_Cancel(t *testing.T) {
+	t.Parallel()
+
+	for name, tc := range map[string]struct {
+		giveMode  string
+		wantAfter time.Duration // the minimal time to stop the processes
+		wantDone  bool          // the child is stopped gracefully
+	}{
+		"stops on SIGTERM":     {giveMode: "", wantAfter: 0},
+		"killed after grace":   {giveMode: "ignore-term", wantAfter: 500 * time.Millisecond},
+		"child stops in grace": {giveMode: "slow-term", wantAfter: 300 * time.Millisecond, wantDone: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				dir     = t.TempDir()
+				script  = filepath.Join(dir, "yt-dlp")
+				pidFile = filepath.Join(dir, "child.pid")
+			)
+
+			if err := os.WriteFile(script, []byte(fakeDownloader), 0o700); err != nil { //nolint:gosec
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var done = make(chan error, 1)
+
+			go func() {
+				_, err := ytdlp.RunForTest(ctx, 500*time.Millisecond, script, pidFile, tc.giveMode)
+
+				done <- err
+			}()
+
+			var childPid = waitForPid(t, pidFile)
+
+			var canceledAt = time.Now()
+
+			cancel()
+
+			select {
+			case err := <-done:
+				if !errors.Is(err, context.Canceled) {
+					t.Errorf("expected the cancellation error, got %v", err)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("the command is not stopped")
+			}
+
+			if elapsed := time.Since(canceledAt); elapsed < tc.wantAfter {
+				t.Errorf("the command is stopped too early (%s), before the grace period", elapsed)
+			}
+
+			if _, err := os.Stat(pidFile + ".done"); tc.wantDone && err != nil {
+				t.Errorf("the child process is not stopped gracefully: %v", err)
+			}
+
+			// the child process is stopped too (not only the script itself)
+			for deadline := time.Now().Add(5 * time.Second); processAlive(childPid); {
+				if time.Now().After(deadline) {
+					t.Fatalf("the child process %d is still running", childPid)
+				}
+
+				time.Sleep(10 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestSystemRunner_NotCanceled(t *testing.T) {
+	t.Parallel()
+
+	res, err := ytdlp.RunForTest(context.Background(), 0, "/bin/sh", "-c", "sleep 0.1 & wait; echo done")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if out, _ := io.ReadAll(res.Stdout); strings.TrimSpace(string(out)) != "done" {
+		t.Errorf("unexpected output: %q", out)
+	}
+}
+
+// waitForPid waits for the PID file, written by the fake downloader.
+func waitForPid(t *testing.T, path string) int {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if data, err := os.ReadFile(path); err == nil {
+			pid, pErr := strconv.Atoi(strings.TrimSpace(string(data)))
+			if pErr != nil {
+				t.Fatal(pErr)
+			}
+
+			return pid
+		}
+	}
+
+	t.Fatal("the child process is not started")
+
+	return 0
+}
+
+// processAlive reports whether the process is running (the zombies, that are not reaped yet, are not).
+func processAlive(pid int) bool {
+	if err := syscall.Kill(pid, 0); err != nil {
+		return false
+	}
+
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return true // no procfs (e.g., on macOS)
+	}
+
+	// the state follows the command name in parentheses, e.g., "123 (sleep) Z ..."
+	var _, rest, _ = strings.Cut(string(stat), ") ")
+
+	return !strings.HasPrefix(rest, "Z")
+}
@@ -0,0 +1,312 @@
+package ytdlp
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultReleasesURL is the base URL of the yt-dlp releases.
+	DefaultReleasesURL = "https://github.com/yt-dlp/yt-dlp/releases"
+
+	// DefaultAsset is the name of the release asset to download (the platform-independent zipimport binary,
+	// requires Python to be installed).
+	DefaultAsset = "yt-dlp"
+
+	checksumsAsset  = "SHA2-256SUMS"  // name of the release asset with the SHA256 checksums of other assets
+	binaryPrefix    = "yt-dlp-bin-"   // prefix of the downloaded binaries (followed by the checksum prefix)
+	maxChecksumsLen = 64 * 1024       // the checksums file is small, larger responses are rejected
+	maxBinaryLen    = 1 << 30         // sanity limit for the binary size
+	updateTimeout   = 5 * time.Minute // default HTTP client timeout for the downloads
+)
+
+type (
+	// Updater downloads the yt-dlp release binaries, verifies and smoke-tests them, and switches the binary used
+	// by default (see ExePath). The binary used before the last update is kept, so the update can be rolled back.
+	// Updater is safe for concurrent use (the updates are serialized).
+	Updater struct {
+		dir         string // writable directory for the downloaded binaries
+		releasesURL string
+		asset       string
+		client      *http.Client
+		runner      runner
+
+		mu       sync.Mutex
+		previous string // path to the binary used before the last update (empty if there were no updates)
+	}
+
+	// UpdaterOption is a function that configures the Updater.
+	UpdaterOption func(*Updater)
+
+	// UpdateResult describes the result of the update (or rollback).
+	UpdateResult struct {
+		Updated         bool   // false if the binary is already up to date
+		Path            string // path to the binary used now
+		Version         string // version of the binary used now
+		PreviousVersion string // version of the binary used before (if known)
+	}
+)
+
+// WithReleasesURL sets the base URL of the releases (useful for mirrors and testing).
+func WithReleasesURL(u string) UpdaterOption {
+	return func(up *Updater) { up.releasesURL = strings.TrimRight(u, "/") }
+}
+
+// WithAsset sets the name of the release asset to download (e.g., "yt-dlp_linux" for the standalone binary).
+func WithAsset(name string) UpdaterOption { return func(up *Updater) { up.asset = name } }
+
+// WithUpdaterHTTPClient sets the HTTP client used to download the releases.
+func WithUpdaterHTTPClient(c *http.Client) UpdaterOption { return func(up *Updater) { up.client = c } }
+
+// WithUpdaterRunner sets the command runner used for the smoke tests (useful for testing).
+func WithUpdaterRunner(r runner) UpdaterOption { return func(up *Updater) { up.runner = r } }
+
+// NewUpdater creates a new Updater that stores the downloaded binaries in the given directory (it's created if
+// it doesn't exist).
+func NewUpdater(dir string, opts ...UpdaterOption) (*Updater, error) {
+	var u = Updater{
+		dir:         dir,
+		releasesURL: DefaultReleasesURL,
+		asset:       DefaultAsset,
+		client:      &http.Client{Timeout: updateTimeout},
+		runner:      new(systemRunner),
+	}
+
+	for _, opt := range opts {
+		opt(&u)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil { //nolint:mnd
+		return nil, fmt.Errorf("%s: failed to create the updates directory: %w", errPrefix, err)
+	}
+
+	return &u, nil
+}
+
+// Update downloads the given release version (e.g., "2025.01.15"; empty string or "latest" for the latest one),
+// verifies its checksum, runs a smoke test, and switches the default binary to it. On any failure, the current
+// binary is kept. If the current binary is the same as the requested release, nothing is changed.
+func (u *Updater) Update(ctx context.Context, version string) (_ *UpdateResult, outErr error) { //nolint:funlen
+	defer func() {
+		if outErr != nil {
+			outErr = fmt.Errorf("%s: update failed: %w", errPrefix, outErr)
+		}
+	}()
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	var baseURL = u.releasesURL + "/download/" + version
+	if version == "" || version == "latest" {
+		baseURL = u.releasesURL + "/latest/download"
+	}
+
+	wantSum, err := u.checksum(ctx, baseURL+"/"+checksumsAsset)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		current        = ExePath()
+		currentVer, _  = Version(ctx, WithExePath(current), WithRunner(u.runner))
+		currentSum, _  = fileChecksum(current)
+		targetPath     = filepath.Join(u.dir, binaryPrefix+wantSum[:16])
+		alreadyUpdated = currentSum == wantSum
+	)
+
+	if alreadyUpdated {
+		return &UpdateResult{Path: current, Version: currentVer, PreviousVersion: currentVer}, nil
+	}
+
+	if err = u.download(ctx, baseURL+"/"+u.asset, targetPath, wantSum); err != nil {
+		return nil, err
+	}
+
+	newVer, err := Version(ctx, WithExePath(targetPath), WithRunner(u.runner))
+	if err != nil {
+		_ = os.Remove(targetPath)
+
+		return nil, fmt.Errorf("smoke test failed (the current binary is kept): %w", err)
+	}
+
+	exePath.Store(&targetPath)
+	u.previous = current
+	u.cleanup(targetPath, current)
+
+	return &UpdateResult{Updated: true, Path: targetPath, Version: newVer, PreviousVersion: currentVer}, nil
+}
+
+// Rollback switches the default binary back to the one used before the last update (or rollback).
+func (u *Updater) Rollback(ctx context.Context) (_ *UpdateResult, outErr error) {
+	defer func() {
+		if outErr != nil {
+			outErr = fmt.Errorf("%s: rollback failed: %w", errPrefix, outErr)
+		}
+	}()
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.previous == "" {
+		return nil, errors.New("there is no previous binary")
+	}
+
+	var current, previous = ExePath(), u.previous
+
+	prevVer, err := Version(ctx, WithExePath(previous), WithRunner(u.runner))
+	if err != nil {
+		return nil, fmt.Errorf("smoke test of the previous binary failed: %w", err)
+	}
+
+	var currentVer, _ = Version(ctx, WithExePath(current), WithRunner(u.runner))
+
+	exePath.Store(&previous)
+	u.previous = current
+
+	return &UpdateResult{Updated: true, Path: previous, Version: prevVer, PreviousVersion: currentVer}, nil
+}
+
+// checksum downloads the checksums file and returns the checksum of the asset.
+func (u *Updater) checksum(ctx context.Context, url string) (string, error) {
+	resp, err := u.get(ctx, url)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	var scanner = bufio.NewScanner(io.LimitReader(resp.Body, maxChecksumsLen))
+
+	for scanner.Scan() { // format: "<sha256>  <asset name>"
+		if fields := strings.Fields(scanner.Text()); len(fields) == 2 && fields[1] == u.asset { //nolint:mnd
+			if sum := strings.ToLower(fields[0]); len(sum) == sha256.Size*2 { //nolint:mnd
+				return sum, nil
+			}
+		}
+	}
+
+	if err = scanner.Err(); err != nil {
+		return "", fmt.Errorf("failed to read the checksums: %w", err)
+	}
+
+	return "", fmt.Errorf("checksum for %q not found in %s", u.asset, url)
+}
+
+// download downloads the binary to the target path, verifying its checksum. The file is written to a temporary
+// file first, so the target path never contains a partially downloaded binary.
+func (u *Updater) download(ctx context.Context, url, target, wantSum string) error {
+	resp, err := u.get(ctx, url)
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	tmp, err := os.CreateTemp(u.dir, ".download-*")
+	if err != nil {
+		return err
+	}
+
+	defer func() { _ = os.Remove(tmp.Name()) }() // no-op after the successful rename
+
+	var hash = sha256.New()
+
+	if _, err = io.Copy(io.MultiWriter(tmp, hash), io.LimitReader(resp.Body, maxBinaryLen)); err != nil {
+		_ = tmp.Close()
+
+		return fmt.Errorf("failed to download the binary: %w", err)
+	}
+
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+
+	if gotSum := hex.EncodeToString(hash.Sum(nil)); gotSum != wantSum {
+		return fmt.Errorf("checksum mismatch: expected %s, got %s", wantSum, gotSum)
+	}
+
+	if err = os.Chmod(tmp.Name(), 0o755); err != nil { //nolint:gosec,mnd // the binary must be executable
+		return err
+	}
+
+	return os.Rename(tmp.Name(), target)
+}
+
+// get performs the GET request and checks the response status.
+func (u *Updater) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+
+		return nil, fmt.Errorf("unexpected status code %d for %s", resp.StatusCode, url)
+	}
+
+	return resp, nil
+}
+
+// cleanup removes the downloaded binaries, except the given ones.
+func (u *Updater) cleanup(keep ...string) {
+	entries, err := os.ReadDir(u.dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		var path = filepath.Join(u.dir, entry.Name())
+
+		if !strings.HasPrefix(entry.Name(), binaryPrefix) || containsPath(keep, path) {
+			continue
+		}
+
+		_ = os.Remove(path)
+	}
+}
+
+// containsPath reports whether the list contains the path (paths are compared after cleaning).
+func containsPath(list []string, path string) bool {
+	for _, p := range list {
+		if filepath.Clean(p) == filepath.Clean(path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// fileChecksum returns the hex-encoded SHA256 checksum of the file.
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() { _ = f.Close() }()
+
+	var hash = sha256.New()
+
+	if _, err = io.Copy(hash, f); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,148 @@
+package ytdlp_test
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	ytdlp "gh.tarampamp.am/video-dl-bot/internal/yt-dlp"
+)
+
+// fakeBinary returns the content of a shell script, that behaves like yt-dlp with the "--version" flag.
+func fakeBinary(version string) []byte {
+	return []byte("#!/bin/sh\necho " + version + "\n")
+}
+
+// releaseServer is a local stand-in for the GitHub releases. The releases map contains the version (or
+// "latest") to the binary content; the checksums can be overridden to simulate the corrupted downloads.
+func releaseServer(t *testing.T, releases map[string][]byte, badChecksums map[string]bool) *httptest.Server {
+	t.Helper()
+
+	var srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var version, asset string
+
+		switch parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/"); {
+		case len(parts) == 3 && parts[0] == "latest" && parts[1] == "download":
+			version, asset = "latest", parts[2]
+		case len(parts) == 3 && parts[0] == "download":
+			version, asset = parts[1], parts[2]
+		default:
+			http.NotFound(w, r)
+
+			return
+		}
+
+		bin, ok := releases[version]
+		if !ok {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		switch asset {
+		case "SHA2-256SUMS":
+			var sum = sha256.Sum256(bin)
+			if badChecksums[version] {
+				sum = sha256.Sum256([]byte("something else"))
+			}
+
+			_, _ = fmt.Fprintf(w, "%x  yt-dlp.exe\n%x  yt-dlp\n", sha256.Sum256([]byte("exe")), sum)
+		case "yt-dlp":
+			_, _ = w.Write(bin)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestUpdater(t *testing.T) { //nolint:funlen // not parallel, since the default binary path is changed
+	var (
+		initial = filepath.Join(t.TempDir(), "yt-dlp")
+		srv     = releaseServer(t, map[string][]byte{
+			"latest":     fakeBinary("2025.02.01"),
+			"2025.01.01": fakeBinary("2025.01.01"),
+			"2024.12.01": []byte("#!/bin/sh\nexit 1\n"), // broken binary
+			"2024.11.01": fakeBinary("2024.11.01"),
+		}, map[string]bool{"2024.11.01": true})
+	)
+
+	if err := os.WriteFile(initial, fakeBinary("2024.10.01"), 0o700); err != nil { //nolint:gosec
+		t.Fatal(err)
+	}
+
+	ytdlp.SetExePathForTest(t, initial)
+
+	u, err := ytdlp.NewUpdater(filepath.Join(t.TempDir(), "bin"), ytdlp.WithReleasesURL(srv.URL+"/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = u.Rollback(t.Context()); err == nil {
+		t.Fatal("expected an error, since there were no updates")
+	}
+
+	// pinned version
+	res, err := u.Update(t.Context(), "2025.01.01")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !res.Updated || res.Version != "2025.01.01" || res.PreviousVersion != "2024.10.01" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	if ytdlp.ExePath() != res.Path {
+		t.Fatalf("expected the default binary to be switched to %s, got %s", res.Path, ytdlp.ExePath())
+	}
+
+	if v, _ := ytdlp.Version(t.Context()); v != "2025.01.01" {
+		t.Fatalf("expected the new version to be used, got %q", v)
+	}
+
+	// the same version again - nothing changes
+	if res, err = u.Update(t.Context(), "2025.01.01"); err != nil || res.Updated {
+		t.Fatalf("expected no update, got %+v (error: %v)", res, err)
+	}
+
+	// the broken binary is not used
+	if _, err = u.Update(t.Context(), "2024.12.01"); err == nil || !strings.Contains(err.Error(), "smoke test") {
+		t.Fatalf("expected the smoke test error, got %v", err)
+	}
+
+	// checksum mismatch
+	if _, err = u.Update(t.Context(), "2024.11.01"); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("expected the checksum error, got %v", err)
+	}
+
+	// unknown version
+	if _, err = u.Update(t.Context(), "1999.01.01"); err == nil {
+		t.Fatal("expected an error for the unknown version")
+	}
+
+	if v, _ := ytdlp.Version(t.Context()); v != "2025.01.01" {
+		t.Fatalf("expected the current binary to be kept after the failures, got %q", v)
+	}
+
+	// latest version
+	if res, err = u.Update(t.Context(), ""); err != nil || !res.Updated || res.Version != "2025.02.01" {
+		t.Fatalf("unexpected result: %+v (error: %v)", res, err)
+	}
+
+	// rollback to the previous one
+	if res, err = u.Rollback(t.Context()); err != nil || res.Version != "2025.01.01" {
+		t.Fatalf("unexpected rollback result: %+v (error: %v)", res, err)
+	}
+
+	if v, _ := ytdlp.Version(t.Context()); v != "2025.01.01" {
+		t.Fatalf("expected the previous version to be used, got %q", v)
+	}
+}
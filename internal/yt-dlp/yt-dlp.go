@@ -1,6 +1,7 @@
 package ytdlp
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -8,35 +9,119 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
 const errPrefix = "yt-dlp" // error prefix for all yt-dlp errors
 
-// Locate yt-dlp binary once at package initialization. Will be empty string if not found.
-var exePath, _ = exec.LookPath("yt-dlp") //nolint:gochecknoglobals
+// defaultFormat returns the default format selector, that prefers mp4 video with m4a audio under the maximal file
+// size (in bytes).
+func defaultFormat(maxSize int64) string {
+	return fmt.Sprintf("bv*[ext=mp4][filesize<%[1]d]+ba[ext=m4a][filesize<%[1]d]/"+
+		"bv*[ext=mp4]+ba[ext=m4a]/best[filesize<%[1]d]/best", maxSize)
+}
+
+// Default file size limits (the downloads outside the limits fail with ErrFileSize).
+const (
+	DefaultMinFileSize int64 = 50 << 10 // 50 KiB
+	DefaultMaxFileSize int64 = 2 << 30  // 2 GiB
+)
+
+// resultsListName is the name of the file in the temporary directory, where yt-dlp writes the paths of the
+// downloaded files (after all the post-processing), one per line.
+const resultsListName = "results.txt"
+
+// Patterns of the temporary directories and files, created by Download in the working directory.
+const (
+	tempDirPattern    = "yt-dlp-*"
+	resultFilePattern = "yt-dlp-result-*" // the result files were moved out of the directory in the previous versions
+)
+
+// TempPatterns returns the glob patterns of the temporary directories and files, created by Download in the working
+// directory (the files that are left after a crash can be found by them).
+func TempPatterns() []string { return []string{tempDirPattern, resultFilePattern} }
+
+// exePath is the path to the yt-dlp binary used by default. It's located once at package initialization (empty
+// string if not found), and can be switched at runtime by the Updater.
+var exePath atomic.Pointer[string] //nolint:gochecknoglobals
 
-// Downloaded holds metadata and file path of the downloaded video.
+func init() { //nolint:gochecknoinits
+	var path, _ = exec.LookPath("yt-dlp")
+
+	exePath.Store(&path)
+}
+
+// ExePath returns the path to the yt-dlp binary used by default (empty string if yt-dlp is not found).
+func ExePath() string { return *exePath.Load() }
+
+// MediaKind is the kind of the downloaded media file.
+type MediaKind string
+
+const (
+	MediaVideo MediaKind = "video" // video (or audio) file
+	MediaImage MediaKind = "image" // image (e.g., a photo of the Instagram carousel or the Twitter/X post)
+)
+
+// Item is a single downloaded media file: the video itself, or an entry of the multi-entry post (e.g., a photo or a
+// video of the Instagram carousel).
+type Item struct {
+	Filepath string        // Local path to the downloaded file
+	Kind     MediaKind     // Kind of the media (video or image)
+	ID       string        // Entry ID
+	Title    string        // Entry title
+	Duration time.Duration // Duration of the video (zero for the images)
+	Chapter  *Chapter      // Chapter of the video, the file contains (see WithSplitChapters; nil otherwise)
+}
+
+// Downloaded holds metadata and the downloaded files. For the multi-entry posts (playlists), the metadata describes
+// the post itself, and the items are its entries (in the playlist order).
 type Downloaded struct {
-	Filepath    string        // Local path to the downloaded video file
+	Items       []Item        // Downloaded files (a single one for the regular video)
 	ID          string        // Video ID (e.g., "daOyEt3nTnY")
 	Title       string        // Short title of the video
 	FullTitle   string        // Full title, often includes ID or extra info
-	Description string        // Description text of the video
+	Description string        // Description text of the video (or the post caption)
 	WebpageURL  string        // Original video URL
 	MediaType   string        // Type of media (e.g., "short", "video")
 	Extractor   string        // Source site or extractor (e.g., "youtube")
-	Resolution  string        // e.g., "1080x1920"
-	Duration    time.Duration // Duration of the video
+	Resolution  string        // e.g., "1080x1920" (of the first entry, for the multi-entry posts)
+	Duration    time.Duration // Duration of the video (of the first entry, too)
+	InfoJSON    []byte        // Raw metadata JSON, written by yt-dlp (of the post itself, for the multi-entry posts)
+
+	Metadata // Extended metadata (of the first entry, for the multi-entry posts)
+
+	dir string // temporary directory with the downloaded files
 }
 
+// IsVideo reports whether the result is a single video (not an image, nor a multi-entry post).
+func (d *Downloaded) IsVideo() bool { return len(d.Items) == 1 && d.Items[0].Kind == MediaVideo }
+
+// Cleanup removes the downloaded files, together with the temporary directory they are located in.
+func (d *Downloaded) Cleanup() error { return os.RemoveAll(d.dir) }
+
 type (
 	// options contains runtime configuration for yt-dlp commands.
 	options struct {
-		runner      runner // Interface to run system commands
-		exePath     string // Path to yt-dlp binary
-		cookiesFile string // Path to cookies file (optional, for sites requiring authentication)
+		runner      runner  // Interface to run system commands
+		exePath     string  // Path to yt-dlp binary
+		cookiesFile string  // Path to cookies file (optional, for sites requiring authentication)
+		format      string  // Format selector (https://github.com/yt-dlp/yt-dlp?tab=readme-ov-file#format-selection)
+		proxy       string  // Proxy URL (e.g., "socks5://127.0.0.1:1080"; optional)
+		workDir     string  // Directory for the downloaded files (the system temporary directory, if empty)
+		minFileSize int64   // Smaller files are not downloaded (in bytes)
+		maxFileSize int64   // Larger files are not downloaded (in bytes)
+		maxItems    int     // Max number of the entries to download from the multi-entry posts (playlists)
+		convert     Convert // Conversion of the downloaded videos (see Convert)
+
+		section       *Chapter // Download only this part of the video (optional)
+		splitChapters bool     // Split the video into the files per chapter
+
+		liveDuration time.Duration // Record the live stream for this long (the stream is downloaded as is, if zero)
 
 		// To download from YouTube, yt-dlp needs to solve JavaScript challenges presented by YouTube using an
 		// external JavaScript runtime. This involves running challenge solver scripts maintained at yt-dlp-ejs
@@ -57,15 +142,53 @@ func WithExePath(path string) Option { return func(o *options) { o.exePath = pat
 // WithCookiesFile sets the path to a cookies file for yt-dlp.
 func WithCookiesFile(path string) Option { return func(o *options) { o.cookiesFile = path } }
 
+// WithFormat overrides the default format selector (e.g., "best", "bv*+ba/b").
+func WithFormat(format string) Option { return func(o *options) { o.format = format } }
+
+// WithProxy sets the HTTP/HTTPS/SOCKS proxy for yt-dlp (e.g., "http://127.0.0.1:3128", "socks5://127.0.0.1:1080").
+func WithProxy(proxy string) Option { return func(o *options) { o.proxy = proxy } }
+
 // WithJSRuntimes sets the JavaScript runtimes for yt-dlp (e.g., "node", "bun", "deno", "quickjs").
 func WithJSRuntimes(runtimes string) Option { return func(o *options) { o.jsRuntimes = runtimes } }
 
+// WithMinFileSize sets the minimal size of the file to download, in bytes (DefaultMinFileSize if not set).
+func WithMinFileSize(size int64) Option { return func(o *options) { o.minFileSize = size } }
+
+// WithMaxFileSize sets the maximal size of the file to download, in bytes (DefaultMaxFileSize if not set).
+func WithMaxFileSize(size int64) Option { return func(o *options) { o.maxFileSize = size } }
+
+// WithMaxItems sets the maximal number of the entries, downloaded from the multi-entry posts (e.g., the Instagram
+// carousels). Only the first entry is downloaded by default, so the playlists and channels are not downloaded
+// entirely, unless it's set.
+func WithMaxItems(n int) Option { return func(o *options) { o.maxItems = n } }
+
+// WithConvert sets the conversion mode of the downloaded videos (see Convert). The conversion requires ffmpeg and
+// ffprobe, and the videos are kept as is by default.
+func WithConvert(c Convert) Option { return func(o *options) { o.convert = c } }
+
+// WithSection limits the download to the part of the video (e.g., a single chapter). The part is cut at the
+// nearest keyframes, so it may start a bit earlier.
+func WithSection(start, end time.Duration) Option {
+	return func(o *options) { o.section = &Chapter{Start: start, End: end} }
+}
+
+// WithSplitChapters splits the downloaded video into the files per chapter (see Item.Chapter). The video is
+// returned as is, if it has no chapters.
+func WithSplitChapters() Option { return func(o *options) { o.splitChapters = true } }
+
+// WithWorkDir sets the directory for the downloaded files (the system temporary directory is used by default).
+func WithWorkDir(dir string) Option { return func(o *options) { o.workDir = dir } }
+
+// WithLiveRecording limits the download of a live stream to the given duration, starting from the current moment
+// (the stream is recorded by ffmpeg, and the file size limits are not applied).
+func WithLiveRecording(d time.Duration) Option { return func(o *options) { o.liveDuration = d } }
+
 // Apply sets default values and applies any functional options.
 func (o options) Apply(opts ...Option) options {
 	{ // set defaults if not already provided
-		switch {
-		case o.exePath == "" && exePath != "":
-			o.exePath = exePath // use the found yt-dlp binary path
+		switch path := ExePath(); {
+		case o.exePath == "" && path != "":
+			o.exePath = path // use the found (or updated) yt-dlp binary path
 		case o.exePath == "":
 			o.exePath = "yt-dlp" // default to "yt-dlp" if not set
 		}
@@ -79,13 +202,30 @@ func (o options) Apply(opts ...Option) options {
 		opt(&o)
 	}
 
+	// the defaults below are set after the options, to ignore empty values set by them
+	if o.minFileSize <= 0 {
+		o.minFileSize = DefaultMinFileSize
+	}
+
+	if o.maxFileSize <= 0 {
+		o.maxFileSize = DefaultMaxFileSize
+	}
+
+	if o.format == "" {
+		o.format = defaultFormat(o.maxFileSize) // after the size, to prefer the formats under the effective limit
+	}
+
+	if o.maxItems <= 0 {
+		o.maxItems = 1
+	}
+
 	return o
 }
 
-// Download downloads a single video from the given URL using yt-dlp.
-// It writes output to a temp directory and returns structured metadata.
-// The caller is responsible for cleaning up the downloaded file.
-// TODO: make file size limits configurable via options.
+// Download downloads a video (or the entries of the multi-entry post, see WithMaxItems) from the given URL using
+// yt-dlp. It writes output to a temp directory and returns structured metadata.
+// The files stay where yt-dlp wrote them, and the caller is responsible for cleaning them up (see
+// Downloaded.Cleanup).
 func Download(ctx context.Context, in string, opts ...Option) (_ *Downloaded, outErr error) { //nolint:funlen
 	// defer error wrapping to include module-specific prefix
 	defer func() {
@@ -94,24 +234,27 @@ func Download(ctx context.Context, in string, opts ...Option) (_ *Downloaded, ou
 		}
 	}()
 
+	var o = options{}.Apply(opts...) // initialize options
+
 	// create temporary directory for download
-	tmpDir, tmpErr := os.MkdirTemp("", "yt-dlp-*")
+	tmpDir, tmpErr := os.MkdirTemp(o.workDir, tempDirPattern)
 	if tmpErr != nil {
 		return nil, fmt.Errorf("failed to create temporary directory: %w", tmpErr)
 	}
 
-	defer func() { _ = os.RemoveAll(tmpDir) }() // clean up the temporary directory on return
+	// clean up the temporary directory on failure (on success, the caller does it with Downloaded.Cleanup)
+	defer func() {
+		if outErr != nil {
+			_ = os.RemoveAll(tmpDir)
+		}
+	}()
 
-	// initialize options
 	var (
-		o    = options{}.Apply(opts...)
 		args = []string{
 			// general options
 			"--ignore-config",  // don't load any more configuration files except those given to --config-locations
 			"--color", "never", // disable colored output
 			// video selection
-			"--min-filesize", "50k", // abort download if filesize is smaller than
-			"--max-filesize", "2G", // abort download if filesize is larger than
 			"--no-playlist", // download only the video, if the URL refers to a video and a playlist
 			// download options
 			"--concurrent-fragments", "1", // number of fragments of a dash/hlsnative video that should be downloaded conc-ly
@@ -120,8 +263,9 @@ func Download(ctx context.Context, in string, opts ...Option) (_ *Downloaded, ou
 			"--abort-on-unavailable-fragments", // abort download if a fragment is unavailable
 			// filesystem options
 			"--paths", tmpDir, // set the path to the temporary directory
-			// output filename template (https://github.com/yt-dlp/yt-dlp?tab=readme-ov-file#output-template)
-			"--output", "result.%(ext)s",
+			// output filename template (https://github.com/yt-dlp/yt-dlp?tab=readme-ov-file#output-template);
+			// "result.mp4" for the single video, and "result.1.jpg", "result.2.mp4", etc. for the playlist entries
+			"--output", "result%(playlist_index&.{}|)s.%(ext)s",
 			"--restrict-filenames",    // restrict filenames to only ASCII characters, and avoid "&" and spaces in filenames
 			"--trim-filenames", "128", // limit the filename length (excluding extension)
 			"--no-overwrites",           // do not overwrite any files
@@ -134,12 +278,18 @@ func Download(ctx context.Context, in string, opts ...Option) (_ *Downloaded, ou
 			"--no-progress", // do not print progress bar
 			// video format options
 			// https://github.com/yt-dlp/yt-dlp?tab=readme-ov-file#format-selection
-			"--format", "bv*[ext=mp4][filesize<2G]+ba[ext=m4a][filesize<2G]/bv*[ext=mp4]+ba[ext=m4a]/best[filesize<2G]/best",
+			"--format", o.format,
 			"--no-post-overwrites", // do not overwrite post-processed files
 			"--no-embed-info-json", // do not embed the infojson as an attachment to the video file
+			// the final paths of the downloaded files (the file name is a template too, so "%" is escaped)
+			"--print-to-file", "after_move:filepath",
+			strings.ReplaceAll(filepath.Join(tmpDir, resultsListName), "%", "%%"),
 		}
 	)
 
+	// download the first entries of the multi-entry posts (e.g., the Instagram carousels; only the first one by default)
+	args = append(args, "--playlist-items", fmt.Sprintf("1:%d", o.maxItems))
+
 	if o.cookiesFile != "" {
 		args = append(args,
 			"--cookies", // Netscape formatted file to read cookies from
@@ -151,88 +301,303 @@ func Download(ctx context.Context, in string, opts ...Option) (_ *Downloaded, ou
 		args = append(args, "--js-runtimes", o.jsRuntimes)
 	}
 
+	if o.proxy != "" {
+		args = append(args, "--proxy", o.proxy)
+	}
+
+	if o.section != nil {
+		args = append(args, "--download-sections", sectionArg(o.section))
+	}
+
+	if o.splitChapters {
+		// the chapter files are written next to the full video, which is kept (and ignored)
+		args = append(args, "--split-chapters", "--output", "chapter:"+chapterFilePrefix+"%(section_number)03d.%(ext)s")
+	}
+
+	if o.liveDuration <= 0 {
+		args = append(args,
+			"--min-filesize", strconv.FormatInt(o.minFileSize, 10), // abort download if filesize is smaller than
+			"--max-filesize", strconv.FormatInt(o.maxFileSize, 10), // abort download if filesize is larger than
+		)
+	} else { // the size of the recording is not known in advance, so the limits are not applied
+		args = append(args,
+			"--no-live-from-start",   // record from the current moment
+			"--downloader", "ffmpeg", // ffmpeg stops the recording gracefully, so the file is playable
+			"--downloader-args", fmt.Sprintf("ffmpeg:-t %d", int64(o.liveDuration.Seconds())),
+		)
+	}
+
 	// run yt-dlp with selected flags
-	if _, err := o.runner.Run(ctx, o.exePath, append(args, in)...); err != nil {
-		return nil, fmt.Errorf("failed to download: %w", err)
+	res, runErr := o.runner.Run(ctx, o.exePath, append(args, in)...)
+	if runErr != nil {
+		return nil, fmt.Errorf("failed to download: %w", classifyError(runErr))
 	}
 
-	// construct paths for result and metadata files
-	var (
-		resultFile = filepath.Join(tmpDir, "result.mp4")
-		infoFile   = filepath.Join(tmpDir, "result.info.json")
-	)
+	dl, err := readResults(tmpDir)
+	if err != nil {
+		return nil, err
+	}
 
-	// ensure the result file exists
-	if _, err := os.Stat(resultFile); err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("result file does not exist: %s", resultFile)
+	if len(dl.Items) == 0 {
+		// yt-dlp skips the files out of the size limits without an error
+		if sizeErr := fileSizeError(res); sizeErr != nil {
+			return nil, sizeErr
 		}
 
-		return nil, err
+		return nil, fmt.Errorf("result file does not exist in %s", tmpDir)
 	}
 
-	// ensure the metadata file exists
-	if _, err := os.Stat(infoFile); err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("info file does not exist: %s", infoFile)
+	if o.splitChapters && dl.IsVideo() {
+		chapters, chErr := readChapterFiles(tmpDir, dl.Chapters)
+		if chErr != nil {
+			return nil, chErr
 		}
 
+		if len(chapters) > 0 {
+			dl.Items = chapters
+		}
+	}
+
+	// convert the videos for Telegram, if needed
+	if o.convert != ConvertNone && o.convert != "" {
+		for i, item := range dl.Items {
+			if item.Kind != MediaVideo {
+				continue
+			}
+
+			path, err := convertVideo(ctx, o, item.Filepath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to convert the video: %w", err)
+			}
+
+			dl.Items[i].Filepath = path
+		}
+	}
+
+	return dl, nil
+}
+
+// infoJSON is the metadata, written by yt-dlp to the ".info.json" files (of the video, or the playlist and each of
+// its entries).
+type infoJSON struct {
+	Type          string  `json:"_type"` // "playlist" for the playlists, empty (or "video") for the videos
+	ID            string  `json:"id"`
+	Title         string  `json:"title"`
+	FullTitle     string  `json:"fulltitle"`
+	Description   string  `json:"description"`
+	WebpageURL    string  `json:"webpage_url"`
+	MediaType     string  `json:"media_type"`
+	Extractor     string  `json:"extractor"`
+	Resolution    string  `json:"resolution"`
+	Duration      float32 `json:"duration"`
+	PlaylistIndex int     `json:"playlist_index"` // position of the entry in the playlist (starting from 1)
+
+	metadataJSON
+
+	raw []byte // the original JSON
+}
+
+var (
+	// imageExtensions are the extensions of the files, that are considered as images.
+	imageExtensions = []string{"jpg", "jpeg", "png", "webp", "heic"} //nolint:gochecknoglobals
+
+	// tempExtensions are the extensions of the temporary files, that yt-dlp may leave in the directory.
+	tempExtensions = []string{"part", "ytdl", "temp"} //nolint:gochecknoglobals
+)
+
+// readResults reads the metadata files in the directory, and returns the downloaded files with the metadata. The
+// entries without the downloaded file (e.g., skipped because of the size limits) are ignored, so the result may
+// have no items.
+func readResults(dir string) (*Downloaded, error) {
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
 		return nil, err
 	}
 
-	var info struct {
-		ID          string  `json:"id"`
-		Title       string  `json:"title"`
-		FullTitle   string  `json:"fulltitle"`
-		Description string  `json:"description"`
-		WebpageURL  string  `json:"webpage_url"`
-		MediaType   string  `json:"media_type"`
-		Extractor   string  `json:"extractor"`
-		Resolution  string  `json:"resolution"`
-		Duration    float32 `json:"duration"`
+	var names = make([]string, 0, len(dirEntries))
+
+	for _, e := range dirEntries {
+		names = append(names, e.Name())
+	}
+
+	// the files printed by yt-dlp are preferred, as the directory may contain the leftovers of the post-processing
+	var candidates = names
+
+	if printed := readResultsList(filepath.Join(dir, resultsListName)); len(printed) > 0 {
+		candidates = printed
 	}
 
-	{ // open and decode metadata JSON file
-		fp, fpErr := os.Open(infoFile)
-		if fpErr != nil {
-			return nil, fmt.Errorf("failed to open info file: %w", fpErr)
+	type entry struct {
+		info *infoJSON
+		item Item
+	}
+
+	var (
+		playlist *infoJSON
+		entries  []entry
+	)
+
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".info.json") {
+			continue
+		}
+
+		info, rErr := readInfoFile(filepath.Join(dir, name))
+		if rErr != nil {
+			return nil, rErr
 		}
 
-		defer func() { _ = fp.Close() }() // ensure the file is closed after reading
+		if info.Type == "playlist" {
+			playlist = info
+
+			continue
+		}
+
+		var file = resultFile(dir, strings.TrimSuffix(name, "info.json"), candidates)
+		if file == "" {
+			continue
+		}
+
+		var kind = MediaVideo
+
+		if slices.Contains(imageExtensions, strings.ToLower(strings.TrimPrefix(filepath.Ext(file), "."))) {
+			kind = MediaImage
+		}
+
+		entries = append(entries, entry{info: info, item: Item{
+			Filepath: filepath.Join(dir, file),
+			Kind:     kind,
+			ID:       info.ID,
+			Title:    info.Title,
+			Duration: time.Duration(info.Duration * float32(time.Second)),
+		}})
+	}
+
+	// keep the playlist order (the files are listed by name, so "result.10" goes before "result.2")
+	slices.SortStableFunc(entries, func(a, b entry) int { return a.info.PlaylistIndex - b.info.PlaylistIndex })
+
+	var (
+		dl   = Downloaded{Items: make([]Item, 0, len(entries)), dir: dir}
+		meta infoJSON // the metadata of the playlist (if any), completed with the first entry one
+	)
+
+	for _, e := range entries {
+		dl.Items = append(dl.Items, e.item)
+	}
+
+	if len(entries) > 0 {
+		meta = *entries[0].info
+	}
+
+	if playlist != nil {
+		meta.ID, meta.Title, meta.FullTitle = playlist.ID, playlist.Title, playlist.Title
+		meta.Description, meta.WebpageURL = playlist.Description, playlist.WebpageURL
+		meta.Extractor = cmp.Or(playlist.Extractor, meta.Extractor)
+		meta.Uploader = cmp.Or(playlist.Uploader, meta.Uploader)
+		meta.ChannelURL = cmp.Or(playlist.ChannelURL, meta.ChannelURL)
+		meta.raw = playlist.raw
+	}
+
+	dl.ID, dl.Title, dl.FullTitle, dl.Description = meta.ID, meta.Title, meta.FullTitle, meta.Description
+	dl.WebpageURL, dl.MediaType, dl.Extractor = meta.WebpageURL, meta.MediaType, meta.Extractor
+	dl.Resolution, dl.InfoJSON, dl.Metadata = meta.Resolution, meta.raw, meta.metadata()
+	dl.Duration = time.Duration(meta.Duration * float32(time.Second))
 
-		if err := json.NewDecoder(fp).Decode(&info); err != nil {
-			return nil, fmt.Errorf("failed to decode info file: %w", err)
+	return &dl, nil
+}
+
+// readResultsList returns the names of the files, listed in the results file (see resultsListName). Nil is
+// returned, if the file cannot be read (e.g., the old yt-dlp versions don't write it).
+func readResultsList(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+
+	for line := range strings.Lines(string(data)) {
+		if line = strings.TrimSpace(line); line != "" {
+			names = append(names, filepath.Base(line))
 		}
 	}
 
-	{ // move the video to a stable temporary path (ensures file won't disappear on return)
-		newTmpFile, newTmpErr := os.CreateTemp("", fmt.Sprintf("yt-dlp-result-*%s", filepath.Ext(resultFile)))
-		if newTmpErr != nil {
-			return nil, newTmpErr
+	return names
+}
+
+// resultFile returns the name of the downloaded file in the directory, that has the prefix (e.g., "result." or
+// "result.2.") and any extension - the video may be merged into the mp4, webm or mkv container, depending on the
+// format. An empty string is returned, if there is no such file (e.g., it's skipped because of the size limits).
+func resultFile(dir, prefix string, candidates []string) string {
+	for _, name := range candidates {
+		var ext, ok = strings.CutPrefix(name, prefix)
+
+		// the intermediate files (e.g., "result.f137.mp4" or "result.mp4.part") have more than one extension
+		if !ok || ext == "" || strings.Contains(ext, ".") || slices.Contains(tempExtensions, ext) {
+			continue
 		}
 
-		_ = newTmpFile.Close()
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// readInfoFile reads and decodes the metadata file.
+func readInfoFile(path string) (*infoJSON, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read info file: %w", err)
+	}
+
+	var info = infoJSON{raw: data}
+
+	if err = json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("failed to decode info file: %w", err)
+	}
+
+	return &info, nil
+}
 
-		if err := os.Rename(resultFile, newTmpFile.Name()); err != nil {
-			return nil, err
+// fileSizeError returns ErrFileSize (with the yt-dlp message), if the output says the file is skipped because of
+// the size limits, or nil otherwise.
+func fileSizeError(res *RunResult) error {
+	if res == nil || res.Stdout == nil {
+		return nil
+	}
+
+	out, _ := io.ReadAll(res.Stdout)
+
+	for line := range strings.Lines(string(out)) {
+		if l := strings.ToLower(line); strings.Contains(l, "than max-filesize") || strings.Contains(l, "than min-filesize") {
+			return fmt.Errorf("%w: %s", ErrFileSize, strings.TrimSpace(line))
 		}
+	}
+
+	return nil
+}
+
+// sizeRe matches the sizes like "50k", "1.5G" or "1024".
+var sizeRe = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([kmgt]?)(?:i?b)?$`)
+
+// ParseSize parses the size with an optional binary unit suffix (e.g., "50k", "1.5G", "2GiB" or "1024" bytes),
+// like yt-dlp does.
+func ParseSize(s string) (int64, error) {
+	var m = sizeRe.FindStringSubmatch(strings.ToLower(strings.TrimSpace(s)))
+	if m == nil {
+		return 0, fmt.Errorf("invalid size %q (expected a number with an optional k, M, G or T suffix)", s)
+	}
+
+	value, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	var shift = map[string]int{"": 0, "k": 10, "m": 20, "g": 30, "t": 40}[m[2]] //nolint:mnd
 
-		resultFile = newTmpFile.Name() // update result path
-	}
-
-	// return metadata and final file path
-	return &Downloaded{
-		Filepath:    resultFile,
-		ID:          info.ID,
-		Title:       info.Title,
-		FullTitle:   info.FullTitle,
-		Description: info.Description,
-		WebpageURL:  info.WebpageURL,
-		MediaType:   info.MediaType,
-		Extractor:   info.Extractor,
-		Resolution:  info.Resolution,
-		Duration:    time.Duration(info.Duration * float32(time.Second)),
-	}, nil
+	return int64(value * float64(int64(1)<<shift)), nil
 }
 
 // Version returns the version of yt-dlp installed on the system.
@@ -0,0 +1,233 @@
+package ytdlp_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	ytdlp "gh.tarampamp.am/video-dl-bot/internal/yt-dlp"
+)
+
+func TestDownload_FileSize(t *testing.T) {
+	t.Parallel()
+
+	var gotArgs []string
+
+	_, err := ytdlp.Download(context.Background(), "https://example.com",
+		ytdlp.WithMaxFileSize(1<<20),
+		ytdlp.WithRunner(runnerFunc(func(args []string) (*ytdlp.RunResult, error) {
+			gotArgs = args
+
+			return &ytdlp.RunResult{Stdout: strings.NewReader(
+				"[info] foo: Downloading 1 format(s): 18\n" +
+					"[download] File is larger than max-filesize (5242880 bytes > 1048576 bytes). Aborting.\n",
+			)}, nil
+		})),
+	)
+	if !errors.Is(err, ytdlp.ErrFileSize) || ytdlp.ErrorClass(err) != "file-size" {
+		t.Errorf("expected ErrFileSize, got %v", err)
+	}
+
+	for name, want := range map[string]string{"--max-filesize": "1048576", "--min-filesize": "51200"} {
+		if i := slices.Index(gotArgs, name); i < 0 || gotArgs[i+1] != want {
+			t.Errorf("expected %s %s, got %v", name, want, gotArgs)
+		}
+	}
+
+	// the default format prefers the formats under the max size
+	if i := slices.Index(gotArgs, "--format"); i < 0 || !strings.Contains(gotArgs[i+1], "[filesize<1048576]") ||
+		strings.Contains(gotArgs[i+1], "2G") {
+		t.Errorf("expected the format limited by the max size, got %v", gotArgs)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	t.Parallel()
+
+	for give, want := range map[string]int64{
+		"1024":  1024,
+		"50k":   50 << 10,
+		"50K":   50 << 10,
+		"1.5M":  3 << 19,
+		"2G":    2 << 30,
+		"2GiB":  2 << 30,
+		"1 tb":  1 << 40,
+		"0":     0,
+		"":      -1,
+		"foo":   -1,
+		"-1":    -1,
+		"10x":   -1,
+		"1.2.3": -1,
+	} {
+		t.Run(give, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ytdlp.ParseSize(give)
+
+			switch {
+			case want < 0 && err == nil:
+				t.Errorf("expected an error, got %d", got)
+			case want >= 0 && (err != nil || got != want):
+				t.Errorf("want %d, got %d (error: %v)", want, got, err)
+			}
+		})
+	}
+}
+
+func TestDownload_LiveRecording(t *testing.T) {
+	t.Parallel()
+
+	var gotArgs []string
+
+	_, _ = ytdlp.Download(context.Background(), "https://example.com/live",
+		ytdlp.WithLiveRecording(10*time.Minute),
+		ytdlp.WithRunner(runnerFunc(func(args []string) (*ytdlp.RunResult, error) {
+			gotArgs = args
+
+			return nil, errors.New("exit status 1")
+		})),
+	)
+
+	for name, want := range map[string]string{"--downloader": "ffmpeg", "--downloader-args": "ffmpeg:-t 600"} {
+		if i := slices.Index(gotArgs, name); i < 0 || gotArgs[i+1] != want {
+			t.Errorf("expected %s %s, got %v", name, want, gotArgs)
+		}
+	}
+
+	if !slices.Contains(gotArgs, "--no-live-from-start") {
+		t.Errorf("expected the recording from the current moment, got %v", gotArgs)
+	}
+
+	for _, name := range []string{"--min-filesize", "--max-filesize"} {
+		if slices.Contains(gotArgs, name) {
+			t.Errorf("expected no %s for the live recording, got %v", name, gotArgs)
+		}
+	}
+}
+
+func TestDownload_InPlace(t *testing.T) {
+	t.Parallel()
+
+	var workDir = t.TempDir()
+
+	dl, err := ytdlp.Download(context.Background(), "https://example.com",
+		ytdlp.WithWorkDir(workDir),
+		ytdlp.WithRunner(&scriptedRunner{script: []error{nil}}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the file is not moved out of the directory, where yt-dlp wrote it
+	if len(dl.Items) != 1 || !dl.IsVideo() {
+		t.Fatalf("expected a single video, got %+v", dl.Items)
+	}
+
+	var dir = filepath.Dir(dl.Items[0].Filepath)
+
+	if filepath.Dir(dir) != workDir || !strings.HasPrefix(filepath.Base(dir), "yt-dlp-") {
+		t.Errorf("unexpected file path: %s", dl.Items[0].Filepath)
+	}
+
+	if err = dl.Cleanup(); err != nil {
+		t.Fatal(err)
+	}
+
+	if entries, _ := os.ReadDir(workDir); len(entries) != 0 {
+		t.Errorf("expected the working directory to be empty, got %d entries", len(entries))
+	}
+}
+
+func TestDownload_MultiEntry(t *testing.T) {
+	t.Parallel()
+
+	var gotArgs []string
+
+	dl, err := ytdlp.Download(context.Background(), "https://example.com/post",
+		ytdlp.WithWorkDir(t.TempDir()),
+		ytdlp.WithMaxItems(10),
+		ytdlp.WithRunner(runnerFunc(func(args []string) (*ytdlp.RunResult, error) {
+			gotArgs = args
+
+			var dir = argValue(args, "--paths")
+
+			for name, content := range map[string]string{
+				"result.info.json": `{"_type":"playlist","id":"post","title":"Post","description":"Caption",` +
+					`"extractor":"instagram","uploader":"Author"}`,
+				"result.1.info.json":  `{"id":"a","ext":"jpg","playlist_index":1,"uploader":"Someone"}`,
+				"result.1.jpg":        "image",
+				"result.2.info.json":  `{"id":"b","ext":"mp4","playlist_index":2,"duration":1.5,"format_id":"hd"}`,
+				"result.2.mp4":        "video",
+				"result.3.info.json":  `{"id":"c","ext":"mp4","playlist_index":3}`, // skipped (no file)
+				"result.10.info.json": `{"id":"d","ext":"webp","playlist_index":10}`,
+				"result.10.webp":      "image",
+			} {
+				if wErr := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); wErr != nil {
+					return nil, wErr
+				}
+			}
+
+			return &ytdlp.RunResult{Stdout: strings.NewReader(""), Stderr: strings.NewReader("")}, nil
+		})),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = dl.Cleanup() })
+
+	if i := slices.Index(gotArgs, "--playlist-items"); i < 0 || gotArgs[i+1] != "1:10" {
+		t.Errorf("expected the first 10 entries to be requested, got %v", gotArgs)
+	}
+
+	var got []string
+
+	for _, item := range dl.Items {
+		got = append(got, item.ID+":"+string(item.Kind)+":"+filepath.Base(item.Filepath))
+	}
+
+	var want = []string{"a:image:result.1.jpg", "b:video:result.2.mp4", "d:image:result.10.webp"}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("unexpected items: %v", got)
+	}
+
+	if dl.IsVideo() || dl.ID != "post" || dl.Description != "Caption" || dl.Extractor != "instagram" ||
+		dl.Items[1].Duration != 1500*time.Millisecond || dl.FormatID != "" || dl.Uploader != "Author" ||
+		!strings.Contains(string(dl.InfoJSON), `"_type":"playlist"`) {
+		t.Errorf("unexpected result: %+v", dl)
+	}
+}
+
+func TestDownload_FirstEntryByDefault(t *testing.T) {
+	t.Parallel()
+
+	var runner = &mediaRunner{files: map[string]string{
+		"result.info.json":   `{"_type":"playlist","id":"list","title":"Playlist","extractor":"youtube:tab"}`,
+		"result.1.info.json": `{"id":"a","ext":"mp4","playlist_index":1}`,
+		"result.1.mp4":       "video",
+	}}
+
+	dl, err := ytdlp.Download(context.Background(), "https://example.com/playlist",
+		ytdlp.WithWorkDir(t.TempDir()),
+		ytdlp.WithRunner(runner),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { _ = dl.Cleanup() })
+
+	if got := argValue(runner.args, "--playlist-items"); got != "1:1" {
+		t.Errorf("expected only the first entry to be requested, got %q", got)
+	}
+
+	if len(dl.Items) != 1 {
+		t.Errorf("unexpected items: %+v", dl.Items)
+	}
+}